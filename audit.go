@@ -0,0 +1,467 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/preceeder/db/builder"
+)
+
+// 审计：开启后，ExecByBuilder 执行的 InsertMap/InsertMany/Upsert/UpdateMap/UpdateOrdered/Delete 会生成 AuditRecord
+//   - UPDATE/DELETE 执行前在同一事务中以 SELECT ... FOR UPDATE 读取变更前的行，执行后按主键读取变更后的行
+//   - 没有传入事务时会自动开启一个事务
+//   - INSERT 没有指定主键时使用 LastInsertId（多行插入按自增步长为 1 连续推算）
+//   - 原生 SQL（ExecRaw）不会被审计
+
+// AuditRecord 一行数据的变更记录
+type AuditRecord struct {
+	Table      string         `json:"table"`
+	Op         string         `json:"op"` // insert、upsert、update、delete
+	PrimaryKey map[string]any `json:"primary_key"`
+	Changed    []string       `json:"changed"`       // 变化的列（排序后）
+	Old        map[string]any `json:"old,omitempty"` // update 时只包含变化的列，delete 时为整行
+	New        map[string]any `json:"new,omitempty"` // update 时只包含变化的列，insert 时为写入的值；物理删除时为 nil
+	Actor      string         `json:"actor,omitempty"`
+	RequestId  string         `json:"request_id,omitempty"`
+	At         time.Time      `json:"at"`
+}
+
+// AuditSink 接收审计记录，在数据变更所在的事务中、提交之前调用
+// 返回错误时本次变更失败（自动开启的事务会回滚）
+// 写到数据库以外的 Sink 应使用 AuditAfterCommit，在事务提交后再发送
+type AuditSink interface {
+	WriteAudit(ctx context.Context, tx *sqlx.Tx, records []AuditRecord) error
+}
+
+// AuditSinkFunc 函数形式的 AuditSink
+type AuditSinkFunc func(ctx context.Context, tx *sqlx.Tx, records []AuditRecord) error
+
+func (f AuditSinkFunc) WriteAudit(ctx context.Context, tx *sqlx.Tx, records []AuditRecord) error {
+	return f(ctx, tx, records)
+}
+
+// AuditAfterCommit 返回在事务提交后才调用 fn 的 AuditSink，事务回滚时丢弃记录；fn 的错误只记录日志
+// 通过 Transaction/RunInTx 之外的事务（直接传入 *sqlx.Tx）执行时无法得知提交结果，会立即调用 fn
+func AuditAfterCommit(fn func(ctx context.Context, records []AuditRecord) error) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, tx *sqlx.Tx, records []AuditRecord) error {
+		l := loggerFromContext(ctx)
+		send := func(ctx context.Context) {
+			if err := fn(ctx, records); err != nil {
+				l.log(ctx, LogClient, slog.LevelError, "mdb audit sink failed", slog.Int("records", len(records)), errorAttr(err))
+			}
+		}
+		if err := OnCommit(ctx, send); errors.Is(err, ErrNotInTransaction) {
+			send(ctx)
+		}
+		return nil
+	})
+}
+
+// AuditConfig 审计配置
+type AuditConfig struct {
+	// Sink 接收审计记录
+	Sink AuditSink
+	// Tables 需要审计的表，为空时审计所有表
+	Tables []string
+	// PrimaryKeys 表的主键列，默认从 Schema 读取，读取不到时使用 id
+	PrimaryKeys map[string][]string
+	// Actor 与 RequestId 默认从 WithAuditActor/WithRequestId 设置的 ctx 中读取
+	Actor     func(ctx context.Context) string
+	RequestId func(ctx context.Context) string
+	// Redaction 写入 Sink 前对 Old/New 脱敏，默认不处理（审计记录保存原值）；
+	// 与日志脱敏（WithRedaction）相互独立
+	Redaction *RedactionPolicy
+}
+
+// WithAudit 开启审计
+func WithAudit(cfg AuditConfig) Option {
+	return func(c *MysqlClient) {
+		if cfg.Actor == nil {
+			cfg.Actor = AuditActorFromContext
+		}
+		if cfg.RequestId == nil {
+			cfg.RequestId = RequestIdFromContext
+		}
+		c.audit = &cfg
+	}
+}
+
+type auditActorKey struct{}
+type requestIdKey struct{}
+
+// WithAuditActor 设置审计记录中的操作人
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+// AuditActorFromContext 读取 WithAuditActor 设置的操作人
+func AuditActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorKey{}).(string)
+	return actor
+}
+
+// WithRequestId 设置请求 ID，写入审计记录
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestIdFromContext 读取 WithRequestId 设置的请求 ID
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+// auditMutation 返回需要审计的变更，不需要审计时返回 false
+func (s MysqlClient) auditMutation(ctx context.Context, b *builder.SqlBuilder) (builder.Mutation, bool, error) {
+	if s.audit == nil || s.audit.Sink == nil {
+		return builder.Mutation{}, false, nil
+	}
+	m, ok, err := b.Mutation(s.buildContext(ctx))
+	if err != nil || !ok {
+		return m, false, err
+	}
+	if len(s.audit.Tables) > 0 && !slices.Contains(s.audit.Tables, m.Table) {
+		return m, false, nil
+	}
+	return m, true, nil
+}
+
+// execAudited 在事务中执行变更并写入审计记录
+func (s MysqlClient) execAudited(ctx context.Context, b *builder.SqlBuilder, m builder.Mutation, tx ...*sqlx.Tx) (sql.Result, error) {
+	if len(tx) > 0 && tx[0] != nil {
+		return s.auditInTx(ctx, b, m, tx[0])
+	}
+	var rs sql.Result
+	err := s.runTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		var err error
+		rs, err = s.auditInTx(ctx, b, m, tx)
+		return err
+	})
+	return rs, err
+}
+
+func (s MysqlClient) auditInTx(ctx context.Context, b *builder.SqlBuilder, m builder.Mutation, tx *sqlx.Tx) (sql.Result, error) {
+	pk := s.auditPrimaryKey(ctx, m.Table)
+	var before []map[string]any
+	if m.Op == builder.MutationUpdate || m.Op == builder.MutationDelete {
+		var err error
+		if before, err = s.preImage(ctx, b, tx); err != nil {
+			return nil, err
+		}
+	}
+	rs, err := s.execByBuilder(ctx, b, tx)
+	if err != nil {
+		return rs, err
+	}
+
+	var records []AuditRecord
+	switch m.Op {
+	case builder.MutationInsert, builder.MutationUpsert:
+		records = insertRecords(m, pk, rs)
+	default:
+		if records, err = s.changeRecords(ctx, tx, m, pk, before); err != nil {
+			return rs, err
+		}
+	}
+	if len(records) == 0 {
+		return rs, nil
+	}
+	actor, requestId, at := s.audit.Actor(ctx), s.audit.RequestId(ctx), time.Now()
+	for i := range records {
+		records[i].Actor, records[i].RequestId, records[i].At = actor, requestId, at
+		if redact := s.audit.Redaction; redact != nil {
+			records[i].Old, records[i].New = redact.Params(records[i].Old), redact.Params(records[i].New)
+		}
+	}
+	if err := s.audit.Sink.WriteAudit(ctx, tx, records); err != nil {
+		s.logger().log(ctx, LogClient, slog.LevelError, "mdb audit failed", slog.String(AttrTable, m.Table), errorAttr(err))
+		return rs, err
+	}
+	return rs, nil
+}
+
+// auditPrimaryKey 返回表的主键列
+func (s MysqlClient) auditPrimaryKey(ctx context.Context, table string) []string {
+	if pk, ok := s.audit.PrimaryKeys[table]; ok && len(pk) > 0 {
+		return pk
+	}
+	name := table[strings.LastIndex(table, ".")+1:]
+	if schema, err := s.Schema(ctx); err == nil {
+		if t, ok := schema.Table(name); ok && len(t.PrimaryKey()) > 0 {
+			return t.PrimaryKey()
+		}
+	}
+	return []string{"id"}
+}
+
+// preImage 锁定并读取将要被修改的行
+func (s MysqlClient) preImage(ctx context.Context, b *builder.SqlBuilder, tx *sqlx.Tx) ([]map[string]any, error) {
+	sqlStr, params, err := s.buildSql(ctx, b.PreImage())
+	if err != nil {
+		return nil, err
+	}
+	q, args, err := s.sqlParseSafe(ctx, sqlStr, params)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditRows(tx.QueryxContext(ctx, q, args...))
+}
+
+// changeRecords 按主键读取变更后的行，与变更前的行比较
+func (s MysqlClient) changeRecords(ctx context.Context, tx *sqlx.Tx, m builder.Mutation, pk []string, before []map[string]any) ([]AuditRecord, error) {
+	if len(before) == 0 {
+		return nil, nil
+	}
+	after, err := fetchByPrimaryKey(ctx, tx, m.Table, pk, before)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]AuditRecord, 0, len(before))
+	for _, old := range before {
+		key := pickColumns(old, pk)
+		cur := after[auditKey(key, pk)]
+		rec := AuditRecord{Table: m.Table, Op: m.Op, PrimaryKey: key}
+		switch {
+		case m.Op == builder.MutationDelete && cur == nil:
+			rec.Changed = sortedKeys(old)
+			rec.Old = old
+		case m.Op == builder.MutationDelete:
+			// 软删除：保留整行，便于查看被删除的数据
+			rec.Changed = diffColumns(old, cur)
+			rec.Old, rec.New = old, cur
+		default:
+			rec.Changed = diffColumns(old, cur)
+			if len(rec.Changed) == 0 {
+				continue
+			}
+			rec.Old, rec.New = pickColumns(old, rec.Changed), pickColumns(cur, rec.Changed)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// insertRecords 生成写入的记录；没有指定主键时使用 LastInsertId 推算
+func insertRecords(m builder.Mutation, pk []string, rs sql.Result) []AuditRecord {
+	var lastId int64
+	if len(pk) == 1 {
+		lastId, _ = rs.LastInsertId()
+		if affected, err := rs.RowsAffected(); m.Op == builder.MutationInsert && (err != nil || affected != int64(len(m.Rows))) {
+			// INSERT IGNORE 跳过了部分行，无法对应自增 ID
+			lastId = 0
+		}
+		if m.Op == builder.MutationUpsert && len(m.Rows) > 1 {
+			lastId = 0
+		}
+	}
+	records := make([]AuditRecord, 0, len(m.Rows))
+	for i, row := range m.Rows {
+		key := pickColumns(row, pk)
+		if len(key) < len(pk) && lastId > 0 {
+			key = map[string]any{pk[0]: lastId + int64(i)}
+		}
+		records = append(records, AuditRecord{
+			Table:      m.Table,
+			Op:         m.Op,
+			PrimaryKey: key,
+			Changed:    sortedKeys(row),
+			New:        row,
+		})
+	}
+	return records
+}
+
+// fetchByPrimaryKey 读取 rows 对应主键的当前行，以 auditKey 为键
+func fetchByPrimaryKey(ctx context.Context, tx *sqlx.Tx, table string, pk []string, rows []map[string]any) (map[string]map[string]any, error) {
+	conds := make([]string, 0, len(rows))
+	var args []any
+	for _, row := range rows {
+		parts := make([]string, len(pk))
+		for i, col := range pk {
+			v, ok := row[col]
+			if !ok {
+				return nil, fmt.Errorf("mdb audit: primary key %s not found in %s", col, table)
+			}
+			parts[i] = quoteIdent(col) + " = ?"
+			args = append(args, v)
+		}
+		conds = append(conds, "("+strings.Join(parts, " AND ")+")")
+	}
+	q := "SELECT * FROM " + quoteIdent(table) + " WHERE " + strings.Join(conds, " OR ")
+	after, err := scanAuditRows(tx.QueryxContext(ctx, q, args...))
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]map[string]any, len(after))
+	for _, row := range after {
+		byKey[auditKey(row, pk)] = row
+	}
+	return byKey, nil
+}
+
+func scanAuditRows(rows *sqlx.Rows, err error) ([]map[string]any, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []map[string]any
+	for rows.Next() {
+		row := map[string]any{}
+		if err := rows.MapScan(row); err != nil {
+			return nil, err
+		}
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// quoteIdent 为表名（可以带库名前缀）或列名加反引号
+func quoteIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+func auditKey(row map[string]any, pk []string) string {
+	parts := make([]string, len(pk))
+	for i, col := range pk {
+		parts[i] = fmt.Sprint(row[col])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func pickColumns(row map[string]any, columns []string) map[string]any {
+	out := make(map[string]any, len(columns))
+	for _, col := range columns {
+		if v, ok := row[col]; ok {
+			out[col] = v
+		}
+	}
+	return out
+}
+
+func diffColumns(old, cur map[string]any) []string {
+	var changed []string
+	for col, v := range cur {
+		if !reflect.DeepEqual(old[col], v) {
+			changed = append(changed, col)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func sortedKeys(row map[string]any) []string {
+	keys := make([]string, 0, len(row))
+	for k := range row {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// -------------------- 审计表 --------------------
+
+// AuditTable 将审计记录写入数据库表的 AuditSink，记录与数据变更在同一事务中提交
+type AuditTable struct {
+	Name string
+}
+
+// NewAuditTable 创建审计表 Sink，name 为空时使用 audit_log
+func NewAuditTable(name string) *AuditTable {
+	if name == "" {
+		name = "audit_log"
+	}
+	return &AuditTable{Name: name}
+}
+
+// CreateTableSQL 返回审计表的建表语句
+func (t *AuditTable) CreateTableSQL() string {
+	return builder.CreateTable(t.Name).IfNotExists().
+		Column(builder.Col("id", "bigint").Unsigned().NotNull().AutoIncrement()).
+		Column(builder.Col("table_name", "varchar(128)").NotNull()).
+		Column(builder.Col("op", "varchar(16)").NotNull()).
+		Column(builder.Col("primary_key", "json").NotNull()).
+		Column(builder.Col("changed", "json").NotNull()).
+		Column(builder.Col("old_values", "json").Null()).
+		Column(builder.Col("new_values", "json").Null()).
+		Column(builder.Col("actor", "varchar(128)").NotNull().Default("")).
+		Column(builder.Col("request_id", "varchar(128)").NotNull().Default("")).
+		Column(builder.Col("created_at", "datetime(3)").NotNull()).
+		PrimaryKey("id").
+		Index(builder.Index("idx_table_created", "table_name", "created_at")).
+		Index(builder.Index("idx_request", "request_id")).
+		Engine("InnoDB").
+		Charset("utf8mb4").
+		Sql()
+}
+
+func (t *AuditTable) WriteAudit(ctx context.Context, tx *sqlx.Tx, records []AuditRecord) error {
+	rows := make([]map[string]any, len(records))
+	for i, r := range records {
+		rows[i] = map[string]any{
+			"table_name":  r.Table,
+			"op":          r.Op,
+			"primary_key": auditJSON(r.PrimaryKey),
+			"changed":     auditJSON(r.Changed),
+			"old_values":  auditJSONOrNil(r.Old),
+			"new_values":  auditJSONOrNil(r.New),
+			"actor":       r.Actor,
+			"request_id":  r.RequestId,
+			"created_at":  r.At.UTC(),
+		}
+	}
+	// 审计表是内部表，不应用租户作用域
+	sqlStr, params, err := builder.Table(t.Name).InsertMany(rows).WithoutTimestamps().CrossTenant().SqlContext(ctx)
+	if err != nil {
+		return err
+	}
+	q, args, err := sqlx.Named(sqlStr, params)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+func auditJSONOrNil(row map[string]any) any {
+	if row == nil {
+		return nil
+	}
+	return auditJSON(row)
+}
+
+// auditJSON 序列化为 JSON，无法序列化的值（例如 SQL 表达式）使用 fmt 格式化
+func auditJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		if row, ok := v.(map[string]any); ok {
+			safe := make(map[string]any, len(row))
+			for k, val := range row {
+				if _, err := json.Marshal(val); err != nil {
+					val = fmt.Sprint(val)
+				}
+				safe[k] = val
+			}
+			data, _ = json.Marshal(safe)
+		}
+	}
+	return string(data)
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/preceeder/db/builder"
+)
+
+func TestAuditTable_WriteAuditBuildError(t *testing.T) {
+	builder.RegisterShardRule("t_audit_sharded", builder.ShardRule{Column: "shard_id", Strategy: builder.HashShard{Count: 2}})
+	defer builder.UnregisterShardRule("t_audit_sharded")
+
+	records := []AuditRecord{{Table: "t_user", Op: builder.MutationInsert, At: time.Now()}}
+	err := NewAuditTable("t_audit_sharded").WriteAudit(context.Background(), nil, records)
+	if !errors.Is(err, builder.ErrShardKeyRequired) {
+		t.Fatalf("WriteAudit = %v", err)
+	}
+}
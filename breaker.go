@@ -0,0 +1,272 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，调用被快速失败
+var ErrCircuitOpen = errors.New("mdb: circuit open")
+
+// CircuitOpenError 调用因熔断被拒绝，可以用 errors.Is(err, ErrCircuitOpen) 判断
+type CircuitOpenError struct {
+	Op    string
+	State BreakerState
+	Until time.Time // 预计进入半开状态的时间；半开探测名额已满时为零值
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Until.IsZero() {
+		return fmt.Sprintf("mdb: %s rejected: circuit %s", e.Op, e.State)
+	}
+	return fmt.Sprintf("mdb: %s rejected: circuit %s until %s", e.Op, e.State, e.Until.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState 熔断器状态
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行
+	BreakerOpen                         // 快速失败
+	BreakerHalfOpen                     // 放行少量探测调用
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerConfig 熔断器配置，零值字段使用默认值
+type BreakerConfig struct {
+	Window         time.Duration // 统计失败率的时间窗口，默认 10s
+	MinRequests    int           // 窗口内至少有这么多调用才计算失败率，默认 20
+	FailureRate    float64       // 失败率达到该值时打开，默认 0.5
+	OpenTimeout    time.Duration // 打开后多久进入半开，默认 5s
+	HalfOpenProbes int           // 半开时允许的并发探测数，全部成功后关闭，任一失败重新打开，默认 1
+	// IsFailure 判断错误是否计入失败，默认只统计连接类错误（见 isConnectionError），
+	// 业务错误（例如主键冲突、乐观锁失败）不会触发熔断
+	IsFailure func(error) bool
+	// OnStateChange 状态变化回调，在锁外同步调用
+	OnStateChange func(from, to BreakerState)
+}
+
+// WithCircuitBreaker 为客户端开启熔断
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(c *MysqlClient) {
+		c.breaker = newBreaker(cfg)
+	}
+}
+
+type breaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	total       int
+	failures    int
+	openedAt    time.Time
+	probes      int // 半开状态下正在进行的探测数
+	successes   int // 半开状态下成功的探测数
+}
+
+func newBreaker(cfg BreakerConfig) *breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = isConnectionError
+	}
+	return &breaker{cfg: cfg, now: time.Now}
+}
+
+// allow 判断是否放行，放行时返回上报结果的函数；b 为 nil 时总是放行
+func (b *breaker) allow(op string) (func(error), error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+	b.mu.Lock()
+	now := b.now()
+	var changed func()
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		changed = b.setState(BreakerHalfOpen, now)
+	}
+	switch b.state {
+	case BreakerOpen:
+		err := &CircuitOpenError{Op: op, State: b.state, Until: b.openedAt.Add(b.cfg.OpenTimeout)}
+		b.mu.Unlock()
+		return nil, err
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			b.mu.Unlock()
+			notify(changed)
+			return nil, &CircuitOpenError{Op: op, State: BreakerHalfOpen}
+		}
+		b.probes++
+		b.mu.Unlock()
+		notify(changed)
+		return b.reportProbe, nil
+	}
+	b.mu.Unlock()
+	return b.report, nil
+}
+
+// errNotStarted 放行后调用没有真正执行（例如被并发限制拒绝），只归还探测名额，不计入统计
+var errNotStarted = errors.New("mdb: call not started")
+
+// report 关闭状态下上报调用结果
+func (b *breaker) report(err error) {
+	if err == errNotStarted {
+		return
+	}
+	failed := err != nil && b.cfg.IsFailure(err)
+	b.mu.Lock()
+	now := b.now()
+	if b.state != BreakerClosed {
+		// 统计期间状态已变化（例如并发调用触发了熔断），结果不再计入
+		b.mu.Unlock()
+		return
+	}
+	if now.Sub(b.windowStart) >= b.cfg.Window {
+		b.windowStart, b.total, b.failures = now, 0, 0
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+	var changed func()
+	if failed && b.total >= b.cfg.MinRequests && float64(b.failures)/float64(b.total) >= b.cfg.FailureRate {
+		changed = b.setState(BreakerOpen, now)
+	}
+	b.mu.Unlock()
+	notify(changed)
+}
+
+// reportProbe 半开状态下上报探测结果
+func (b *breaker) reportProbe(err error) {
+	failed := err != nil && err != errNotStarted && b.cfg.IsFailure(err)
+	b.mu.Lock()
+	now := b.now()
+	var changed func()
+	if b.state == BreakerHalfOpen {
+		b.probes--
+		switch {
+		case err == errNotStarted:
+		case failed:
+			changed = b.setState(BreakerOpen, now)
+		default:
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenProbes {
+				changed = b.setState(BreakerClosed, now)
+			}
+		}
+	}
+	b.mu.Unlock()
+	notify(changed)
+}
+
+// setState 切换状态并重置计数，返回需要在锁外执行的回调
+func (b *breaker) setState(to BreakerState, now time.Time) func() {
+	from := b.state
+	b.state = to
+	b.windowStart, b.total, b.failures = now, 0, 0
+	b.probes, b.successes = 0, 0
+	if to == BreakerOpen {
+		b.openedAt = now
+	}
+	if from == to || b.cfg.OnStateChange == nil {
+		return nil
+	}
+	return func() { b.cfg.OnStateChange(from, to) }
+}
+
+func notify(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
+
+func (b *breaker) currentState() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// BreakerState 返回熔断器状态，没有开启 WithCircuitBreaker 时总是 BreakerClosed
+func (s MysqlClient) BreakerState() BreakerState {
+	if s.breaker == nil {
+		return BreakerClosed
+	}
+	return s.breaker.currentState()
+}
+
+// isConnectionError 判断是否为连接类错误：网络错误、连接失效、服务端不可用或超时
+// 调用方主动取消（context.Canceled）以及 SQL 本身的错误不算
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1040, // Too many connections
+			1053,                   // Server shutdown in progress
+			1927,                   // Connection was killed
+			2002, 2003, 2006, 2013: // 无法连接、server has gone away、lost connection
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestBreaker_StateMachine(t *testing.T) {
+	now := time.Unix(0, 0)
+	var changes []string
+	b := newBreaker(BreakerConfig{
+		Window:         time.Minute,
+		MinRequests:    4,
+		FailureRate:    0.5,
+		OpenTimeout:    time.Second,
+		HalfOpenProbes: 2,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	b.now = func() time.Time { return now }
+	connErr := mysql.ErrInvalidConn
+	call := func(err error) error {
+		report, aerr := b.allow("ExecRaw")
+		if aerr != nil {
+			return aerr
+		}
+		report(err)
+		return nil
+	}
+
+	// 业务错误不计入失败
+	for i := 0; i < 10; i++ {
+		_ = call(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	}
+	if b.currentState() != BreakerClosed {
+		t.Fatal("opened on non-connection errors")
+	}
+
+	now = now.Add(time.Minute) // 新窗口
+	_ = call(nil)
+	_ = call(connErr)
+	_ = call(nil)
+	if b.currentState() != BreakerClosed {
+		t.Fatal("opened below MinRequests")
+	}
+	_ = call(connErr)
+	if b.currentState() != BreakerOpen {
+		t.Fatal("not opened at failure rate 0.5")
+	}
+	err := call(nil)
+	var oe *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &oe) || oe.State != BreakerOpen {
+		t.Fatalf("open: err = %v", err)
+	}
+
+	// 半开：探测失败重新打开
+	now = now.Add(time.Second)
+	if err := call(connErr); err != nil {
+		t.Fatal(err)
+	}
+	if b.currentState() != BreakerOpen {
+		t.Fatal("failed probe did not reopen")
+	}
+
+	// 半开：最多 HalfOpenProbes 个并发探测，全部成功后关闭
+	now = now.Add(time.Second)
+	r1, err1 := b.allow("q")
+	r2, err2 := b.allow("q")
+	_, err3 := b.allow("q")
+	if err1 != nil || err2 != nil || !errors.Is(err3, ErrCircuitOpen) {
+		t.Fatalf("probes: %v %v %v", err1, err2, err3)
+	}
+	r1(nil)
+	if b.currentState() != BreakerHalfOpen {
+		t.Fatal("closed before all probes succeeded")
+	}
+	r2(nil)
+	if b.currentState() != BreakerClosed {
+		t.Fatal("not closed after probes succeeded")
+	}
+
+	want := "closed->open open->half-open half-open->open open->half-open half-open->closed"
+	if got := fmt.Sprint(changes); got != "["+want+"]" {
+		t.Fatalf("changes = %s", got)
+	}
+}
+
+func TestBegin_BreakerBeforeBulkhead(t *testing.T) {
+	c := &MysqlClient{}
+	WithBulkhead(BulkheadConfig{MaxConcurrent: 1})(c)
+	WithCircuitBreaker(BreakerConfig{MinRequests: 1, OpenTimeout: time.Second})(c)
+	now := time.Unix(0, 0)
+	c.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	release, err := c.bulkhead.acquire(ctx, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, _ := c.breaker.allow("q")
+	report(mysql.ErrInvalidConn)
+
+	// 熔断时不占用也不等待并发名额
+	var ce *CircuitOpenError
+	if _, _, err := c.begin(ctx, "q", "", 0); !errors.As(err, &ce) {
+		t.Fatalf("open: err = %v", err)
+	}
+
+	// 半开探测被并发限制拒绝时归还探测名额，不影响熔断状态
+	now = now.Add(time.Second)
+	if _, _, err := c.begin(ctx, "q", "", 0); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("bulkhead: err = %v", err)
+	}
+	if st := c.breaker.currentState(); st != BreakerHalfOpen {
+		t.Fatalf("state = %v", st)
+	}
+	release()
+	_, done, err := c.begin(ctx, "q", "", 0)
+	if err != nil {
+		t.Fatalf("probe: err = %v", err)
+	}
+	done(nil)
+	if st := c.breaker.currentState(); st != BreakerClosed {
+		t.Fatalf("state after probe = %v", st)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("syntax error"), false},
+		{&mysql.MySQLError{Number: 1062}, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, true},
+		{mysql.ErrInvalidConn, true},
+		{&mysql.MySQLError{Number: 1040}, true},
+		{fmt.Errorf("wrap: %w", &net.OpError{Op: "dial", Err: errors.New("refused")}), true},
+	}
+	for _, c := range cases {
+		if got := isConnectionError(c.err); got != c.want {
+			t.Errorf("isConnectionError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
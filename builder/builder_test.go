@@ -2,6 +2,7 @@ package builder
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -106,3 +107,27 @@ func TestComplexQuery(b *testing.T) {
 	fmt.Println(data)
 
 }
+
+func TestLockingRead(t *testing.T) {
+	cases := []struct {
+		lock func(*SqlBuilder) *SqlBuilder
+		want string
+	}{
+		{(*SqlBuilder).ForUpdate, " LIMIT 10 FOR UPDATE"},
+		{(*SqlBuilder).ForShare, " LIMIT 10 FOR SHARE"},
+		{(*SqlBuilder).SkipLocked, " LIMIT 10 FOR UPDATE SKIP LOCKED"},
+		{func(b *SqlBuilder) *SqlBuilder { return b.ForShare().NoWait() }, " LIMIT 10 FOR SHARE NOWAIT"},
+		{func(b *SqlBuilder) *SqlBuilder { return b.ForUpdate().SkipLocked() }, " LIMIT 10 FOR UPDATE SKIP LOCKED"},
+	}
+	for _, c := range cases {
+		tb := Table("t_job")
+		b := c.lock(tb.Where(tb.Field("status").Eq(0, "status")).Order(tb.Field("id").Asc()).Limit(10))
+		sql, _ := b.Sql()
+		if !strings.HasSuffix(sql, c.want) {
+			t.Errorf("got %s, want suffix %q", sql, c.want)
+		}
+		if got, _ := b.Copy().Sql(); got != sql {
+			t.Errorf("Copy lost lock clause: %s", got)
+		}
+	}
+}
@@ -64,7 +64,17 @@ func eq(field string, d any, key ...string) Expr {
 	if len(key) > 0 {
 		str = "%s = :%s"
 	}
-	return handler(str, field, d, key...)
+	return withArg(handler(str, field, d, key...), "=", d)
+}
+
+// withArg 记录条件的操作与原始值
+func withArg(expr Expr, op string, d any) Expr {
+	if c, ok := expr.(Condition); ok {
+		c.op = op
+		c.arg = d
+		return c
+	}
+	return expr
 }
 
 // Eq
@@ -192,7 +202,7 @@ func in(field string, d any, key ...string) Expr {
 			str = "%s in (%s)"
 		}
 	}
-	return handler(str, field, d, key...)
+	return withArg(handler(str, field, d, key...), "in", d)
 }
 
 // NotIn
@@ -0,0 +1,629 @@
+package builder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DDL 构建器：按调用顺序输出，结果是确定的，适合在迁移与测试中直接断言
+//
+//	builder.CreateTable("user").IfNotExists().
+//		Column(builder.Col("id", "BIGINT").Unsigned().NotNull().AutoIncrement()).
+//		Column(builder.Col("name", "VARCHAR(64)").NotNull().Default("")).
+//		PrimaryKey("id").
+//		Index(builder.UniqueIndex("uk_name", "name")).
+//		Engine("InnoDB").Charset("utf8mb4").
+//		Sql()
+
+// ddlQuote 将字符串转为 SQL 字面量，DDL 不经过命名参数解析，所以不转义冒号
+func ddlQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(s) + "'"
+}
+
+// ddlColumns 引用列名列表，支持前缀长度与排序，例如 name(10)、created_at DESC
+func ddlColumns(cols []string) string {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		name, rest := c, ""
+		if idx := strings.IndexAny(c, "( "); idx > 0 {
+			name, rest = c[:idx], c[idx:]
+		}
+		parts[i] = ColumnNameHandler(name) + rest
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// ddlLiteral 默认值转为 SQL 字面量
+func ddlLiteral(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return ddlQuote(val)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case Field:
+		return val.String()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(val)
+	default:
+		return ddlQuote(fmt.Sprint(val))
+	}
+}
+
+// ColumnDef 列定义
+type ColumnDef struct {
+	name          string
+	typ           string
+	unsigned      bool
+	notNull       bool
+	explicitNull  bool
+	hasDefault    bool
+	defaultValue  string
+	autoIncrement bool
+	onUpdate      string
+	charset       string
+	collate       string
+	comment       string
+	generated     string
+	stored        bool
+	position      string
+}
+
+// Col 创建列定义，typ 为 MySQL 类型，例如 BIGINT、VARCHAR(64)、DECIMAL(10,2)
+func Col(name, typ string) *ColumnDef {
+	return &ColumnDef{name: name, typ: typ}
+}
+
+// Name 返回列名
+func (c *ColumnDef) Name() string { return c.name }
+
+func (c *ColumnDef) Unsigned() *ColumnDef {
+	c.unsigned = true
+	return c
+}
+
+func (c *ColumnDef) NotNull() *ColumnDef {
+	c.notNull, c.explicitNull = true, false
+	return c
+}
+
+// Null 显式输出 NULL
+func (c *ColumnDef) Null() *ColumnDef {
+	c.notNull, c.explicitNull = false, true
+	return c
+}
+
+// Default 默认值，字符串会被转义为字面量；表达式使用 DefaultExpr
+func (c *ColumnDef) Default(v any) *ColumnDef {
+	c.hasDefault, c.defaultValue = true, ddlLiteral(v)
+	return c
+}
+
+// DefaultExpr 原样输出的默认值，例如 CURRENT_TIMESTAMP、(UUID())
+func (c *ColumnDef) DefaultExpr(expr string) *ColumnDef {
+	c.hasDefault, c.defaultValue = true, expr
+	return c
+}
+
+func (c *ColumnDef) AutoIncrement() *ColumnDef {
+	c.autoIncrement = true
+	return c
+}
+
+// OnUpdate 例如 OnUpdate("CURRENT_TIMESTAMP")
+func (c *ColumnDef) OnUpdate(expr string) *ColumnDef {
+	c.onUpdate = expr
+	return c
+}
+
+func (c *ColumnDef) Charset(charset string) *ColumnDef {
+	c.charset = charset
+	return c
+}
+
+func (c *ColumnDef) Collate(collate string) *ColumnDef {
+	c.collate = collate
+	return c
+}
+
+func (c *ColumnDef) Comment(comment string) *ColumnDef {
+	c.comment = comment
+	return c
+}
+
+// Generated 生成列，stored 为 true 时为 STORED，否则为 VIRTUAL
+func (c *ColumnDef) Generated(expr string, stored bool) *ColumnDef {
+	c.generated, c.stored = expr, stored
+	return c
+}
+
+// First 仅用于 ALTER TABLE：放在第一列
+func (c *ColumnDef) First() *ColumnDef {
+	c.position = "FIRST"
+	return c
+}
+
+// After 仅用于 ALTER TABLE：放在 column 之后
+func (c *ColumnDef) After(column string) *ColumnDef {
+	c.position = "AFTER " + ColumnNameHandler(column)
+	return c
+}
+
+// Sql 返回列定义（不含 FIRST/AFTER）
+func (c *ColumnDef) Sql() string {
+	var b strings.Builder
+	b.WriteString(ColumnNameHandler(c.name))
+	b.WriteString(" ")
+	b.WriteString(c.typ)
+	if c.unsigned {
+		b.WriteString(" UNSIGNED")
+	}
+	if c.charset != "" {
+		b.WriteString(" CHARACTER SET " + c.charset)
+	}
+	if c.collate != "" {
+		b.WriteString(" COLLATE " + c.collate)
+	}
+	if c.generated != "" {
+		b.WriteString(" GENERATED ALWAYS AS (" + c.generated + ")")
+		if c.stored {
+			b.WriteString(" STORED")
+		} else {
+			b.WriteString(" VIRTUAL")
+		}
+	}
+	if c.notNull {
+		b.WriteString(" NOT NULL")
+	} else if c.explicitNull {
+		b.WriteString(" NULL")
+	}
+	if c.hasDefault {
+		b.WriteString(" DEFAULT " + c.defaultValue)
+	}
+	if c.onUpdate != "" {
+		b.WriteString(" ON UPDATE " + c.onUpdate)
+	}
+	if c.autoIncrement {
+		b.WriteString(" AUTO_INCREMENT")
+	}
+	if c.comment != "" {
+		b.WriteString(" COMMENT " + ddlQuote(c.comment))
+	}
+	return b.String()
+}
+
+func (c *ColumnDef) positioned() string {
+	if c.position == "" {
+		return c.Sql()
+	}
+	return c.Sql() + " " + c.position
+}
+
+// IndexDef 索引定义
+type IndexDef struct {
+	name    string
+	kind    string // ""、UNIQUE、FULLTEXT、SPATIAL
+	columns []string
+	using   string
+	comment string
+}
+
+// Index 普通索引，列支持前缀长度与排序，例如 "name(10)"、"created_at DESC"
+func Index(name string, columns ...string) *IndexDef {
+	return &IndexDef{name: name, columns: columns}
+}
+
+// UniqueIndex 唯一索引
+func UniqueIndex(name string, columns ...string) *IndexDef {
+	return &IndexDef{name: name, kind: "UNIQUE", columns: columns}
+}
+
+// FulltextIndex 全文索引
+func FulltextIndex(name string, columns ...string) *IndexDef {
+	return &IndexDef{name: name, kind: "FULLTEXT", columns: columns}
+}
+
+// Using 索引类型，例如 BTREE、HASH
+func (i *IndexDef) Using(typ string) *IndexDef {
+	i.using = typ
+	return i
+}
+
+func (i *IndexDef) Comment(comment string) *IndexDef {
+	i.comment = comment
+	return i
+}
+
+// Name 返回索引名
+func (i *IndexDef) Name() string { return i.name }
+
+// Sql 返回 CREATE TABLE / ALTER TABLE ADD 中的索引定义
+func (i *IndexDef) Sql() string {
+	var b strings.Builder
+	if i.kind != "" {
+		b.WriteString(i.kind + " ")
+	}
+	b.WriteString("KEY " + ColumnNameHandler(i.name) + " " + ddlColumns(i.columns))
+	i.writeOptions(&b)
+	return b.String()
+}
+
+func (i *IndexDef) writeOptions(b *strings.Builder) {
+	if i.using != "" {
+		b.WriteString(" USING " + i.using)
+	}
+	if i.comment != "" {
+		b.WriteString(" COMMENT " + ddlQuote(i.comment))
+	}
+}
+
+// ForeignKeyDef 外键定义
+type ForeignKeyDef struct {
+	name       string
+	columns    []string
+	refTable   string
+	refColumns []string
+	onDelete   string
+	onUpdate   string
+}
+
+// ForeignKey 外键，需要调用 References 指定引用的表与列
+func ForeignKey(name string, columns ...string) *ForeignKeyDef {
+	return &ForeignKeyDef{name: name, columns: columns}
+}
+
+func (f *ForeignKeyDef) References(table string, columns ...string) *ForeignKeyDef {
+	f.refTable, f.refColumns = table, columns
+	return f
+}
+
+// OnDelete 例如 CASCADE、SET NULL、RESTRICT
+func (f *ForeignKeyDef) OnDelete(action string) *ForeignKeyDef {
+	f.onDelete = action
+	return f
+}
+
+func (f *ForeignKeyDef) OnUpdate(action string) *ForeignKeyDef {
+	f.onUpdate = action
+	return f
+}
+
+// Sql 返回外键约束定义
+func (f *ForeignKeyDef) Sql() string {
+	var b strings.Builder
+	b.WriteString("CONSTRAINT " + ColumnNameHandler(f.name) + " FOREIGN KEY " + ddlColumns(f.columns))
+	b.WriteString(" REFERENCES " + ColumnNameHandler(f.refTable) + " " + ddlColumns(f.refColumns))
+	if f.onDelete != "" {
+		b.WriteString(" ON DELETE " + f.onDelete)
+	}
+	if f.onUpdate != "" {
+		b.WriteString(" ON UPDATE " + f.onUpdate)
+	}
+	return b.String()
+}
+
+// PartitionDef RANGE/LIST 分区中的一个分区
+type PartitionDef struct {
+	Name   string
+	Values string // RANGE 为 LESS THAN 的值（例如 "2024" 或 "MAXVALUE"），LIST 为 IN 的值列表（例如 "1, 2"）
+}
+
+// tableOptions 表选项
+type tableOptions struct {
+	engine    string
+	charset   string
+	collate   string
+	comment   string
+	autoInc   uint64
+	partition string
+}
+
+func (o *tableOptions) sql() string {
+	var parts []string
+	if o.engine != "" {
+		parts = append(parts, "ENGINE="+o.engine)
+	}
+	if o.autoInc > 0 {
+		parts = append(parts, "AUTO_INCREMENT="+strconv.FormatUint(o.autoInc, 10))
+	}
+	if o.charset != "" {
+		parts = append(parts, "DEFAULT CHARSET="+o.charset)
+	}
+	if o.collate != "" {
+		parts = append(parts, "COLLATE="+o.collate)
+	}
+	if o.comment != "" {
+		parts = append(parts, "COMMENT="+ddlQuote(o.comment))
+	}
+	return strings.Join(parts, " ")
+}
+
+// CreateTableBuilder CREATE TABLE 构建器
+type CreateTableBuilder struct {
+	name        string
+	ifNotExists bool
+	columns     []*ColumnDef
+	primaryKey  []string
+	indexes     []*IndexDef
+	foreignKeys []*ForeignKeyDef
+	options     tableOptions
+}
+
+// CreateTable 创建 CREATE TABLE 构建器
+func CreateTable(name string) *CreateTableBuilder {
+	return &CreateTableBuilder{name: name}
+}
+
+func (c *CreateTableBuilder) IfNotExists() *CreateTableBuilder {
+	c.ifNotExists = true
+	return c
+}
+
+func (c *CreateTableBuilder) Column(cols ...*ColumnDef) *CreateTableBuilder {
+	c.columns = append(c.columns, cols...)
+	return c
+}
+
+func (c *CreateTableBuilder) PrimaryKey(columns ...string) *CreateTableBuilder {
+	c.primaryKey = columns
+	return c
+}
+
+func (c *CreateTableBuilder) Index(indexes ...*IndexDef) *CreateTableBuilder {
+	c.indexes = append(c.indexes, indexes...)
+	return c
+}
+
+func (c *CreateTableBuilder) ForeignKey(fks ...*ForeignKeyDef) *CreateTableBuilder {
+	c.foreignKeys = append(c.foreignKeys, fks...)
+	return c
+}
+
+func (c *CreateTableBuilder) Engine(engine string) *CreateTableBuilder {
+	c.options.engine = engine
+	return c
+}
+
+func (c *CreateTableBuilder) Charset(charset string) *CreateTableBuilder {
+	c.options.charset = charset
+	return c
+}
+
+func (c *CreateTableBuilder) Collate(collate string) *CreateTableBuilder {
+	c.options.collate = collate
+	return c
+}
+
+func (c *CreateTableBuilder) Comment(comment string) *CreateTableBuilder {
+	c.options.comment = comment
+	return c
+}
+
+// AutoIncrement 自增起始值
+func (c *CreateTableBuilder) AutoIncrement(start uint64) *CreateTableBuilder {
+	c.options.autoInc = start
+	return c
+}
+
+// PartitionByHash PARTITION BY HASH(expr) PARTITIONS n，expr 为列名时自动加引号
+func (c *CreateTableBuilder) PartitionByHash(expr string, n int) *CreateTableBuilder {
+	c.options.partition = "PARTITION BY HASH(" + ColumnNameHandler(expr) + ") PARTITIONS " + strconv.Itoa(n)
+	return c
+}
+
+// PartitionByKey PARTITION BY KEY(columns) PARTITIONS n
+func (c *CreateTableBuilder) PartitionByKey(n int, columns ...string) *CreateTableBuilder {
+	c.options.partition = "PARTITION BY KEY" + ddlColumns(columns) + " PARTITIONS " + strconv.Itoa(n)
+	return c
+}
+
+// PartitionByRange PARTITION BY RANGE(expr)，expr 可以是列名或表达式，例如 YEAR(`created_at`)
+func (c *CreateTableBuilder) PartitionByRange(expr string, parts ...PartitionDef) *CreateTableBuilder {
+	defs := make([]string, len(parts))
+	for i, p := range parts {
+		values := p.Values
+		if values != "MAXVALUE" {
+			values = "(" + values + ")"
+		}
+		defs[i] = "PARTITION " + ColumnNameHandler(p.Name) + " VALUES LESS THAN " + values
+	}
+	c.options.partition = "PARTITION BY RANGE(" + ColumnNameHandler(expr) + ") (" + strings.Join(defs, ", ") + ")"
+	return c
+}
+
+// PartitionByList PARTITION BY LIST(expr)
+func (c *CreateTableBuilder) PartitionByList(expr string, parts ...PartitionDef) *CreateTableBuilder {
+	defs := make([]string, len(parts))
+	for i, p := range parts {
+		defs[i] = "PARTITION " + ColumnNameHandler(p.Name) + " VALUES IN (" + p.Values + ")"
+	}
+	c.options.partition = "PARTITION BY LIST(" + ColumnNameHandler(expr) + ") (" + strings.Join(defs, ", ") + ")"
+	return c
+}
+
+// Sql 生成 CREATE TABLE 语句，每个定义单独一行
+func (c *CreateTableBuilder) Sql() string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE ")
+	if c.ifNotExists {
+		b.WriteString("IF NOT EXISTS ")
+	}
+	b.WriteString(ColumnNameHandler(c.name))
+	b.WriteString(" (\n")
+	var defs []string
+	for _, col := range c.columns {
+		defs = append(defs, col.Sql())
+	}
+	if len(c.primaryKey) > 0 {
+		defs = append(defs, "PRIMARY KEY "+ddlColumns(c.primaryKey))
+	}
+	for _, idx := range c.indexes {
+		defs = append(defs, idx.Sql())
+	}
+	for _, fk := range c.foreignKeys {
+		defs = append(defs, fk.Sql())
+	}
+	b.WriteString("  " + strings.Join(defs, ",\n  "))
+	b.WriteString("\n)")
+	if opts := c.options.sql(); opts != "" {
+		b.WriteString(" " + opts)
+	}
+	if c.options.partition != "" {
+		b.WriteString("\n" + c.options.partition)
+	}
+	return b.String()
+}
+
+// AlterTableBuilder ALTER TABLE 构建器，多个操作合并为一条语句
+type AlterTableBuilder struct {
+	name    string
+	specs   []string
+	options tableOptions
+}
+
+// AlterTable 创建 ALTER TABLE 构建器
+func AlterTable(name string) *AlterTableBuilder {
+	return &AlterTableBuilder{name: name}
+}
+
+func (a *AlterTableBuilder) AddColumn(col *ColumnDef) *AlterTableBuilder {
+	a.specs = append(a.specs, "ADD COLUMN "+col.positioned())
+	return a
+}
+
+func (a *AlterTableBuilder) ModifyColumn(col *ColumnDef) *AlterTableBuilder {
+	a.specs = append(a.specs, "MODIFY COLUMN "+col.positioned())
+	return a
+}
+
+// ChangeColumn 重命名列并修改定义
+func (a *AlterTableBuilder) ChangeColumn(oldName string, col *ColumnDef) *AlterTableBuilder {
+	a.specs = append(a.specs, "CHANGE COLUMN "+ColumnNameHandler(oldName)+" "+col.positioned())
+	return a
+}
+
+// RenameColumn 只重命名列（MySQL 8.0+）
+func (a *AlterTableBuilder) RenameColumn(oldName, newName string) *AlterTableBuilder {
+	a.specs = append(a.specs, "RENAME COLUMN "+ColumnNameHandler(oldName)+" TO "+ColumnNameHandler(newName))
+	return a
+}
+
+func (a *AlterTableBuilder) DropColumn(name string) *AlterTableBuilder {
+	a.specs = append(a.specs, "DROP COLUMN "+ColumnNameHandler(name))
+	return a
+}
+
+func (a *AlterTableBuilder) AddIndex(idx *IndexDef) *AlterTableBuilder {
+	a.specs = append(a.specs, "ADD "+idx.Sql())
+	return a
+}
+
+func (a *AlterTableBuilder) DropIndex(name string) *AlterTableBuilder {
+	a.specs = append(a.specs, "DROP INDEX "+ColumnNameHandler(name))
+	return a
+}
+
+func (a *AlterTableBuilder) AddPrimaryKey(columns ...string) *AlterTableBuilder {
+	a.specs = append(a.specs, "ADD PRIMARY KEY "+ddlColumns(columns))
+	return a
+}
+
+func (a *AlterTableBuilder) DropPrimaryKey() *AlterTableBuilder {
+	a.specs = append(a.specs, "DROP PRIMARY KEY")
+	return a
+}
+
+func (a *AlterTableBuilder) AddForeignKey(fk *ForeignKeyDef) *AlterTableBuilder {
+	a.specs = append(a.specs, "ADD "+fk.Sql())
+	return a
+}
+
+func (a *AlterTableBuilder) DropForeignKey(name string) *AlterTableBuilder {
+	a.specs = append(a.specs, "DROP FOREIGN KEY "+ColumnNameHandler(name))
+	return a
+}
+
+func (a *AlterTableBuilder) RenameTo(name string) *AlterTableBuilder {
+	a.specs = append(a.specs, "RENAME TO "+ColumnNameHandler(name))
+	return a
+}
+
+func (a *AlterTableBuilder) Engine(engine string) *AlterTableBuilder {
+	a.options.engine = engine
+	return a
+}
+
+func (a *AlterTableBuilder) Charset(charset string) *AlterTableBuilder {
+	a.options.charset = charset
+	return a
+}
+
+func (a *AlterTableBuilder) Collate(collate string) *AlterTableBuilder {
+	a.options.collate = collate
+	return a
+}
+
+func (a *AlterTableBuilder) Comment(comment string) *AlterTableBuilder {
+	a.options.comment = comment
+	return a
+}
+
+// Empty 没有任何修改
+func (a *AlterTableBuilder) Empty() bool {
+	return len(a.specs) == 0 && a.options.sql() == ""
+}
+
+// Sql 生成 ALTER TABLE 语句，没有任何修改时返回空字符串
+func (a *AlterTableBuilder) Sql() string {
+	if a.Empty() {
+		return ""
+	}
+	specs := a.specs
+	if opts := a.options.sql(); opts != "" {
+		specs = append(specs[:len(specs):len(specs)], opts)
+	}
+	return "ALTER TABLE " + ColumnNameHandler(a.name) + " " + strings.Join(specs, ", ")
+}
+
+// CreateIndex CREATE [UNIQUE|FULLTEXT] INDEX ... ON table (...)
+func CreateIndex(table string, idx *IndexDef) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if idx.kind != "" {
+		b.WriteString(idx.kind + " ")
+	}
+	b.WriteString("INDEX " + ColumnNameHandler(idx.name) + " ON " + ColumnNameHandler(table) + " " + ddlColumns(idx.columns))
+	idx.writeOptions(&b)
+	return b.String()
+}
+
+// DropIndex DROP INDEX name ON table
+func DropIndex(table, name string) string {
+	return "DROP INDEX " + ColumnNameHandler(name) + " ON " + ColumnNameHandler(table)
+}
+
+// DropTable DROP TABLE [IF EXISTS] tables...
+func DropTable(ifExists bool, tables ...string) string {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = ColumnNameHandler(t)
+	}
+	if ifExists {
+		return "DROP TABLE IF EXISTS " + strings.Join(names, ", ")
+	}
+	return "DROP TABLE " + strings.Join(names, ", ")
+}
+
+// RenameTable RENAME TABLE from TO to [, from TO to ...]，pairs 依次为 旧名、新名
+func RenameTable(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, ColumnNameHandler(pairs[i])+" TO "+ColumnNameHandler(pairs[i+1]))
+	}
+	return "RENAME TABLE " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,92 @@
+package builder
+
+import "testing"
+
+func TestDDL_CreateTable(t *testing.T) {
+	sql := CreateTable("t_user").IfNotExists().
+		Column(
+			Col("id", "BIGINT").Unsigned().NotNull().AutoIncrement(),
+			Col("name", "VARCHAR(64)").NotNull().Default("it's").Comment("名称"),
+			Col("score", "DECIMAL(10,2)").Default(0),
+			Col("created_at", "DATETIME").NotNull().DefaultExpr("CURRENT_TIMESTAMP").OnUpdate("CURRENT_TIMESTAMP"),
+			Col("name_len", "INT").Generated("CHAR_LENGTH(`name`)", true),
+			Col("org_id", "BIGINT").Null(),
+		).
+		PrimaryKey("id").
+		Index(UniqueIndex("uk_name", "name"), Index("idx_created", "created_at DESC", "name(10)").Using("BTREE")).
+		ForeignKey(ForeignKey("fk_org", "org_id").References("t_org", "id").OnDelete("SET NULL")).
+		Engine("InnoDB").Charset("utf8mb4").Collate("utf8mb4_general_ci").Comment("用户表").
+		PartitionByHash("id", 4).
+		Sql()
+
+	want := "CREATE TABLE IF NOT EXISTS `t_user` (\n" +
+		"  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,\n" +
+		"  `name` VARCHAR(64) NOT NULL DEFAULT 'it''s' COMMENT '名称',\n" +
+		"  `score` DECIMAL(10,2) DEFAULT 0,\n" +
+		"  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+		"  `name_len` INT GENERATED ALWAYS AS (CHAR_LENGTH(`name`)) STORED,\n" +
+		"  `org_id` BIGINT NULL,\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `uk_name` (`name`),\n" +
+		"  KEY `idx_created` (`created_at` DESC, `name`(10)) USING BTREE,\n" +
+		"  CONSTRAINT `fk_org` FOREIGN KEY (`org_id`) REFERENCES `t_org` (`id`) ON DELETE SET NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci COMMENT='用户表'\n" +
+		"PARTITION BY HASH(`id`) PARTITIONS 4"
+	if sql != want {
+		t.Fatalf("CreateTable:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestDDL_PartitionByRange(t *testing.T) {
+	sql := CreateTable("t_log").Column(Col("y", "INT").NotNull()).
+		PartitionByRange("y", PartitionDef{Name: "p2023", Values: "2024"}, PartitionDef{Name: "pmax", Values: "MAXVALUE"}).
+		Sql()
+	want := "CREATE TABLE `t_log` (\n  `y` INT NOT NULL\n)\n" +
+		"PARTITION BY RANGE(`y`) (PARTITION `p2023` VALUES LESS THAN (2024), PARTITION `pmax` VALUES LESS THAN MAXVALUE)"
+	if sql != want {
+		t.Fatalf("PartitionByRange:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestDDL_AlterTable(t *testing.T) {
+	sql := AlterTable("t_user").
+		AddColumn(Col("email", "VARCHAR(128)").NotNull().Default("").After("name")).
+		ModifyColumn(Col("name", "VARCHAR(128)").NotNull()).
+		ChangeColumn("nick", Col("nickname", "VARCHAR(32)").First()).
+		DropColumn("age").
+		AddIndex(Index("idx_email", "email")).
+		DropIndex("idx_old").
+		DropForeignKey("fk_org").
+		Comment("users").
+		Sql()
+	want := "ALTER TABLE `t_user` " +
+		"ADD COLUMN `email` VARCHAR(128) NOT NULL DEFAULT '' AFTER `name`, " +
+		"MODIFY COLUMN `name` VARCHAR(128) NOT NULL, " +
+		"CHANGE COLUMN `nick` `nickname` VARCHAR(32) FIRST, " +
+		"DROP COLUMN `age`, " +
+		"ADD KEY `idx_email` (`email`), " +
+		"DROP INDEX `idx_old`, " +
+		"DROP FOREIGN KEY `fk_org`, " +
+		"COMMENT='users'"
+	if sql != want {
+		t.Fatalf("AlterTable:\n got: %s\nwant: %s", sql, want)
+	}
+	if sql := AlterTable("t_user").Sql(); sql != "" {
+		t.Fatalf("empty AlterTable should render nothing, got %q", sql)
+	}
+}
+
+func TestDDL_IndexAndTableStatements(t *testing.T) {
+	cases := map[string]string{
+		CreateIndex("t_user", UniqueIndex("uk_email", "email", "org_id")): "CREATE UNIQUE INDEX `uk_email` ON `t_user` (`email`, `org_id`)",
+		DropIndex("t_user", "uk_email"):                                   "DROP INDEX `uk_email` ON `t_user`",
+		DropTable(true, "t_a", "t_b"):                                     "DROP TABLE IF EXISTS `t_a`, `t_b`",
+		RenameTable("t_a", "t_a_old", "t_b", "t_a"):                       "RENAME TABLE `t_a` TO `t_a_old`, `t_b` TO `t_a`",
+		AlterTable("t_a").RenameTo("t_c").Sql():                           "ALTER TABLE `t_a` RENAME TO `t_c`",
+	}
+	for got, want := range cases {
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -257,3 +257,35 @@ func TestUpdateOrdered_WithExpressions(t *testing.T) {
 		t.Fatalf("UpdateOrdered should keep where params, got: %+v", params)
 	}
 }
+
+func TestUpdateMap_WithVersion(t *testing.T) {
+	tbl := Table("t_user")
+	b := tbl.Where(tbl.Field("id").Eq(1, "id")).
+		UpdateMap(map[string]any{"name": "new"}).
+		Version("version", 3)
+	sql, params := b.Sql()
+
+	if want := "UPDATE `t_user` SET `name` = :name, `version` = `t_user`.`version` + 1 WHERE `t_user`.`id` = :id AND `t_user`.`version` = :version_expected"; sql != want {
+		t.Fatalf("UpdateMap with version:\n got: %s\nwant: %s", sql, want)
+	}
+	if params["version_expected"] != 3 || params["id"] != 1 {
+		t.Fatalf("UpdateMap with version params unexpected: %+v", params)
+	}
+	if !b.HasVersion() {
+		t.Fatal("HasVersion should be true for versioned update")
+	}
+	if len(tbl.WhereParam) != 1 {
+		t.Fatal("version condition must not be appended to WhereParam")
+	}
+}
+
+func TestUpdateOrdered_WithVersion(t *testing.T) {
+	tbl := Table("t_user").As("u")
+	sql, _ := tbl.Where(tbl.Field("id").Eq(1, "id")).
+		UpdateOrdered([]map[string]any{{"name": "a"}}).
+		Version("ver", 1).Sql()
+	if !strings.Contains(sql, "SET `name` = :name, `ver` = u.`ver` + 1 WHERE") ||
+		!strings.HasSuffix(sql, "u.`ver` = :ver_expected") {
+		t.Fatalf("UpdateOrdered with version unexpected: %s", sql)
+	}
+}
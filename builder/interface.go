@@ -20,6 +20,9 @@ type Condition struct {
 	Name  string           // 字段名（可选）
 	S     string           // SQL 字符串
 	Value *map[string]any  // 参数值
+
+	op  string // 比较操作（目前只记录 = 与 in），用于分片路由
+	arg any    // 比较的原始值
 }
 
 func (f Condition) String() string {
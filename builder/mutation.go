@@ -0,0 +1,111 @@
+package builder
+
+import (
+	"context"
+)
+
+// DML 变更类型
+const (
+	MutationInsert = "insert"
+	MutationUpsert = "upsert"
+	MutationUpdate = "update"
+	MutationDelete = "delete"
+)
+
+// Mutation DML 构建器的变更描述，供审计等功能使用
+type Mutation struct {
+	Op    string // MutationInsert、MutationUpsert、MutationUpdate、MutationDelete
+	Table string // 被修改的表（去掉反引号，分片表为实际的物理表）
+	// Rows insert/upsert 写入的行，已应用时间戳、租户等约定
+	Rows []map[string]any
+}
+
+// Mutation 返回构建器的变更描述，不是 DML（例如 SELECT）时返回 false
+// ctx 与 SqlContext 相同，用于读取时间戳约定、租户与分片键
+func (s *SqlBuilder) Mutation(ctx context.Context) (Mutation, bool, error) {
+	if s.dmlType == "" || s.Table == nil {
+		return Mutation{}, false, nil
+	}
+	b, err := s.prepare(ctx)
+	if err != nil {
+		return Mutation{}, false, err
+	}
+	if b.scope != nil && b.scope.err != nil {
+		return Mutation{}, false, b.scope.err
+	}
+	if b.Table == nil {
+		// 广播到多个分片的语句
+		return Mutation{}, false, nil
+	}
+	m := Mutation{Table: b.Table.rawName()}
+	switch b.dmlType {
+	case "insert", "insert_ignore":
+		m.Op = MutationInsert
+		if row, ok := b.dmlData.(map[string]any); ok {
+			m.Rows = []map[string]any{row}
+		}
+	case "insert_many", "insert_ignore_many":
+		m.Op = MutationInsert
+		m.Rows, _ = b.dmlData.([]map[string]any)
+	case "insert_on_duplicate_cols":
+		m.Op = MutationUpsert
+		if data, ok := b.dmlData.(InsertOnDuplicateColsData); ok {
+			m.Rows = data.Rows
+		}
+	case "insert_on_duplicate_map":
+		m.Op = MutationUpsert
+		if data, ok := b.dmlData.(InsertOnDuplicateMapData); ok {
+			m.Rows = data.Rows
+		}
+	case "update", "update_ordered":
+		m.Op = MutationUpdate
+	case "delete":
+		m.Op = MutationDelete
+		if b.deleteTarget != nil && b.deleteTarget.Table != nil {
+			m.Table = b.deleteTarget.Table.rawName()
+		}
+	default:
+		return Mutation{}, false, nil
+	}
+	return m, true, nil
+}
+
+// PreImage 返回 UPDATE/DELETE 将要修改的行的查询：
+// 与原语句相同的表、JOIN、WHERE（包括乐观锁、软删除与租户条件）、ORDER BY 与 LIMIT，
+// 只查询被修改表的列，并加上 FOR UPDATE，在事务中执行可以锁定这些行
+// 不是 UPDATE/DELETE 时返回 nil
+func (s *SqlBuilder) PreImage() *SqlBuilder {
+	switch s.dmlType {
+	case "update", "update_ordered", "delete":
+	default:
+		return nil
+	}
+	b := s.Copy()
+	target := b
+	if b.deleteTarget != nil && b.deleteTarget != b {
+		target = b.deleteTarget
+	}
+	if len(b.JoinTable) > 0 || target != b {
+		name := target.Table.Label
+		if name == "" {
+			name = target.Table.GetName()
+		}
+		b.FieldParam = []string{name + ".*"}
+	} else {
+		b.FieldParam = nil
+	}
+	if b.version != nil && b.HasVersion() {
+		b.WhereParam = append(b.WhereParam, b.versionWhere())
+	}
+	b.dmlType = ""
+	b.dmlData = nil
+	b.deleteTarget = nil
+	b.customSetClauses = nil
+	b.version = nil
+	if b.forceDelete {
+		// 物理删除同样会删除已软删除的行
+		b.forceDelete = false
+		b.trashed = trashedWith
+	}
+	return b.ForUpdate()
+}
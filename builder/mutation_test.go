@@ -0,0 +1,49 @@
+package builder
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMutation(t *testing.T) {
+	ctx := context.Background()
+	m, ok, err := Table("t_user").InsertMany([]map[string]any{{"name": "a"}, {"name": "b"}}).Mutation(ctx)
+	if err != nil || !ok || m.Op != MutationInsert || m.Table != "t_user" || len(m.Rows) != 2 {
+		t.Fatalf("insert mutation = %+v, %v, %v", m, ok, err)
+	}
+	tb := Table("t_user")
+	if m, ok, _ = tb.Where(tb.Field("id").Eq(1)).UpdateMap(map[string]any{"name": "x"}).Mutation(ctx); !ok || m.Op != MutationUpdate {
+		t.Fatalf("update mutation = %+v", m)
+	}
+	if _, ok, _ = Table("t_user").Select("id").Mutation(ctx); ok {
+		t.Fatal("select is not a mutation")
+	}
+}
+
+func TestPreImage(t *testing.T) {
+	tb := Table("t_user")
+	b := tb.Where(tb.Field("age").Gt(18, "min_age")).Order(tb.Field("id").Asc()).Limit(10).
+		UpdateMap(map[string]any{"name": "x"}).Version("version", 3)
+	sql, params := b.PreImage().Sql()
+	want := "SELECT * FROM `t_user` WHERE `t_user`.`age` > :min_age AND `t_user`.`version` = :version_expected ORDER BY `t_user`.`id` ASC LIMIT 10 FOR UPDATE"
+	if sql != want {
+		t.Fatalf("got  %s\nwant %s", sql, want)
+	}
+	if params["min_age"] != 18 || params["version_expected"] != 3 {
+		t.Fatalf("params = %v", params)
+	}
+	// 原构建器不受影响
+	if sql, _ := b.Sql(); sql == "" || sql[:6] != "UPDATE" {
+		t.Fatalf("original builder changed: %s", sql)
+	}
+
+	u := Table("t_user").As("u")
+	o := Table("t_order").As("o")
+	del := u.InnerJoin(o, u.Field("id").Eq(o.Field("user_id"))).Where(o.Field("status").Eq(0, "status")).Delete(o)
+	if sql, _ := del.PreImage().Sql(); sql != "SELECT o.* FROM `t_user` AS u inner join `t_order` AS o on u.`id` = o.`user_id` WHERE o.`status` = :status FOR UPDATE" {
+		t.Fatalf("join pre-image: %s", sql)
+	}
+	if Table("t_user").InsertMap(map[string]any{"a": 1}).PreImage() != nil {
+		t.Fatal("insert has no pre-image")
+	}
+}
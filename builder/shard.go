@@ -0,0 +1,473 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrShardKeyRequired 分片表无法从 Where/插入数据中确定唯一的分片
+var ErrShardKeyRequired = errors.New("builder: shard key required")
+
+// ErrShardScatter 查询需要跨分片执行，但不满足跨分片查询的条件
+var ErrShardScatter = errors.New("builder: scatter query not supported")
+
+// ShardStrategy 分片策略：根据分片键的值计算物理表后缀
+type ShardStrategy interface {
+	Shard(value any) (string, error) // 返回物理表后缀
+	Shards() []string                // 所有后缀，跨分片查询时使用
+}
+
+// ShardRule 逻辑表的分片规则，物理表名为 <逻辑表>_<后缀>
+type ShardRule struct {
+	Column   string        // 分片键列名
+	Strategy ShardStrategy // 分片策略
+	// Scatter 为 true 时，没有分片键（或 in 命中多个分片）的 SELECT 会改写为
+	// 对各分片 UNION ALL 后在外层统一排序与分页；为 false 时返回 ErrShardKeyRequired
+	Scatter bool
+}
+
+var shardRegistry = struct {
+	sync.RWMutex
+	rules map[string]ShardRule // 逻辑表名 -> 规则
+}{rules: map[string]ShardRule{}}
+
+// RegisterShardRule 注册逻辑表的分片规则
+// 注册后 builder.Table(table) 在构建时（SqlContext/Sql）根据 Where 中分片键的 Eq/In 条件
+// 或 InsertMap/InsertMany 中分片键的值解析出物理表，例如 t_order -> t_order_05
+func RegisterShardRule(table string, rule ShardRule) {
+	shardRegistry.Lock()
+	shardRegistry.rules[table] = rule
+	shardRegistry.Unlock()
+}
+
+// UnregisterShardRule 取消逻辑表的分片规则
+func UnregisterShardRule(table string) {
+	shardRegistry.Lock()
+	delete(shardRegistry.rules, table)
+	shardRegistry.Unlock()
+}
+
+func shardRuleFor(table string) (ShardRule, bool) {
+	if table == "" {
+		return ShardRule{}, false
+	}
+	shardRegistry.RLock()
+	defer shardRegistry.RUnlock()
+	rule, ok := shardRegistry.rules[table]
+	return rule, ok
+}
+
+func hasShardRules() bool {
+	shardRegistry.RLock()
+	defer shardRegistry.RUnlock()
+	return len(shardRegistry.rules) > 0
+}
+
+// -------------------- 分片策略 --------------------
+
+// HashShard 按哈希取模分片：整数直接取模，其它类型取 fnv32a 哈希后取模
+// 后缀按 Width 补零，Width 为 0 时取 Count-1 的位数，例如 Count=64 -> 00..63
+type HashShard struct {
+	Count int
+	Width int
+}
+
+func (h HashShard) Shard(value any) (string, error) {
+	if h.Count <= 0 {
+		return "", fmt.Errorf("builder: HashShard count must be positive")
+	}
+	var n uint64
+	if i, ok := toInt64(value); ok {
+		if i < 0 {
+			i = -i
+		}
+		n = uint64(i) % uint64(h.Count)
+	} else {
+		hs := fnv.New32a()
+		_, _ = hs.Write([]byte(fmt.Sprint(value)))
+		n = uint64(hs.Sum32()) % uint64(h.Count)
+	}
+	return h.suffix(int(n)), nil
+}
+
+func (h HashShard) Shards() []string {
+	shards := make([]string, 0, h.Count)
+	for i := 0; i < h.Count; i++ {
+		shards = append(shards, h.suffix(i))
+	}
+	return shards
+}
+
+func (h HashShard) suffix(n int) string {
+	width := h.Width
+	if width <= 0 {
+		width = len(strconv.Itoa(h.Count - 1))
+	}
+	s := strconv.Itoa(n)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+	return s
+}
+
+// ShardRange 范围分片的一个区间 [Min, Max)
+type ShardRange struct {
+	Min    int64
+	Max    int64
+	Suffix string
+}
+
+// RangeShard 按整数范围分片
+type RangeShard struct {
+	Ranges []ShardRange
+}
+
+func (r RangeShard) Shard(value any) (string, error) {
+	i, ok := toInt64(value)
+	if !ok {
+		return "", fmt.Errorf("builder: RangeShard requires integer shard key, got %T", value)
+	}
+	for _, rg := range r.Ranges {
+		if i >= rg.Min && i < rg.Max {
+			return rg.Suffix, nil
+		}
+	}
+	return "", fmt.Errorf("builder: RangeShard no range for %d", i)
+}
+
+func (r RangeShard) Shards() []string {
+	shards := make([]string, 0, len(r.Ranges))
+	for _, rg := range r.Ranges {
+		shards = append(shards, rg.Suffix)
+	}
+	return shards
+}
+
+// DateShard 按日期分片，后缀为分片键按 Layout 格式化的结果
+// 例如 Layout="200601" 按月分表：t_log_202401；From/To 用于跨分片查询时枚举所有分片
+type DateShard struct {
+	Layout   string
+	Unit     string // day | month | year，枚举分片时的步长，默认 month
+	From     time.Time
+	To       time.Time
+	Location *time.Location // 解析/格式化使用的时区，默认 time.Local
+}
+
+func (d DateShard) Shard(value any) (string, error) {
+	loc := d.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		var err error
+		if t, err = time.ParseInLocation(time.DateTime, v, loc); err != nil {
+			if t, err = time.ParseInLocation(time.DateOnly, v, loc); err != nil {
+				return "", fmt.Errorf("builder: DateShard cannot parse %q", v)
+			}
+		}
+	default:
+		i, ok := toInt64(value)
+		if !ok {
+			return "", fmt.Errorf("builder: DateShard unsupported shard key %T", value)
+		}
+		t = time.Unix(i, 0)
+	}
+	return t.In(loc).Format(d.Layout), nil
+}
+
+func (d DateShard) Shards() []string {
+	var shards []string
+	seen := map[string]struct{}{}
+	for t := d.From; !t.After(d.To); {
+		suffix, _ := d.Shard(t)
+		if _, ok := seen[suffix]; !ok {
+			seen[suffix] = struct{}{}
+			shards = append(shards, suffix)
+		}
+		switch d.Unit {
+		case "day":
+			t = t.AddDate(0, 0, 1)
+		case "year":
+			t = t.AddDate(1, 0, 0)
+		default:
+			t = t.AddDate(0, 1, 0)
+		}
+	}
+	return shards
+}
+
+func toInt64(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// -------------------- 构建时解析 --------------------
+
+// resolveShards 在已拷贝的构建树上把逻辑表替换为物理表
+// top 表示最外层构建器，只有最外层的 SELECT 允许跨分片查询
+func (s *SqlBuilder) resolveShards(top bool) error {
+	if s == nil {
+		return nil
+	}
+	for i := range s.JoinTable {
+		sub := &s.JoinTable[i].SubTable
+		if sub.label == "" {
+			if _, ok := shardRuleFor(sub.Table.rawName()); ok {
+				return fmt.Errorf("%w: join on sharded table %s, use a labeled subquery with the shard key", ErrShardKeyRequired, sub.Table.rawName())
+			}
+			continue
+		}
+		if err := sub.resolveShards(false); err != nil {
+			return err
+		}
+	}
+	if err := s.FromSubQuery.resolveShards(false); err != nil {
+		return err
+	}
+	for i := range s.UnionBuilder {
+		if err := s.UnionBuilder[i].Table.resolveShards(false); err != nil {
+			return err
+		}
+	}
+	if s.Table == nil {
+		return nil
+	}
+	logical := s.Table.rawName()
+	rule, ok := shardRuleFor(logical)
+	if !ok {
+		return nil
+	}
+
+	suffixes, err := s.shardSuffixes(rule)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(suffixes) == 1:
+		s.usePhysicalTable(logical, suffixes[0])
+		return nil
+	case !rule.Scatter || !top || s.dmlType != "":
+		return fmt.Errorf("%w: table %s column %s", ErrShardKeyRequired, logical, rule.Column)
+	case len(s.GroupParam) > 0 || s.HavingParam != nil:
+		return fmt.Errorf("%w: group by on sharded table %s", ErrShardScatter, logical)
+	}
+	if len(suffixes) == 0 {
+		suffixes = rule.Strategy.Shards()
+	}
+	s.scatterShards = suffixes
+	return nil
+}
+
+// shardSuffixes 从插入数据或 Where 条件中解析分片后缀（去重排序）
+// 返回空表示没有找到分片键
+func (s *SqlBuilder) shardSuffixes(rule ShardRule) ([]string, error) {
+	var values []any
+	switch data := s.dmlData.(type) {
+	case map[string]any:
+		if s.dmlType == "insert" || s.dmlType == "insert_ignore" {
+			values = []any{data[rule.Column]}
+		}
+	case []map[string]any:
+		if s.dmlType == "insert_many" || s.dmlType == "insert_ignore_many" {
+			for _, row := range data {
+				values = append(values, row[rule.Column])
+			}
+		}
+	case InsertOnDuplicateColsData:
+		for _, row := range data.Rows {
+			values = append(values, row[rule.Column])
+		}
+	case InsertOnDuplicateMapData:
+		for _, row := range data.Rows {
+			values = append(values, row[rule.Column])
+		}
+	}
+	if len(values) == 0 {
+		values = s.shardKeyValues(rule.Column)
+	}
+
+	seen := map[string]struct{}{}
+	var suffixes []string
+	for _, v := range values {
+		if v == nil {
+			return nil, fmt.Errorf("%w: table %s column %s is nil", ErrShardKeyRequired, s.Table.rawName(), rule.Column)
+		}
+		suffix, err := rule.Strategy.Shard(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[suffix]; !ok {
+			seen[suffix] = struct{}{}
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	if len(suffixes) > 1 && s.dmlType != "" {
+		// DML 只能落在一个分片上
+		return nil, fmt.Errorf("%w: %s %s spans shards %v", ErrShardKeyRequired, s.dmlType, s.Table.rawName(), suffixes)
+	}
+	sort.Strings(suffixes)
+	return suffixes, nil
+}
+
+// shardKeyValues 从顶层 Where 条件中找出分片键的 Eq/In 值
+func (s *SqlBuilder) shardKeyValues(column string) []any {
+	var values []any
+	for _, expr := range s.WhereParam {
+		c, ok := expr.(Condition)
+		if !ok || c.op == "" || !s.isOwnColumn(c.Name, column) {
+			continue
+		}
+		switch c.arg.(type) {
+		case Field, Expr:
+			continue
+		}
+		if c.op == "in" {
+			rv := reflect.ValueOf(c.arg)
+			if rv.Kind() != reflect.Slice {
+				continue
+			}
+			for i := 0; i < rv.Len(); i++ {
+				values = append(values, rv.Index(i).Interface())
+			}
+			// 多个 in 条件时取第一个即可
+			return values
+		}
+		return []any{c.arg}
+	}
+	return values
+}
+
+// isOwnColumn 判断条件中的列名是否为本表的 column
+func (s *SqlBuilder) isOwnColumn(name, column string) bool {
+	name = strings.ReplaceAll(name, "`", "")
+	qualifier := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		qualifier, name = name[:i], name[i+1:]
+	}
+	if name != column {
+		return false
+	}
+	if qualifier == "" {
+		return true
+	}
+	return qualifier == s.label || qualifier == s.Table.Label || qualifier == s.Table.rawName()
+}
+
+// usePhysicalTable 将表替换为物理表，并用逻辑表名作别名，保证已生成的字段限定名仍然可用
+func (s *SqlBuilder) usePhysicalTable(logical, suffix string) {
+	s.Table.logical = logical
+	s.Table.Name = ColumnNameHandler(strings.TrimPrefix(logical, s.Table.Db+".") + "_" + suffix)
+	if strings.HasPrefix(s.dmlType, "insert") {
+		return
+	}
+	if s.Table.Label == "" {
+		alias := logical
+		if i := strings.LastIndex(alias, "."); i >= 0 {
+			alias = alias[i+1:]
+		}
+		s.Table.Label = alias
+		if s.dmlType == "delete" && s.deleteTarget == nil {
+			// 单表 DELETE 不能使用别名，改写为 DELETE alias FROM table AS alias
+			s.deleteTarget = s
+		}
+	}
+}
+
+var orderQualifierRe = regexp.MustCompile("^(?:[^\\s.`]+|`[^`]+`)\\.(`[^`]+`|[\\w]+)(\\s+(?i:ASC|DESC))?$")
+
+// scatter 将跨分片查询改写为: SELECT * FROM ((分片0) UNION ALL (分片1) ...) shard ORDER BY ... LIMIT ...
+// 每个分片内先按同样的排序取前 offset+limit 行，外层再统一排序与分页
+func (s *SqlBuilder) scatter() (*SqlBuilder, error) {
+	outerOrder := make([]Field, 0, len(s.OrderParam))
+	for _, op := range s.OrderParam {
+		str := strings.TrimSpace(op.String())
+		if m := orderQualifierRe.FindStringSubmatch(str); m != nil {
+			str = m[1] + m[2]
+		} else if strings.ContainsAny(str, "(.") {
+			return nil, fmt.Errorf("%w: order by expression %q", ErrShardScatter, str)
+		}
+		outerOrder = append(outerOrder, Fd{s: str})
+	}
+
+	logical := s.Table.rawName()
+	outer := Table("").Label("shard")
+	outer.scope = s.scope
+	for _, suffix := range s.scatterShards {
+		shard := *s
+		shard.scatterShards = nil
+		tbl := *s.Table
+		shard.Table = &tbl
+		shard.usePhysicalTable(logical, suffix)
+		if s.LimitParam > 0 {
+			shard.LimitParam = s.LimitParam + s.OffsetParam
+			shard.OffsetParam = 0
+		}
+		outer.Union(&shard, "UNION ALL ")
+	}
+	outer.OrderParam = outerOrder
+	outer.LimitParam = s.LimitParam
+	outer.OffsetParam = s.OffsetParam
+	return outer, nil
+}
+
+// ShardKey 返回构建器中分片键 column 的唯一取值：
+// 优先取插入数据中的值（多行时要求所有行一致），其次取 Where 中的 Eq 条件
+func (s *SqlBuilder) ShardKey(column string) (any, bool) {
+	switch data := s.dmlData.(type) {
+	case map[string]any:
+		if s.dmlType == "insert" || s.dmlType == "insert_ignore" {
+			v, ok := data[column]
+			return v, ok && v != nil
+		}
+	case []map[string]any:
+		if s.dmlType == "insert_many" || s.dmlType == "insert_ignore_many" {
+			return sameRowValue(data, column)
+		}
+	case InsertOnDuplicateColsData:
+		return sameRowValue(data.Rows, column)
+	case InsertOnDuplicateMapData:
+		return sameRowValue(data.Rows, column)
+	}
+	if s.Table == nil {
+		return nil, false
+	}
+	values := s.shardKeyValues(column)
+	if len(values) != 1 || values[0] == nil {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func sameRowValue(rows []map[string]any, column string) (any, bool) {
+	if len(rows) == 0 {
+		return nil, false
+	}
+	first, ok := rows[0][column]
+	if !ok || first == nil {
+		return nil, false
+	}
+	for _, row := range rows[1:] {
+		if !reflect.DeepEqual(row[column], first) {
+			return nil, false
+		}
+	}
+	return first, true
+}
@@ -0,0 +1,148 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func registerOrderShards(t *testing.T, scatter bool) {
+	RegisterShardRule("t_sh_order", ShardRule{Column: "user_id", Strategy: HashShard{Count: 64}, Scatter: scatter})
+	t.Cleanup(func() { UnregisterShardRule("t_sh_order") })
+}
+
+func TestShard_SelectByKey(t *testing.T) {
+	registerOrderShards(t, false)
+
+	o := Table("t_sh_order")
+	sql, params := o.Select(o.Field("id")).Where(o.Field("user_id").Eq(130, "uid"), o.Field("status").Eq(1)).Sql()
+	want := "SELECT `t_sh_order`.`id` FROM `t_sh_order_02` AS t_sh_order WHERE `t_sh_order`.`user_id` = :uid AND `t_sh_order`.`status` = 1"
+	if sql != want || params["uid"] != 130 {
+		t.Fatalf("shard select:\n got: %s\nwant: %s", sql, want)
+	}
+	if o.Table.Name != "`t_sh_order`" {
+		t.Fatal("caller builder must keep the logical table")
+	}
+}
+
+func TestShard_InsertUpdateDelete(t *testing.T) {
+	registerOrderShards(t, false)
+
+	sql, _ := Table("t_sh_order").InsertMap(map[string]any{"user_id": int64(65), "no": "a"}).Sql()
+	if !strings.HasPrefix(sql, "INSERT INTO `t_sh_order_01` (") {
+		t.Fatalf("shard insert: %s", sql)
+	}
+
+	_, _, err := Table("t_sh_order").InsertMany([]map[string]any{{"user_id": 1}, {"user_id": 2}}).SqlContext(context.Background())
+	if !errors.Is(err, ErrShardKeyRequired) {
+		t.Fatalf("insert across shards should fail, got %v", err)
+	}
+
+	o := Table("t_sh_order")
+	sql, _ = o.Where(o.Field("user_id").Eq(3)).UpdateMap(map[string]any{"no": "b"}).Sql()
+	if !strings.HasPrefix(sql, "UPDATE `t_sh_order_03` AS t_sh_order SET") {
+		t.Fatalf("shard update: %s", sql)
+	}
+
+	o = Table("t_sh_order")
+	sql, _ = o.Where(o.Field("user_id").Eq(3)).Delete().Sql()
+	if want := "DELETE t_sh_order FROM `t_sh_order_03` AS t_sh_order WHERE `t_sh_order`.`user_id` = 3"; sql != want {
+		t.Fatalf("shard delete:\n got: %s\nwant: %s", sql, want)
+	}
+
+	o = Table("t_sh_order")
+	_, _, err = o.Where(o.Field("status").Eq(1)).Delete().SqlContext(context.Background())
+	if !errors.Is(err, ErrShardKeyRequired) {
+		t.Fatalf("delete without shard key should fail, got %v", err)
+	}
+}
+
+func TestShard_Scatter(t *testing.T) {
+	RegisterShardRule("t_sh_log", ShardRule{Column: "user_id", Strategy: HashShard{Count: 3}, Scatter: true})
+	t.Cleanup(func() { UnregisterShardRule("t_sh_log") })
+
+	l := Table("t_sh_log")
+	sql, _ := l.Select(l.Field("id"), l.Field("created_at")).
+		Where(l.Field("status").Eq(1)).
+		Order(l.Field("created_at").Desc()).
+		Limit(10).Offset(20).Sql()
+	want := "SELECT * FROM ( " +
+		"(SELECT `t_sh_log`.`id`, `t_sh_log`.`created_at` FROM `t_sh_log_0` AS t_sh_log WHERE `t_sh_log`.`status` = 1 ORDER BY `t_sh_log`.`created_at` DESC LIMIT 30) UNION ALL " +
+		"(SELECT `t_sh_log`.`id`, `t_sh_log`.`created_at` FROM `t_sh_log_1` AS t_sh_log WHERE `t_sh_log`.`status` = 1 ORDER BY `t_sh_log`.`created_at` DESC LIMIT 30) UNION ALL " +
+		"(SELECT `t_sh_log`.`id`, `t_sh_log`.`created_at` FROM `t_sh_log_2` AS t_sh_log WHERE `t_sh_log`.`status` = 1 ORDER BY `t_sh_log`.`created_at` DESC LIMIT 30)  ) shard " +
+		"ORDER BY `created_at` DESC LIMIT 20, 10"
+	if sql != want {
+		t.Fatalf("scatter:\n got: %s\nwant: %s", sql, want)
+	}
+
+	l = Table("t_sh_log")
+	sql, _ = l.Where(l.Field("user_id").In([]int{1, 4})).Sql()
+	if !strings.Contains(sql, "FROM `t_sh_log_1` AS t_sh_log") || strings.Contains(sql, "UNION") {
+		t.Fatalf("in on a single shard should be routed: %s", sql)
+	}
+
+	l = Table("t_sh_log")
+	_, _, err := l.Select(Count(l.Field("id"))).Group(l.Field("status")).SqlContext(context.Background())
+	if !errors.Is(err, ErrShardScatter) {
+		t.Fatalf("group by scatter should fail, got %v", err)
+	}
+}
+
+func TestShard_Strategies(t *testing.T) {
+	r := RangeShard{Ranges: []ShardRange{{Min: 0, Max: 100, Suffix: "a"}, {Min: 100, Max: 200, Suffix: "b"}}}
+	if s, err := r.Shard(150); err != nil || s != "b" {
+		t.Fatalf("RangeShard: %s %v", s, err)
+	}
+	if _, err := r.Shard(500); err == nil {
+		t.Fatal("RangeShard out of range should fail")
+	}
+
+	loc := time.UTC
+	d := DateShard{Layout: "200601", Location: loc,
+		From: time.Date(2024, 11, 1, 0, 0, 0, 0, loc), To: time.Date(2025, 2, 1, 0, 0, 0, 0, loc)}
+	if s, err := d.Shard("2024-12-15 10:00:00"); err != nil || s != "202412" {
+		t.Fatalf("DateShard: %s %v", s, err)
+	}
+	if got := strings.Join(d.Shards(), ","); got != "202411,202412,202501,202502" {
+		t.Fatalf("DateShard shards: %s", got)
+	}
+
+	h := HashShard{Count: 8, Width: 2}
+	if s, _ := h.Shard(9); s != "01" {
+		t.Fatalf("HashShard width: %s", s)
+	}
+	if a, _ := h.Shard("user-1"); a == "" {
+		t.Fatal("HashShard string key")
+	}
+}
+
+func TestShard_WithTenantAndSoftDelete(t *testing.T) {
+	RegisterShardRule("t_sh_scoped", ShardRule{Column: "user_id", Strategy: HashShard{Count: 2}})
+	RegisterTenantTables("tenant_id", "t_sh_scoped")
+	RegisterSoftDelete("deleted_at", "t_sh_scoped")
+	t.Cleanup(func() {
+		UnregisterShardRule("t_sh_scoped")
+		UnregisterTenantTables("t_sh_scoped")
+		UnregisterSoftDelete("t_sh_scoped")
+	})
+	ctx := WithTenant(context.Background(), 7)
+
+	tb := Table("t_sh_scoped")
+	sql, params, err := tb.Where(tb.Field("user_id").Eq(5, "uid")).SqlContext(ctx)
+	want := "SELECT * FROM `t_sh_scoped_1` AS t_sh_scoped WHERE `t_sh_scoped`.`user_id` = :uid AND t_sh_scoped.`deleted_at` is null AND t_sh_scoped.`tenant_id` = :tenant"
+	if err != nil || sql != want || params["tenant"] != 7 {
+		t.Fatalf("scoped shard select:\n got: %s %v\nwant: %s", sql, err, want)
+	}
+
+	if _, _, err := tb.Where(tb.Field("user_id").Eq(5, "uid")).SqlContext(context.Background()); !errors.Is(err, ErrTenantRequired) {
+		t.Fatalf("missing tenant: err = %v", err)
+	}
+
+	tb = Table("t_sh_scoped")
+	sql, _, err = tb.Where(tb.Field("user_id").Eq(5, "uid")).Delete().SqlContext(ctx)
+	if err != nil || !strings.HasPrefix(sql, "UPDATE `t_sh_scoped_1` AS t_sh_scoped SET `deleted_at` = NOW() WHERE ") {
+		t.Fatalf("scoped shard delete should be a soft delete: %s %v", sql, err)
+	}
+}
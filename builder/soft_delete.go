@@ -0,0 +1,122 @@
+package builder
+
+import (
+	"bytes"
+	"sync"
+)
+
+// trashedMode 软删除表的查询过滤方式
+type trashedMode int
+
+const (
+	trashedExclude trashedMode = iota // 默认：只查未删除的记录
+	trashedWith                       // 包含已删除的记录
+	trashedOnly                       // 只查已删除的记录
+)
+
+var softDeleteRegistry = struct {
+	sync.RWMutex
+	global string            // 全局软删除列，为空表示未开启
+	tables map[string]string // 表名 -> 软删除列
+}{tables: map[string]string{}}
+
+// SetGlobalSoftDelete 为所有通过 Table 创建的表开启软删除，column 为空表示关闭
+// 单独通过 RegisterSoftDelete 注册的表优先使用自己的列
+func SetGlobalSoftDelete(column string) {
+	softDeleteRegistry.Lock()
+	softDeleteRegistry.global = column
+	softDeleteRegistry.Unlock()
+}
+
+// RegisterSoftDelete 为指定表开启软删除，column 一般为 deleted_at
+// 开启后：
+//   - SELECT/UPDATE 以及 JOIN 该表时自动追加 `deleted_at` is null
+//   - Delete() 转换为 UPDATE ... SET `deleted_at` = NOW()
+//
+// 使用 WithTrashed/OnlyTrashed/ForceDelete 可以按次跳过
+func RegisterSoftDelete(column string, tables ...string) {
+	softDeleteRegistry.Lock()
+	for _, t := range tables {
+		softDeleteRegistry.tables[t] = column
+	}
+	softDeleteRegistry.Unlock()
+}
+
+// UnregisterSoftDelete 取消指定表的软删除配置
+func UnregisterSoftDelete(tables ...string) {
+	softDeleteRegistry.Lock()
+	for _, t := range tables {
+		delete(softDeleteRegistry.tables, t)
+	}
+	softDeleteRegistry.Unlock()
+}
+
+// softDeleteColumn 返回表配置的软删除列，未开启时返回空
+func softDeleteColumn(table string) string {
+	if table == "" {
+		return ""
+	}
+	softDeleteRegistry.RLock()
+	defer softDeleteRegistry.RUnlock()
+	if column, ok := softDeleteRegistry.tables[table]; ok {
+		return column
+	}
+	return softDeleteRegistry.global
+}
+
+// WithTrashed 查询时包含已软删除的记录
+func (s *SqlBuilder) WithTrashed() *SqlBuilder {
+	s.trashed = trashedWith
+	return s
+}
+
+// OnlyTrashed 只查询已软删除的记录
+func (s *SqlBuilder) OnlyTrashed() *SqlBuilder {
+	s.trashed = trashedOnly
+	return s
+}
+
+// ForceDelete 物理删除，即使表开启了软删除
+// 使用: builder.Table("user").Where(...).ForceDelete().Sql()
+func (s *SqlBuilder) ForceDelete(t ...*SqlBuilder) *SqlBuilder {
+	s.forceDelete = true
+	return s.Delete(t...)
+}
+
+// softDeleteTarget 返回 Delete 需要转换为软删除时使用的列，不需要时返回空
+func (s *SqlBuilder) softDeleteTarget() string {
+	if s.forceDelete || s.Table == nil {
+		return ""
+	}
+	target := s
+	if s.deleteTarget != nil {
+		target = s.deleteTarget
+	}
+	if target.Table == nil {
+		return ""
+	}
+	return softDeleteColumn(target.Table.ruleName())
+}
+
+// buildSoftDelete 构建软删除 SQL：UPDATE table SET `deleted_at` = NOW() WHERE ...
+func (s *SqlBuilder) buildSoftDelete(column string) (string, map[string]any) {
+	target := s
+	if s.deleteTarget != nil {
+		target = s.deleteTarget
+	}
+	tbl, tblParams := s.getTable()
+	whereStr, whereParams := s.getWhere()
+
+	var bf bytes.Buffer
+	bf.WriteString("UPDATE ")
+	bf.WriteString(tbl)
+	bf.WriteString(" SET ")
+	if target == s {
+		bf.WriteString(ColumnNameHandler(column))
+	} else {
+		bf.WriteString(target.Field(column).String())
+	}
+	bf.WriteString(" = NOW()")
+	bf.WriteString(whereStr)
+	return bf.String(), mergeParams(tblParams, whereParams)
+}
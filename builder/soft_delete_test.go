@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSoftDelete_Select(t *testing.T) {
+	RegisterSoftDelete("deleted_at", "t_sd_user", "t_sd_info")
+	t.Cleanup(func() { UnregisterSoftDelete("t_sd_user", "t_sd_info") })
+
+	tu := Table("t_sd_user").As("u")
+	ti := Table("t_sd_info").As("i")
+	sql, _ := tu.Select(tu.Field("id")).
+		LeftJoin(ti, tu.Field("id").Eq(ti.Field("user_id"))).
+		Where(tu.Field("id").Gt(0)).Sql()
+
+	want := "SELECT u.`id` FROM `t_sd_user` AS u left join `t_sd_info` AS i on (u.`id` = i.`user_id` AND i.`deleted_at` is null) WHERE u.`id` > 0 AND u.`deleted_at` is null"
+	if sql != want {
+		t.Fatalf("soft delete select:\n got: %s\nwant: %s", sql, want)
+	}
+
+	tw := Table("t_sd_user")
+	sql, _ = tw.Where(tw.Field("id").Eq(1)).WithTrashed().Sql()
+	if strings.Contains(sql, "deleted_at") {
+		t.Fatalf("WithTrashed should not filter: %s", sql)
+	}
+
+	to := Table("t_sd_user")
+	sql, _ = to.OnlyTrashed().Sql()
+	if !strings.HasSuffix(sql, "WHERE `t_sd_user`.`deleted_at` is not null") {
+		t.Fatalf("OnlyTrashed unexpected: %s", sql)
+	}
+}
+
+func TestSoftDelete_SubQuery(t *testing.T) {
+	RegisterSoftDelete("deleted_at", "t_sd_order")
+	t.Cleanup(func() { UnregisterSoftDelete("t_sd_order") })
+
+	sub := Table("t_sd_order").Select("user_id").Label("o")
+	q := Table("").FromSub(sub)
+	sql, _ := q.Select(q.Field("user_id")).Sql()
+	want := "SELECT o.`user_id` FROM (SELECT user_id FROM `t_sd_order` WHERE `t_sd_order`.`deleted_at` is null) o"
+	if sql != want {
+		t.Fatalf("subquery should be scoped with the inner table:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestSoftDelete_Delete(t *testing.T) {
+	RegisterSoftDelete("deleted_at", "t_sd_user")
+	t.Cleanup(func() { UnregisterSoftDelete("t_sd_user") })
+
+	tu := Table("t_sd_user")
+	sql, params := tu.Where(tu.Field("id").Eq(1, "id")).Delete().Sql()
+	want := "UPDATE `t_sd_user` SET `deleted_at` = NOW() WHERE `t_sd_user`.`id` = :id AND `t_sd_user`.`deleted_at` is null"
+	if sql != want || params["id"] != 1 {
+		t.Fatalf("soft delete:\n got: %s %v\nwant: %s", sql, params, want)
+	}
+
+	tf := Table("t_sd_user")
+	sql, _ = tf.Where(tf.Field("id").Eq(1, "id")).ForceDelete().Sql()
+	if want := "DELETE  FROM `t_sd_user` WHERE `t_sd_user`.`id` = :id"; sql != want {
+		t.Fatalf("force delete:\n got: %s\nwant: %s", sql, want)
+	}
+}
+
+func TestSoftDelete_Update(t *testing.T) {
+	RegisterSoftDelete("deleted_at", "t_sd_user")
+	t.Cleanup(func() { UnregisterSoftDelete("t_sd_user") })
+
+	tu := Table("t_sd_user")
+	sql, _ := tu.Where(tu.Field("id").Eq(1, "id")).UpdateMap(map[string]any{"name": "a"}).Sql()
+	if !strings.HasSuffix(sql, "WHERE `t_sd_user`.`id` = :id AND `t_sd_user`.`deleted_at` is null") {
+		t.Fatalf("soft delete update unexpected: %s", sql)
+	}
+}
@@ -407,3 +407,23 @@ func TestUy(t *testing.T) {
 	fmt.Println()
 
 }
+
+func TestTables(t *testing.T) {
+	tu := Table("t_user").As("u")
+	ti := Table("t_user_info").Select("user_id").Label("info")
+	sub := Table("t_order").Select("user_id").Label("o")
+	q := Table("").FromSub(sub)
+	tu.LeftJoin(ti, tu.Field("id").Eq(ti.Field("user_id"))).
+		InnerJoin(q, tu.Field("id").Eq(q.Field("user_id")))
+
+	got := tu.Tables()
+	want := []string{"t_user", "t_user_info", "t_order"}
+	if len(got) != len(want) {
+		t.Fatalf("Tables() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Tables() = %v, want %v", got, want)
+		}
+	}
+}
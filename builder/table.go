@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"context"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ type table struct {
 	Name       string
 	Label      string
 	ForceIndex string
+	logical    string // 分片表替换为物理表后保留的逻辑表名（包含库名前缀）
 }
 
 func (t table) GetName() string {
@@ -25,6 +27,19 @@ func (t table) GetName() string {
 	return bf.String()
 }
 
+// rawName 返回去掉反引号的表名（包含库名前缀）
+func (t table) rawName() string {
+	return strings.ReplaceAll(t.GetName(), "`", "")
+}
+
+// ruleName 返回查找软删除、租户等注册规则时使用的表名：分片表为逻辑表名
+func (t table) ruleName() string {
+	if t.logical != "" {
+		return t.logical
+	}
+	return t.rawName()
+}
+
 type join struct {
 	JoinType string // left| right   默认 left
 	SubTable SqlBuilder
@@ -74,6 +89,28 @@ type SqlBuilder struct {
 	deleteTarget *SqlBuilder // Delete 操作的删除目标表（可选）
 
 	customSetClauses []setClause // 额外的 SET 子句
+
+	version *versionLock // 乐观锁版本列（可选）
+
+	trashed     trashedMode // 软删除过滤方式
+	forceDelete bool        // Delete 时执行物理删除
+	noScope     bool        // 作为普通 JOIN 表渲染时，作用域条件已放入 ON 中
+
+	timestamps   *TimestampConfig // 本次构建使用的时间戳约定（优先于 ctx 与全局配置）
+	noTimestamps bool             // 不自动填充时间戳
+
+	crossTenant bool        // 跨租户查询，不注入租户条件
+	scope       *buildScope // SqlContext 构建期间共享的状态
+
+	scatterShards []string // 需要跨分片查询的分片后缀（构建期间使用）
+
+	lock string // 行锁子句，例如 FOR UPDATE SKIP LOCKED
+}
+
+// versionLock 乐观锁配置
+type versionLock struct {
+	column   string
+	expected any
 }
 
 // As 设置表的别名
@@ -101,6 +138,13 @@ func (s *SqlBuilder) Copy() *SqlBuilder {
 		OffsetParam: s.OffsetParam,
 		label:       s.label,
 		dmlType:     s.dmlType,
+		trashed:     s.trashed,
+		forceDelete: s.forceDelete,
+		noScope:     s.noScope,
+
+		noTimestamps: s.noTimestamps,
+		crossTenant:  s.crossTenant,
+		lock:         s.lock,
 	}
 
 	// 深度拷贝 Table
@@ -213,7 +257,10 @@ func (s *SqlBuilder) Copy() *SqlBuilder {
 	}
 
 	// 深度拷贝 deleteTarget
-	if s.deleteTarget != nil {
+	if s.deleteTarget == s {
+		// Delete(自身) 时避免无限递归
+		copyObject.deleteTarget = copyObject
+	} else if s.deleteTarget != nil {
 		copyObject.deleteTarget = s.deleteTarget.Copy() // 递归拷贝
 	}
 
@@ -234,9 +281,57 @@ func (s *SqlBuilder) Copy() *SqlBuilder {
 		}
 	}
 
+	// 拷贝时间戳约定
+	if s.timestamps != nil {
+		ts := *s.timestamps
+		copyObject.timestamps = &ts
+	}
+
+	// 拷贝乐观锁配置
+	if s.version != nil {
+		copyObject.version = &versionLock{
+			column:   s.version.column,
+			expected: deepCopyValue(s.version.expected),
+		}
+	}
+
 	return copyObject
 }
 
+// Tables 返回该构建器引用的所有表名（去掉反引号，去重，保持出现顺序）
+// 包含主表、JOIN 表、FROM 子查询、UNION 以及 Delete 目标表
+// 注意：以字符串形式传入的子查询（如 In(field, "SELECT ...")）无法被识别
+func (s *SqlBuilder) Tables() []string {
+	seen := map[string]struct{}{}
+	var tables []string
+	s.collectTables(seen, &tables)
+	return tables
+}
+
+func (s *SqlBuilder) collectTables(seen map[string]struct{}, tables *[]string) {
+	if s == nil {
+		return
+	}
+	if s.Table != nil {
+		if name := s.Table.rawName(); name != "" {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				*tables = append(*tables, name)
+			}
+		}
+	}
+	for i := range s.JoinTable {
+		s.JoinTable[i].SubTable.collectTables(seen, tables)
+	}
+	s.FromSubQuery.collectTables(seen, tables)
+	for i := range s.UnionBuilder {
+		s.UnionBuilder[i].Table.collectTables(seen, tables)
+	}
+	if s.deleteTarget != s {
+		s.deleteTarget.collectTables(seen, tables)
+	}
+}
+
 // deepCopyExpr 深度拷贝 Expr 接口
 func deepCopyExpr(expr Expr) Expr {
 	if expr == nil {
@@ -248,6 +343,8 @@ func deepCopyExpr(expr Expr) Expr {
 		copyCond := Condition{
 			Name: cond.Name,
 			S:    cond.S,
+			op:   cond.op,
+			arg:  deepCopyValue(cond.arg),
 		}
 		// 深度拷贝 Value map
 		if cond.Value != nil {
@@ -580,6 +677,37 @@ func (s *SqlBuilder) First() *SqlBuilder {
 	return s
 }
 
+// ForUpdate 查询加排他锁（SELECT ... FOR UPDATE），需要在事务中执行
+func (s *SqlBuilder) ForUpdate() *SqlBuilder {
+	s.lock = "FOR UPDATE"
+	return s
+}
+
+// ForShare 查询加共享锁（SELECT ... FOR SHARE，MySQL 8.0+），需要在事务中执行
+func (s *SqlBuilder) ForShare() *SqlBuilder {
+	s.lock = "FOR SHARE"
+	return s
+}
+
+// SkipLocked 跳过已被其他事务锁定的行（MySQL 8.0+），没有调用 ForShare 时使用 FOR UPDATE
+func (s *SqlBuilder) SkipLocked() *SqlBuilder {
+	return s.lockOption("SKIP LOCKED")
+}
+
+// NoWait 行已被锁定时立即报错而不等待（MySQL 8.0+），没有调用 ForShare 时使用 FOR UPDATE
+func (s *SqlBuilder) NoWait() *SqlBuilder {
+	return s.lockOption("NOWAIT")
+}
+
+func (s *SqlBuilder) lockOption(option string) *SqlBuilder {
+	mode := "FOR UPDATE"
+	if strings.HasPrefix(s.lock, "FOR SHARE") {
+		mode = "FOR SHARE"
+	}
+	s.lock = mode + " " + option
+	return s
+}
+
 // Join
 // joinType  left|right
 func (s *SqlBuilder) join(table SqlBuilder, on Expr, joinType string) *SqlBuilder {
@@ -675,10 +803,9 @@ func (s *SqlBuilder) buildInsertMap() (string, map[string]any) {
 	cols := make([]string, 0, len(data))
 	phs := make([]string, 0, len(data))
 	insertParams := make(map[string]any, len(data))
-	for k, v := range data {
+	for _, k := range sortedKeys(data) {
 		cols = append(cols, ColumnNameHandler(k))
-		phs = append(phs, ":"+k)
-		insertParams[k] = v
+		phs = append(phs, insertValue(k, data[k], insertParams))
 	}
 
 	// 使用统一的参数合并方法
@@ -707,10 +834,9 @@ func (s *SqlBuilder) buildInsertIgnoreMap() (string, map[string]any) {
 	cols := make([]string, 0, len(data))
 	phs := make([]string, 0, len(data))
 	insertParams := make(map[string]any, len(data))
-	for k, v := range data {
+	for _, k := range sortedKeys(data) {
 		cols = append(cols, ColumnNameHandler(k))
-		phs = append(phs, ":"+k)
-		insertParams[k] = v
+		phs = append(phs, insertValue(k, data[k], insertParams))
 	}
 
 	// 使用统一的参数合并方法
@@ -726,6 +852,27 @@ func (s *SqlBuilder) buildInsertIgnoreMap() (string, map[string]any) {
 	return bf.String(), params
 }
 
+// insertValue 返回 VALUES 中 key 对应的内容并写入参数
+// 普通值使用占位符 :key；字段/函数（如 Now()）以及 SetExpr 表达式直接内联
+func insertValue(key string, v any, params map[string]any) string {
+	switch val := v.(type) {
+	case SetExpression:
+		for k, pv := range val.parameters() {
+			params[k] = pv
+		}
+		return val.expression()
+	case Field:
+		if ve := val.Values(); ve != nil {
+			for k, pv := range *ve {
+				params[k] = pv
+			}
+		}
+		return val.String()
+	}
+	params[key] = v
+	return ":" + key
+}
+
 // InsertMany 设置多行插入操作，返回 *SqlBuilder 以支持链式调用
 // 使用: sql, params := builder.Table("user").InsertMany(rows).Sql()
 func (s *SqlBuilder) InsertMany(rows []map[string]any) *SqlBuilder {
@@ -773,12 +920,7 @@ func (s *SqlBuilder) buildInsertMany() (string, map[string]any) {
 		placeholders := make([]string, 0, len(cols))
 		for _, c := range cols {
 			key := c + "_" + strconv.Itoa(i)
-			placeholders = append(placeholders, ":"+key)
-			if v, ok := row[c]; ok {
-				insertParams[key] = v
-			} else {
-				insertParams[key] = nil
-			}
+			placeholders = append(placeholders, insertValue(key, row[c], insertParams))
 		}
 		valuesTuples = append(valuesTuples, "("+strings.Join(placeholders, ", ")+")")
 	}
@@ -824,12 +966,7 @@ func (s *SqlBuilder) buildInsertIgnoreMany() (string, map[string]any) {
 		placeholders := make([]string, 0, len(cols))
 		for _, c := range cols {
 			key := c + "_" + strconv.Itoa(i)
-			placeholders = append(placeholders, ":"+key)
-			if v, ok := row[c]; ok {
-				insertParams[key] = v
-			} else {
-				insertParams[key] = nil
-			}
+			placeholders = append(placeholders, insertValue(key, row[c], insertParams))
 		}
 		valuesTuples = append(valuesTuples, "("+strings.Join(placeholders, ", ")+")")
 	}
@@ -882,8 +1019,8 @@ func (s *SqlBuilder) buildUpdateMap() (string, map[string]any) {
 	setParts := make([]string, 0, len(set)+len(s.customSetClauses))
 	var setParams map[string]any
 	if ok {
-		for k, v := range set {
-			part, params := buildSetAssignment(k, v)
+		for _, k := range sortedKeys(set) {
+			part, params := buildSetAssignment(k, set[k])
 			setParts = append(setParts, part)
 			if params != nil {
 				setParams = mergeParams(setParams, params)
@@ -900,7 +1037,12 @@ func (s *SqlBuilder) buildUpdateMap() (string, map[string]any) {
 		return "", nil
 	}
 	// WHERE 子句
-	whereStr, whereParams := s.getWhere()
+	var versionWhere []Expr
+	if s.version != nil {
+		setParts = append(setParts, s.versionSet())
+		versionWhere = append(versionWhere, s.versionWhere())
+	}
+	whereStr, whereParams := s.getWhere(versionWhere...)
 
 	// 使用统一的参数合并方法
 	params := mergeParams(setParams, tblParams, whereParams)
@@ -939,8 +1081,8 @@ func (s *SqlBuilder) buildUpdateOrdered() (string, map[string]any) {
 	var setParams map[string]any
 	if ok {
 		for _, item := range orderedSet {
-			for k, v := range item {
-				part, params := buildSetAssignment(k, v)
+			for _, k := range sortedKeys(item) {
+				part, params := buildSetAssignment(k, item[k])
 				setParts = append(setParts, part)
 				if params != nil {
 					setParams = mergeParams(setParams, params)
@@ -958,7 +1100,12 @@ func (s *SqlBuilder) buildUpdateOrdered() (string, map[string]any) {
 		return "", nil
 	}
 	// WHERE 子句
-	whereStr, whereParams := s.getWhere()
+	var versionWhere []Expr
+	if s.version != nil {
+		setParts = append(setParts, s.versionSet())
+		versionWhere = append(versionWhere, s.versionWhere())
+	}
+	whereStr, whereParams := s.getWhere(versionWhere...)
 
 	// 使用统一的参数合并方法
 	params := mergeParams(setParams, tblParams, whereParams)
@@ -972,6 +1119,30 @@ func (s *SqlBuilder) buildUpdateOrdered() (string, map[string]any) {
 	return bf.String(), params
 }
 
+// Version 为 UpdateMap/UpdateOrdered 开启乐观锁
+// 会在 SET 中追加 `version` = `version` + 1，并在 WHERE 中追加 `version` = :version_expected
+// 执行时若影响行数为 0，MysqlClient.ExecByBuilder 会返回 ErrStaleObject
+// 使用: builder.Table("user").Where(...).UpdateMap(data).Version("version", 3)
+func (s *SqlBuilder) Version(column string, expected any) *SqlBuilder {
+	s.version = &versionLock{column: column, expected: expected}
+	return s
+}
+
+// HasVersion 是否为带乐观锁版本列的更新
+func (s *SqlBuilder) HasVersion() bool {
+	return s.version != nil && (s.dmlType == "update" || s.dmlType == "update_ordered")
+}
+
+// versionSet 乐观锁的 SET 部分：`version` = `version` + 1
+func (s *SqlBuilder) versionSet() string {
+	return ColumnNameHandler(s.version.column) + " = " + s.Field(s.version.column).String() + " + 1"
+}
+
+// versionWhere 乐观锁的 WHERE 部分：`version` = :version_expected
+func (s *SqlBuilder) versionWhere() Expr {
+	return s.Field(s.version.column).Eq(s.version.expected, s.version.column+"_expected")
+}
+
 func buildSetAssignment(column string, value any) (string, map[string]any) {
 	return buildSetAssignmentWithPlaceholder(column, value, column)
 }
@@ -1068,12 +1239,7 @@ func (s *SqlBuilder) buildInsertOnDuplicateCols() (string, map[string]any) {
 		placeholders := make([]string, 0, len(cols))
 		for _, c := range cols {
 			key := c + "_" + strconv.Itoa(i)
-			placeholders = append(placeholders, ":"+key)
-			if v, ok := row[c]; ok {
-				insertParams[key] = v
-			} else {
-				insertParams[key] = nil
-			}
+			placeholders = append(placeholders, insertValue(key, row[c], insertParams))
 		}
 		valuesTuples = append(valuesTuples, "("+strings.Join(placeholders, ", ")+")")
 	}
@@ -1147,12 +1313,7 @@ func (s *SqlBuilder) buildInsertOnDuplicateMap() (string, map[string]any) {
 		placeholders := make([]string, 0, len(cols))
 		for _, c := range cols {
 			key := c + "_" + strconv.Itoa(i)
-			placeholders = append(placeholders, ":"+key)
-			if v, ok := row[c]; ok {
-				insertParams[key] = v
-			} else {
-				insertParams[key] = nil
-			}
+			placeholders = append(placeholders, insertValue(key, row[c], insertParams))
 		}
 		valuesTuples = append(valuesTuples, "("+strings.Join(placeholders, ", ")+")")
 	}
@@ -1160,9 +1321,9 @@ func (s *SqlBuilder) buildInsertOnDuplicateMap() (string, map[string]any) {
 	// 处理 UPDATE 部分的参数
 	updateParams := make(map[string]any, len(data.Update))
 	upd := make([]string, 0, len(data.Update))
-	for k, v := range data.Update {
+	for _, k := range sortedKeys(data.Update) {
 		placeholder := k + "_upd"
-		part, params := buildSetAssignmentWithPlaceholder(k, v, placeholder)
+		part, params := buildSetAssignmentWithPlaceholder(k, data.Update[k], placeholder)
 		upd = append(upd, part)
 		if params != nil {
 			updateParams = mergeParams(updateParams, params)
@@ -1239,7 +1400,16 @@ func (s *SqlBuilder) getTable() (string, map[string]any) {
 			bf.WriteString(" ")
 			bf.WriteString(jt.JoinType)
 			bf.WriteString(" join ")
-			tb, paramsData := jt.SubTable.subQuery() // 这里可能是 子查询
+			sub := jt.SubTable
+			on := jt.On
+			if sub.label == "" {
+				// 普通表 JOIN：软删除等作用域条件放到 ON 中
+				if scoped := sub.scopeConditions(); len(scoped) > 0 {
+					on = And(append([]Expr{on}, scoped...)...)
+				}
+				sub.noScope = true
+			}
+			tb, paramsData := sub.subQuery() // 这里可能是 子查询
 			if jt.SubTable.label != "" {
 				bf.WriteString("(")
 				bf.WriteString(tb)
@@ -1254,8 +1424,8 @@ func (s *SqlBuilder) getTable() (string, map[string]any) {
 				}
 			}
 			bf.WriteString(" on ")
-			bf.WriteString(jt.On.String())
-			if ve := jt.On.Values(); ve != nil {
+			bf.WriteString(on.String())
+			if ve := on.Values(); ve != nil {
 				for k, v := range *ve {
 					value[k] = v
 				}
@@ -1266,7 +1436,7 @@ func (s *SqlBuilder) getTable() (string, map[string]any) {
 		endLen := 0
 		bf.WriteString("( ")
 		for _, tb := range s.UnionBuilder {
-			tbt, paramsData := tb.Table.Sql()
+			tbt, paramsData := tb.Table.build() // 整个构建树已在 prepare 中统一处理
 			bf.WriteString("(")
 			bf.WriteString(tbt)
 			bf.WriteString(") ")
@@ -1288,15 +1458,54 @@ func (s *SqlBuilder) getTable() (string, map[string]any) {
 	return bf.String(), value
 }
 
-func (s *SqlBuilder) getWhere() (string, map[string]any) {
+// scopeConditions 返回需要自动追加到 WHERE（或 JOIN ON）的作用域条件：软删除与多租户
+func (s *SqlBuilder) scopeConditions() []Expr {
+	if s.noScope || s.Table == nil {
+		return nil
+	}
+	var conds []Expr
+	// ForceDelete 物理删除时不区分是否已软删除
+	if column := softDeleteColumn(s.Table.ruleName()); column != "" && !s.forceDelete {
+		switch s.trashed {
+		case trashedExclude:
+			conds = append(conds, s.scopeField(column).IsNull())
+		case trashedOnly:
+			conds = append(conds, s.scopeField(column).IsNotNull())
+		}
+	}
+	if cond := s.tenantCondition(); cond != nil {
+		conds = append(conds, cond)
+	}
+	return conds
+}
+
+// scopeField 作用域条件使用的字段：按表自身的别名或表名限定，
+// 不使用子查询的外部别名（Label），条件位于子查询内部时外部别名不可见
+func (s *SqlBuilder) scopeField(field string) Fd {
+	if s.Table.Label != "" {
+		return NewField(s.Table.Label + "." + field)
+	}
+	if name := s.Table.GetName(); name != "" {
+		return NewField(name + "." + field)
+	}
+	return NewField(field)
+}
+
+// getWhere 构建 WHERE 子句，extra 为需要额外追加的条件
+func (s *SqlBuilder) getWhere(extra ...Expr) (string, map[string]any) {
 	var value map[string]any = map[string]any{}
-	if len(s.WhereParam) == 0 {
+	conds := s.WhereParam
+	extra = append(extra, s.scopeConditions()...)
+	if len(extra) > 0 {
+		conds = append(conds[:len(conds):len(conds)], extra...)
+	}
+	if len(conds) == 0 {
 		return "", value
 	}
 
 	bf := bytes.Buffer{}
 	bf.WriteString(" WHERE ")
-	for _, v := range s.WhereParam {
+	for _, v := range conds {
 		if vau := v.Values(); vau != nil {
 			for k, vi := range *vau {
 				value[k] = vi
@@ -1391,6 +1600,16 @@ func (s *SqlBuilder) subQuery() (string, map[string]any) {
 	return s.commonQuery(bf, value)
 }
 
+// sortedKeys 按列名排序，保证同样的 map 每次生成相同的 SQL
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // mergeParams 统一合并参数的方法，与 commonQuery 保持一致
 // 将多个参数 map 合并到一个 map 中，预分配容量以提高性能
 func mergeParams(baseParams map[string]any, additionalParams ...map[string]any) map[string]any {
@@ -1457,6 +1676,12 @@ func (s *SqlBuilder) commonQuery(bf bytes.Buffer, value map[string]any) (string,
 	// limit
 	bf.WriteString(s.getLimit())
 
+	// lock
+	if s.lock != "" {
+		bf.WriteString(" ")
+		bf.WriteString(s.lock)
+	}
+
 	// 使用统一的参数合并方法
 	value = mergeParams(value, mapsToMerge...)
 
@@ -1466,6 +1691,58 @@ func (s *SqlBuilder) commonQuery(bf bytes.Buffer, value map[string]any) (string,
 // Sql 统一获取 SQL 语句和参数
 // 支持 SELECT、INSERT、UPDATE、DELETE 等所有操作
 func (s *SqlBuilder) Sql() (string, map[string]any) {
+	sqlStr, params, _ := s.SqlContext(context.Background())
+	return sqlStr, params
+}
+
+// SqlContext 与 Sql 相同，额外从 ctx 中读取构建配置（例如 WithTimestamps 设置的时间戳约定、
+// WithTenant 设置的租户 ID），构建失败时返回 error。MysqlClient 的 *ByBuilder 方法都通过它生成 SQL
+// Sql 在出错时返回空字符串
+func (s *SqlBuilder) SqlContext(ctx context.Context) (string, map[string]any, error) {
+	b, err := s.prepare(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	sqlStr, params := b.build()
+	if b.scope != nil && b.scope.err != nil {
+		return "", nil, b.scope.err
+	}
+	return sqlStr, params, nil
+}
+
+// prepare 返回应用了多租户、分片、自动填充列等约定后的构建器，不会修改 s 本身
+func (s *SqlBuilder) prepare(ctx context.Context) (*SqlBuilder, error) {
+	b := s
+	if hasTenantTables() || hasShardRules() {
+		// 租户条件与分片表需要作用到整个构建树，先深拷贝避免修改调用方的构建器
+		b = s.Copy()
+		tenant, ok := TenantFromContext(ctx)
+		b.setScope(&buildScope{tenant: tenant, hasTenant: ok, cross: s.crossTenant})
+		if err := b.applyTenantInsert(); err != nil {
+			return nil, err
+		}
+		if err := b.resolveShards(true); err != nil {
+			return nil, err
+		}
+		if len(b.scatterShards) > 0 {
+			var err error
+			if b, err = b.scatter(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if data, ok := b.applyTimestamps(ctx); ok {
+		if b == s {
+			c := *s
+			b = &c
+		}
+		b.dmlData = data
+	}
+	return b, nil
+}
+
+// build 按操作类型生成 SQL
+func (s *SqlBuilder) build() (string, map[string]any) {
 	// 如果有 DML 操作类型，生成对应的 SQL
 	switch s.dmlType {
 	case "insert":
@@ -1516,6 +1793,9 @@ func (s *SqlBuilder) Delete(t ...*SqlBuilder) *SqlBuilder {
 
 // buildDelete 构建 DELETE 查询语句
 func (s *SqlBuilder) buildDelete() (string, map[string]any) {
+	if column := s.softDeleteTarget(); column != "" {
+		return s.buildSoftDelete(column)
+	}
 	var value = map[string]any{}
 	bf := bytes.Buffer{}
 	sl := s.getDelete(s.deleteTarget)
@@ -0,0 +1,173 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrTenantRequired 多租户表在 ctx 中没有租户 ID，且查询没有标记 CrossTenant
+var ErrTenantRequired = errors.New("builder: tenant id required")
+
+// ErrTenantMismatch 插入数据中的租户列与 ctx 中的租户 ID 不一致
+var ErrTenantMismatch = errors.New("builder: tenant id mismatch")
+
+var tenantRegistry = struct {
+	sync.RWMutex
+	tables map[string]string // 表名 -> 租户列
+}{tables: map[string]string{}}
+
+// RegisterTenantTables 注册多租户表，column 一般为 tenant_id
+// 注册后通过 SqlContext 构建时（MysqlClient 的 *ByBuilder 方法均是）：
+//   - SELECT/UPDATE/DELETE 中的该表（包括 JOIN、FROM 子查询、UNION）自动追加 `tenant_id` = :tenant
+//   - 插入时自动填充租户列
+//
+// 租户 ID 通过 WithTenant 放入 ctx；缺失时返回 ErrTenantRequired，除非查询调用了 CrossTenant
+// 注意：以字符串形式拼接的子查询（如 In(field, sub.Sql())）不在构建树中，无法自动注入
+func RegisterTenantTables(column string, tables ...string) {
+	tenantRegistry.Lock()
+	for _, t := range tables {
+		tenantRegistry.tables[t] = column
+	}
+	tenantRegistry.Unlock()
+}
+
+// UnregisterTenantTables 取消多租户表注册
+func UnregisterTenantTables(tables ...string) {
+	tenantRegistry.Lock()
+	for _, t := range tables {
+		delete(tenantRegistry.tables, t)
+	}
+	tenantRegistry.Unlock()
+}
+
+func tenantColumn(table string) string {
+	if table == "" {
+		return ""
+	}
+	tenantRegistry.RLock()
+	defer tenantRegistry.RUnlock()
+	return tenantRegistry.tables[table]
+}
+
+func hasTenantTables() bool {
+	tenantRegistry.RLock()
+	defer tenantRegistry.RUnlock()
+	return len(tenantRegistry.tables) > 0
+}
+
+type tenantCtxKey struct{}
+
+// WithTenant 返回携带租户 ID 的 ctx
+func WithTenant(ctx context.Context, tenantID any) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// TenantFromContext 从 ctx 中获取租户 ID
+func TenantFromContext(ctx context.Context) (any, bool) {
+	v := ctx.Value(tenantCtxKey{})
+	return v, v != nil
+}
+
+// CrossTenant 标记为跨租户查询，不注入租户条件（对整个构建树生效）
+func (s *SqlBuilder) CrossTenant() *SqlBuilder {
+	s.crossTenant = true
+	return s
+}
+
+// buildScope 一次构建过程中整个构建树共享的状态
+type buildScope struct {
+	tenant    any
+	hasTenant bool
+	cross     bool
+	err       error
+}
+
+func (sc *buildScope) fail(err error) {
+	if sc.err == nil {
+		sc.err = err
+	}
+}
+
+// setScope 将 scope 设置到整个构建树上
+func (s *SqlBuilder) setScope(sc *buildScope) {
+	if s == nil || s.scope == sc {
+		return
+	}
+	s.scope = sc
+	for i := range s.JoinTable {
+		s.JoinTable[i].SubTable.setScope(sc)
+	}
+	s.FromSubQuery.setScope(sc)
+	for i := range s.UnionBuilder {
+		s.UnionBuilder[i].Table.setScope(sc)
+	}
+	s.deleteTarget.setScope(sc)
+}
+
+// tenantCondition 返回该表的租户条件，不是多租户表时返回 nil
+func (s *SqlBuilder) tenantCondition() Expr {
+	column := tenantColumn(s.Table.ruleName())
+	if column == "" || s.scope == nil || s.scope.cross || s.crossTenant {
+		return nil
+	}
+	if !s.scope.hasTenant {
+		s.scope.fail(fmt.Errorf("%w: table %s", ErrTenantRequired, s.Table.ruleName()))
+		return nil
+	}
+	return s.scopeField(column).Eq(s.scope.tenant, "tenant")
+}
+
+// applyTenantInsert 为插入类操作填充租户列
+func (s *SqlBuilder) applyTenantInsert() error {
+	if s.Table == nil || s.scope == nil || s.scope.cross || s.crossTenant {
+		return nil
+	}
+	column := tenantColumn(s.Table.ruleName())
+	if column == "" {
+		return nil
+	}
+	var rows []map[string]any
+	switch data := s.dmlData.(type) {
+	case map[string]any:
+		if s.dmlType != "insert" && s.dmlType != "insert_ignore" {
+			return nil
+		}
+		rows = []map[string]any{data}
+	case []map[string]any:
+		if s.dmlType != "insert_many" && s.dmlType != "insert_ignore_many" {
+			return nil
+		}
+		rows = data
+	case InsertOnDuplicateColsData:
+		rows = data.Rows
+	case InsertOnDuplicateMapData:
+		rows = data.Rows
+	default:
+		return nil
+	}
+	if !s.scope.hasTenant {
+		return fmt.Errorf("%w: table %s", ErrTenantRequired, s.Table.ruleName())
+	}
+	for _, row := range rows {
+		if v, ok := row[column]; ok && !reflect.DeepEqual(v, s.scope.tenant) {
+			return fmt.Errorf("%w: table %s got %v, want %v", ErrTenantMismatch, s.Table.ruleName(), v, s.scope.tenant)
+		}
+	}
+	filled := rowsWithDefaults(rows, s.scope.tenant, column)
+	switch data := s.dmlData.(type) {
+	case map[string]any:
+		s.dmlData = filled[0]
+	case []map[string]any:
+		s.dmlData = filled
+	case InsertOnDuplicateColsData:
+		data.Rows = filled
+		s.dmlData = data
+	case InsertOnDuplicateMapData:
+		data.Rows = filled
+		s.dmlData = data
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTenant_SelectJoinSubQuery(t *testing.T) {
+	RegisterTenantTables("tenant_id", "t_tn_order", "t_tn_item", "t_tn_user")
+	t.Cleanup(func() { UnregisterTenantTables("t_tn_order", "t_tn_item", "t_tn_user") })
+	ctx := WithTenant(context.Background(), 42)
+
+	o := Table("t_tn_order").As("o")
+	i := Table("t_tn_item").As("i")
+	u := Table("t_tn_user").Select("id").Label("u")
+	b := o.Select(o.Field("id")).
+		LeftJoin(i, o.Field("id").Eq(i.Field("order_id"))).
+		InnerJoin(u, o.Field("user_id").Eq(u.Field("id"))).
+		Where(o.Field("status").Eq(1))
+
+	sql, params, err := b.SqlContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT o.`id` FROM `t_tn_order` AS o left join `t_tn_item` AS i on (o.`id` = i.`order_id` AND i.`tenant_id` = :tenant) " +
+		"inner join (SELECT id FROM `t_tn_user` WHERE `t_tn_user`.`tenant_id` = :tenant) u on o.`user_id` = u.`id` " +
+		"WHERE o.`status` = 1 AND o.`tenant_id` = :tenant"
+	if sql != want {
+		t.Fatalf("tenant select:\n got: %s\nwant: %s", sql, want)
+	}
+	if params["tenant"] != 42 {
+		t.Fatalf("tenant param missing: %+v", params)
+	}
+	if len(o.WhereParam) != 1 {
+		t.Fatal("caller builder must not be modified")
+	}
+}
+
+func TestTenant_FromSubUsesInnerAlias(t *testing.T) {
+	RegisterTenantTables("tenant_id", "t_tn_user")
+	t.Cleanup(func() { UnregisterTenantTables("t_tn_user") })
+
+	inner := Table("t_tn_user").As("tu")
+	sub := inner.Select(inner.Field("id")).Label("u")
+	q := Table("").FromSub(sub)
+	sql, _, err := q.Select(q.Field("id")).SqlContext(WithTenant(context.Background(), 42))
+	want := "SELECT u.`id` FROM (SELECT tu.`id` FROM `t_tn_user` AS tu WHERE tu.`tenant_id` = :tenant) u"
+	if err != nil || sql != want {
+		t.Fatalf("tenant from sub:\n got: %s %v\nwant: %s", sql, err, want)
+	}
+}
+
+func TestTenant_MissingAndCross(t *testing.T) {
+	RegisterTenantTables("tenant_id", "t_tn_order")
+	t.Cleanup(func() { UnregisterTenantTables("t_tn_order") })
+
+	o := Table("t_tn_order")
+	_, _, err := o.Where(o.Field("id").Eq(1)).SqlContext(context.Background())
+	if !errors.Is(err, ErrTenantRequired) {
+		t.Fatalf("missing tenant should fail, got %v", err)
+	}
+	if sql, _ := o.Sql(); sql != "" {
+		t.Fatalf("Sql should return empty on error, got %s", sql)
+	}
+
+	sql, _, err := Table("t_tn_order").CrossTenant().SqlContext(context.Background())
+	if err != nil || strings.Contains(sql, "tenant_id") {
+		t.Fatalf("cross tenant should not inject: %s %v", sql, err)
+	}
+}
+
+func TestTenant_InsertAndUpdate(t *testing.T) {
+	RegisterTenantTables("tenant_id", "t_tn_order")
+	t.Cleanup(func() { UnregisterTenantTables("t_tn_order") })
+	ctx := WithTenant(context.Background(), "acme")
+
+	sql, params, err := Table("t_tn_order").InsertMany([]map[string]any{{"no": "a"}, {"no": "b"}}).SqlContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, "(`no`, `tenant_id`)") || params["tenant_id_1"] != "acme" {
+		t.Fatalf("insert should fill tenant: %s %+v", sql, params)
+	}
+
+	_, _, err = Table("t_tn_order").InsertMap(map[string]any{"no": "a", "tenant_id": "other"}).SqlContext(ctx)
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("mismatched tenant should fail, got %v", err)
+	}
+
+	o := Table("t_tn_order")
+	sql, _, err = o.Where(o.Field("id").Eq(1, "id")).UpdateMap(map[string]any{"no": "c"}).SqlContext(ctx)
+	if err != nil || !strings.HasSuffix(sql, "WHERE `t_tn_order`.`id` = :id AND `t_tn_order`.`tenant_id` = :tenant") {
+		t.Fatalf("update should be scoped: %s %v", sql, err)
+	}
+
+	o = Table("t_tn_order")
+	sql, _, err = o.Where(o.Field("id").Eq(1, "id")).Delete().SqlContext(ctx)
+	if err != nil || !strings.HasSuffix(sql, "AND `t_tn_order`.`tenant_id` = :tenant") {
+		t.Fatalf("delete should be scoped: %s %v", sql, err)
+	}
+}
+
+func TestTenant_DeleteSelfTarget(t *testing.T) {
+	RegisterTenantTables("tenant_id", "t_tn_order")
+	t.Cleanup(func() { UnregisterTenantTables("t_tn_order") })
+
+	o := Table("t_tn_order").As("o")
+	sql, _, err := o.Where(o.Field("id").Eq(1)).Delete(o).SqlContext(WithTenant(context.Background(), 1))
+	if err != nil || !strings.HasPrefix(sql, "DELETE o FROM `t_tn_order` AS o") {
+		t.Fatalf("delete self target unexpected: %s %v", sql, err)
+	}
+}
@@ -0,0 +1,205 @@
+package builder
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimestampConfig 创建/更新时间列的自动填充约定
+// 插入类操作（InsertMap、InsertMany、InsertOnDuplicate* 等）填充 CreatedAt 与 UpdatedAt，
+// 更新类操作（UpdateMap、UpdateOrdered）以及 ON DUPLICATE KEY UPDATE 部分填充 UpdatedAt。
+// 调用方显式传入的列不会被覆盖。
+type TimestampConfig struct {
+	CreatedAt string // 创建时间列，例如 created_at，为空表示不处理
+	UpdatedAt string // 更新时间列，例如 updated_at，为空表示不处理
+
+	// GoTime 为 true 时使用 Go 侧的当前时间作为参数传入，否则使用 NOW() 表达式
+	GoTime bool
+	// Location GoTime 模式下写入的时区：设置时以该时区的 DATETIME 字符串（2006-01-02 15:04:05）传入；
+	// 为空时传入 time.Time，由驱动按 DSN 的 loc 参数（默认 UTC）转换
+	Location *time.Location
+}
+
+// value 返回要写入的时间值
+func (c TimestampConfig) value() any {
+	if !c.GoTime {
+		return Now()
+	}
+	if c.Location == nil {
+		return time.Now()
+	}
+	// 驱动会把 time.Time 转换到 DSN 的 loc，所以按指定时区格式化为字符串
+	return time.Now().In(c.Location).Format(time.DateTime)
+}
+
+var globalTimestamps = struct {
+	sync.RWMutex
+	cfg *TimestampConfig
+}{}
+
+// SetTimestamps 设置全局时间戳约定，传 nil 关闭
+func SetTimestamps(cfg *TimestampConfig) {
+	globalTimestamps.Lock()
+	globalTimestamps.cfg = cfg
+	globalTimestamps.Unlock()
+}
+
+type timestampsCtxKey struct{}
+
+// WithTimestamps 返回携带时间戳约定的 ctx，供 SqlContext 使用（优先级高于全局配置）
+func WithTimestamps(ctx context.Context, cfg TimestampConfig) context.Context {
+	return context.WithValue(ctx, timestampsCtxKey{}, cfg)
+}
+
+// Timestamps 为本次构建指定时间戳约定（优先级最高）
+func (s *SqlBuilder) Timestamps(cfg TimestampConfig) *SqlBuilder {
+	s.timestamps = &cfg
+	return s
+}
+
+// WithoutTimestamps 本次构建不自动填充时间戳
+func (s *SqlBuilder) WithoutTimestamps() *SqlBuilder {
+	s.noTimestamps = true
+	return s
+}
+
+// timestampConfig 按 构建器 > ctx > 全局 的顺序获取时间戳约定
+func (s *SqlBuilder) timestampConfig(ctx context.Context) (TimestampConfig, bool) {
+	if s.noTimestamps {
+		return TimestampConfig{}, false
+	}
+	if s.timestamps != nil {
+		return *s.timestamps, true
+	}
+	if cfg, ok := ctx.Value(timestampsCtxKey{}).(TimestampConfig); ok {
+		return cfg, true
+	}
+	globalTimestamps.RLock()
+	defer globalTimestamps.RUnlock()
+	if globalTimestamps.cfg != nil {
+		return *globalTimestamps.cfg, true
+	}
+	return TimestampConfig{}, false
+}
+
+// applyTimestamps 返回填充了时间戳列的 dmlData 副本，不需要填充时返回 false
+func (s *SqlBuilder) applyTimestamps(ctx context.Context) (any, bool) {
+	if s.dmlData == nil {
+		return nil, false
+	}
+	cfg, ok := s.timestampConfig(ctx)
+	if !ok || (cfg.CreatedAt == "" && cfg.UpdatedAt == "") {
+		return nil, false
+	}
+	now := cfg.value()
+	insertCols := []string{cfg.CreatedAt, cfg.UpdatedAt}
+
+	switch s.dmlType {
+	case "insert", "insert_ignore":
+		data, ok := s.dmlData.(map[string]any)
+		if !ok || len(data) == 0 {
+			return nil, false
+		}
+		return withDefaults(data, now, insertCols...), true
+	case "insert_many", "insert_ignore_many":
+		rows, ok := s.dmlData.([]map[string]any)
+		if !ok || len(rows) == 0 {
+			return nil, false
+		}
+		return rowsWithDefaults(rows, now, insertCols...), true
+	case "insert_on_duplicate_cols":
+		data, ok := s.dmlData.(InsertOnDuplicateColsData)
+		if !ok || len(data.Rows) == 0 {
+			return nil, false
+		}
+		updateCols := data.UpdateCols
+		if cfg.UpdatedAt != "" && !containsString(updateCols, cfg.UpdatedAt) {
+			updateCols = append(updateCols[:len(updateCols):len(updateCols)], cfg.UpdatedAt)
+		}
+		return InsertOnDuplicateColsData{
+			Rows:       rowsWithDefaults(data.Rows, now, insertCols...),
+			UpdateCols: updateCols,
+		}, true
+	case "insert_on_duplicate_map":
+		data, ok := s.dmlData.(InsertOnDuplicateMapData)
+		if !ok || len(data.Rows) == 0 {
+			return nil, false
+		}
+		return InsertOnDuplicateMapData{
+			Rows:   rowsWithDefaults(data.Rows, now, insertCols...),
+			Update: withDefaults(data.Update, now, cfg.UpdatedAt),
+		}, true
+	case "update":
+		set, ok := s.dmlData.(map[string]any)
+		if !ok || cfg.UpdatedAt == "" || s.customSetContains(cfg.UpdatedAt) {
+			return nil, false
+		}
+		return withDefaults(set, now, cfg.UpdatedAt), true
+	case "update_ordered":
+		ordered, ok := s.dmlData.([]map[string]any)
+		if !ok || cfg.UpdatedAt == "" || s.customSetContains(cfg.UpdatedAt) {
+			return nil, false
+		}
+		for _, item := range ordered {
+			if _, exists := item[cfg.UpdatedAt]; exists {
+				return nil, false
+			}
+		}
+		out := make([]map[string]any, len(ordered), len(ordered)+1)
+		copy(out, ordered)
+		return append(out, map[string]any{cfg.UpdatedAt: now}), true
+	}
+	return nil, false
+}
+
+// customSetContains 判断 Set() 添加的自定义子句是否已经给 column 赋值
+func (s *SqlBuilder) customSetContains(column string) bool {
+	quoted := ColumnNameHandler(column)
+	for _, c := range s.customSetClauses {
+		lhs, _, found := strings.Cut(c.clause, "=")
+		if !found {
+			continue
+		}
+		lhs = strings.TrimSpace(lhs)
+		if lhs == column || lhs == quoted || strings.HasSuffix(lhs, "."+column) || strings.HasSuffix(lhs, "."+quoted) {
+			return true
+		}
+	}
+	return false
+}
+
+// withDefaults 复制 data，并为缺失的列填充 value
+func withDefaults(data map[string]any, value any, columns ...string) map[string]any {
+	out := make(map[string]any, len(data)+len(columns))
+	for k, v := range data {
+		out[k] = v
+	}
+	for _, c := range columns {
+		if c == "" {
+			continue
+		}
+		if _, exists := out[c]; !exists {
+			out[c] = value
+		}
+	}
+	return out
+}
+
+func rowsWithDefaults(rows []map[string]any, value any, columns ...string) []map[string]any {
+	out := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		out[i] = withDefaults(row, value, columns...)
+	}
+	return out
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package builder
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTimestamps = TimestampConfig{CreatedAt: "created_at", UpdatedAt: "updated_at"}
+
+func TestTimestamps_InsertMap(t *testing.T) {
+	data := map[string]any{"name": "nick", "created_at": "2024-01-01 00:00:00"}
+	sql, params := Table("t_user").InsertMap(data).Timestamps(testTimestamps).Sql()
+
+	if !strings.Contains(sql, "`updated_at`") || !strings.Contains(sql, "NOW()") {
+		t.Fatalf("updated_at should be filled with NOW(): %s", sql)
+	}
+	if params["created_at"] != "2024-01-01 00:00:00" {
+		t.Fatalf("explicit created_at must not be overwritten: %+v", params)
+	}
+	if len(data) != 2 {
+		t.Fatal("caller data must not be modified")
+	}
+}
+
+func TestTimestamps_InsertManyGoTime(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	rows := []map[string]any{{"name": "a"}, {"name": "b"}}
+	sql, params := Table("t_user").InsertMany(rows).
+		Timestamps(TimestampConfig{CreatedAt: "created_at", UpdatedAt: "updated_at", GoTime: true, Location: loc}).Sql()
+
+	if want := "INSERT INTO `t_user` (`created_at`, `name`, `updated_at`) VALUES (:created_at_0, :name_0, :updated_at_0), (:created_at_1, :name_1, :updated_at_1)"; sql != want {
+		t.Fatalf("InsertMany timestamps:\n got: %s\nwant: %s", sql, want)
+	}
+	ts, ok := params["created_at_1"].(string)
+	if !ok {
+		t.Fatalf("created_at should be a DATETIME string: %#v", params["created_at_1"])
+	}
+	if v, err := time.ParseInLocation(time.DateTime, ts, loc); err != nil || time.Since(v).Abs() > time.Minute {
+		t.Fatalf("created_at should be formatted in configured zone: %s %v", ts, err)
+	}
+}
+
+func TestTimestamps_Upsert(t *testing.T) {
+	sql, _ := Table("t_user").
+		InsertOnDuplicateCols(map[string]any{"id": 1, "name": "a"}, []string{"name"}).
+		Timestamps(testTimestamps).Sql()
+	if !strings.HasSuffix(sql, "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`), `updated_at`=VALUES(`updated_at`)") {
+		t.Fatalf("upsert cols should refresh updated_at: %s", sql)
+	}
+
+	sql, _ = Table("t_user").
+		InsertOnDuplicateMap(map[string]any{"id": 1}, map[string]any{"name": "b"}).
+		Timestamps(testTimestamps).Sql()
+	if !strings.Contains(sql, "`updated_at` = NOW()") {
+		t.Fatalf("upsert map should refresh updated_at: %s", sql)
+	}
+}
+
+func TestTimestamps_UpdateAndOptOut(t *testing.T) {
+	ctx := WithTimestamps(context.Background(), testTimestamps)
+
+	tbl := Table("t_user")
+	sql, _, _ := tbl.Where(tbl.Field("id").Eq(1, "id")).
+		UpdateOrdered([]map[string]any{{"name": "a"}}).SqlContext(ctx)
+	if want := "UPDATE `t_user` SET `name` = :name, `updated_at` = NOW() WHERE `t_user`.`id` = :id"; sql != want {
+		t.Fatalf("UpdateOrdered timestamps:\n got: %s\nwant: %s", sql, want)
+	}
+
+	tbl = Table("t_user")
+	sql, _, _ = tbl.Where(tbl.Field("id").Eq(1, "id")).
+		UpdateMap(map[string]any{"name": "a"}).
+		Set("`updated_at` = :ts", map[string]any{"ts": "2024-01-01"}).SqlContext(ctx)
+	if strings.Contains(sql, "NOW()") {
+		t.Fatalf("custom Set of updated_at must not be overwritten: %s", sql)
+	}
+
+	sql, _, _ = Table("t_user").InsertMap(map[string]any{"name": "a"}).WithoutTimestamps().SqlContext(ctx)
+	if strings.Contains(sql, "created_at") {
+		t.Fatalf("WithoutTimestamps should opt out: %s", sql)
+	}
+
+	sql, _ = Table("t_user").InsertMap(map[string]any{"name": "a"}).Sql()
+	if strings.Contains(sql, "created_at") {
+		t.Fatalf("no config should not fill timestamps: %s", sql)
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull 并发数已满且排队失败（队列已满或排队超时）
+var ErrBulkheadFull = errors.New("mdb: bulkhead full")
+
+// BulkheadError 调用因并发限制被拒绝，可以用 errors.Is(err, ErrBulkheadFull) 判断
+type BulkheadError struct {
+	Op     string
+	Reason string        // queue full 或 queue timeout
+	Waited time.Duration // 排队等待的时间
+}
+
+func (e *BulkheadError) Error() string {
+	return fmt.Sprintf("mdb: %s rejected by bulkhead: %s after %s", e.Op, e.Reason, e.Waited.Round(time.Millisecond))
+}
+
+func (e *BulkheadError) Is(target error) bool {
+	return target == ErrBulkheadFull
+}
+
+// BulkheadConfig 客户端并发限制（舱壁）
+// 限制的是同时执行的调用数（Transaction 整体算一个），应不大于 MaxOpenCons，避免 goroutine 堆积在连接池上
+type BulkheadConfig struct {
+	MaxConcurrent int           // 最大并发调用数，必须大于 0
+	MaxQueue      int           // 最大排队数，0 表示不排队（满了立即拒绝），<0 表示不限制
+	QueueTimeout  time.Duration // 最长排队时间，0 表示只受 ctx 限制
+}
+
+// WithBulkhead 为客户端开启并发限制，超出时返回 *BulkheadError
+// Transaction/RunInTx 在整个回调期间占用一个名额，回调（以及 OnCommit/OnRollback）中使用其 ctx 的非事务调用
+// 复用该名额，不会再次申请，因此 MaxConcurrent=1 时也不会死锁；注意在回调中用该 ctx 启动的 goroutine 同样不受限制
+func WithBulkhead(cfg BulkheadConfig) Option {
+	return func(c *MysqlClient) {
+		if cfg.MaxConcurrent > 0 {
+			c.bulkhead = &bulkhead{cfg: cfg, slots: make(chan struct{}, cfg.MaxConcurrent)}
+		}
+	}
+}
+
+type bulkhead struct {
+	cfg    BulkheadConfig
+	slots  chan struct{}
+	queued atomic.Int64
+}
+
+// bulkheadHeldKey ctx 中标记已持有该舱壁的名额
+type bulkheadHeldKey struct{ b *bulkhead }
+
+// hold 标记 ctx 已持有名额，之后使用该 ctx 的调用不再申请
+func (b *bulkhead) hold(ctx context.Context) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, bulkheadHeldKey{b}, true)
+}
+
+// acquire 占用一个并发名额，返回释放函数；b 为 nil 或 ctx 已持有名额时不做限制
+func (b *bulkhead) acquire(ctx context.Context, op string) (func(), error) {
+	if b == nil || ctx.Value(bulkheadHeldKey{b}) != nil {
+		return func() {}, nil
+	}
+	release := func() { <-b.slots }
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if b.cfg.MaxQueue == 0 {
+		return nil, &BulkheadError{Op: op, Reason: "queue full"}
+	}
+	if n := b.queued.Add(1); b.cfg.MaxQueue > 0 && n > int64(b.cfg.MaxQueue) {
+		b.queued.Add(-1)
+		return nil, &BulkheadError{Op: op, Reason: "queue full"}
+	}
+	defer b.queued.Add(-1)
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if b.cfg.QueueTimeout > 0 {
+		timer := time.NewTimer(b.cfg.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, &BulkheadError{Op: op, Reason: "queue timeout", Waited: time.Since(start)}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// BulkheadStats 并发限制的当前状态
+type BulkheadStats struct {
+	MaxConcurrent int
+	InUse         int
+	Queued        int
+}
+
+// BulkheadStats 返回并发限制状态，没有开启 WithBulkhead 时返回零值
+func (s MysqlClient) BulkheadStats() BulkheadStats {
+	if s.bulkhead == nil {
+		return BulkheadStats{}
+	}
+	return BulkheadStats{
+		MaxConcurrent: s.bulkhead.cfg.MaxConcurrent,
+		InUse:         len(s.bulkhead.slots),
+		Queued:        int(s.bulkhead.queued.Load()),
+	}
+}
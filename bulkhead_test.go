@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	c := &MysqlClient{}
+	WithBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 20 * time.Millisecond})(c)
+	ctx := context.Background()
+
+	release, err := c.bulkhead.acquire(ctx, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 排队超时
+	_, err = c.bulkhead.acquire(ctx, "q")
+	var be *BulkheadError
+	if !errors.Is(err, ErrBulkheadFull) || !errors.As(err, &be) || be.Reason != "queue timeout" {
+		t.Fatalf("queue timeout: err = %v", err)
+	}
+
+	release()
+
+	// 队列已满
+	c = &MysqlClient{}
+	WithBulkhead(BulkheadConfig{MaxConcurrent: 1, MaxQueue: 1})(c)
+	if release, err = c.bulkhead.acquire(ctx, "q"); err != nil {
+		t.Fatal(err)
+	}
+	queued := make(chan error, 1)
+	go func() {
+		r, err := c.bulkhead.acquire(ctx, "q")
+		if err == nil {
+			r()
+		}
+		queued <- err
+	}()
+	for c.BulkheadStats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.bulkhead.acquire(ctx, "q"); !errors.As(err, &be) || be.Reason != "queue full" {
+		t.Fatalf("queue full: err = %v", err)
+	}
+	release()
+	if err := <-queued; err != nil {
+		t.Fatalf("queued call: %v", err)
+	}
+	if st := c.BulkheadStats(); st.InUse != 0 || st.Queued != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheBackend 查询缓存的存储后端，可以替换为 redis 等实现
+// 返回的 []byte 为空切片时表示"查询无结果"，同样是一次有效命中
+type CacheBackend interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+}
+
+// QueryCache QueryByBuilder/FetchByBuilder 的读穿透缓存
+// 缓存 key 由规范化后的 SQL、参数以及所读表的版本号组成；
+// ExecByBuilder 写某张表时只需要更换该表的版本号，旧的缓存自然失效，
+// 等待 TTL 或 LRU 淘汰即可。版本号与缓存一起保存在 backend 中，
+// 多个进程共用 redis 等后端时，一个进程的写入同样会使其它进程的缓存失效。
+// 结果通过 encoding/json 序列化，dest 类型需要能够正常往返编解码。
+type QueryCache struct {
+	backend CacheBackend
+	ttl     time.Duration
+
+	group singleflight.Group
+}
+
+// NewQueryCache 创建查询缓存，ttl 为默认过期时间（<=0 表示不过期）
+func NewQueryCache(backend CacheBackend, ttl time.Duration) *QueryCache {
+	return &QueryCache{
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
+// Invalidate 使读取了这些表的缓存全部失效：为每张表写入新的版本号，旧的缓存条目不再被命中
+func (c *QueryCache) Invalidate(ctx context.Context, tables ...string) {
+	for _, t := range tables {
+		c.backend.Set(ctx, generationKey(t), []byte(rand.Text()), 0)
+	}
+}
+
+// generation 读取表的版本号；不存在（从未写入或被淘汰）时写入新的版本号，
+// 避免版本号被淘汰后重新命中更早的缓存
+func (c *QueryCache) generation(ctx context.Context, table string) []byte {
+	if gen, ok := c.backend.Get(ctx, generationKey(table)); ok && len(gen) > 0 {
+		return gen
+	}
+	gen := []byte(rand.Text())
+	c.backend.Set(ctx, generationKey(table), gen, 0)
+	return gen
+}
+
+func generationKey(table string) string {
+	return "mdb:gen:" + table
+}
+
+// key 生成缓存 key：表版本 + SQL + 参数
+func (c *QueryCache) key(ctx context.Context, tables []string, sqlStr string, params map[string]any) (string, error) {
+	ps, err := json.Marshal(params) // json 会对 map 的 key 排序，保证顺序稳定
+	if err != nil {
+		return "", err
+	}
+	sorted := append([]string(nil), tables...)
+	sort.Strings(sorted)
+
+	h := sha256.New()
+	for _, t := range sorted {
+		h.Write([]byte(t))
+		h.Write([]byte{0})
+		h.Write(c.generation(ctx, t))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(normalizeSql(sqlStr)))
+	h.Write([]byte{0})
+	h.Write(ps)
+	return "mdb:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// load 先查缓存，未命中时通过 singleflight 只执行一次 query，并把结果写回 dest
+// query 返回 false 表示没有查到数据（sql.ErrNoRows）
+func (c *QueryCache) load(ctx context.Context, tables []string, sqlStr string, params map[string]any, dest any, query func(dest any) (bool, error)) error {
+	key, err := c.key(ctx, tables, sqlStr, params)
+	if err != nil {
+		// 参数无法序列化时直接查库
+		_, err = query(dest)
+		return err
+	}
+	if data, ok := c.backend.Get(ctx, key); ok {
+		return decodeCached(data, dest)
+	}
+
+	ttl := c.ttl
+	if d, ok := ctx.Value(cacheTTLKey{}).(time.Duration); ok {
+		ttl = d
+	}
+	for {
+		v, err, shared := c.group.Do(key, func() (any, error) {
+			fresh := reflect.New(reflect.TypeOf(dest).Elem()).Interface()
+			found, err := query(fresh)
+			if err != nil {
+				return nil, err
+			}
+			data := []byte{}
+			if found {
+				if data, err = json.Marshal(fresh); err != nil {
+					return nil, err
+				}
+			}
+			c.backend.Set(ctx, key, data, ttl)
+			return data, nil
+		})
+		// 共享的查询使用的是发起者的 ctx，它被取消时不应让其他等待者失败：用自己的 ctx 重新加载
+		if err != nil && shared && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return decodeCached(v.([]byte), dest)
+	}
+}
+
+func decodeCached(data []byte, dest any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// normalizeSql 合并多余的空白字符，避免格式差异导致缓存 key 不同
+func normalizeSql(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+type skipCacheKey struct{}
+type cacheTTLKey struct{}
+
+// SkipCache 返回一个跳过查询缓存的 ctx（本次查询直接读库，也不会写入缓存）
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+// CacheTTL 返回一个覆盖本次查询缓存过期时间的 ctx
+func CacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheTTLKey{}, ttl)
+}
+
+// -------------------- LRU 内存实现 --------------------
+
+// LRUCache 基于 LRU 的内存缓存后端，并发安全
+type LRUCache struct {
+	capacity int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+// NewLRUCache 创建容量为 capacity 的 LRU 缓存
+func NewLRUCache(capacity int) *LRUCache {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+func (l *LRUCache) Get(_ context.Context, key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		l.removeElement(el)
+		return nil, false
+	}
+	l.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (l *LRUCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) {
+	var expireAt time.Time
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.value = value
+		entry.expireAt = expireAt
+		l.ll.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	for l.ll.Len() > l.capacity {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *LRUCache) Delete(_ context.Context, keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.removeElement(el)
+		}
+	}
+}
+
+// Len 返回当前缓存的条目数
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRUCache) removeElement(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.items, el.Value.(*lruEntry).key)
+}
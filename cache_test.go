@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestLRUCache_EvictAndExpire(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUCache(2)
+	c.Set(ctx, "a", []byte("1"), 0)
+	c.Set(ctx, "b", []byte("2"), 0)
+	c.Get(ctx, "a") // a 变为最近使用
+	c.Set(ctx, "c", []byte("3"), 0)
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Fatal("b should be evicted")
+	}
+	if v, ok := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatalf("a should stay, got %q %v", v, ok)
+	}
+
+	c.Set(ctx, "d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get(ctx, "d"); ok {
+		t.Fatal("d should be expired")
+	}
+}
+
+func TestQueryCache_LoadAndInvalidate(t *testing.T) {
+	ctx := context.Background()
+	qc := NewQueryCache(NewLRUCache(16), time.Minute)
+
+	var calls int32
+	query := func(dest any) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		*(dest.(*[]int)) = []int{1, 2, 3}
+		return true, nil
+	}
+
+	var got []int
+	sqlStr := "SELECT id FROM `t_user`  WHERE id > :id"
+	for i := 0; i < 3; i++ {
+		got = nil
+		if err := qc.load(ctx, []string{"t_user"}, sqlStr, map[string]any{"id": 1}, &got, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 || len(got) != 3 {
+		t.Fatalf("expected 1 query and cached rows, calls=%d got=%v", calls, got)
+	}
+
+	// 空白差异不影响命中
+	if err := qc.load(ctx, []string{"t_user"}, "SELECT id FROM `t_user` WHERE id > :id", map[string]any{"id": 1}, &got, query); err != nil || calls != 1 {
+		t.Fatalf("normalized sql should hit cache, calls=%d err=%v", calls, err)
+	}
+
+	qc.Invalidate(ctx, "t_other")
+	_ = qc.load(ctx, []string{"t_user"}, sqlStr, map[string]any{"id": 1}, &got, query)
+	if calls != 1 {
+		t.Fatalf("unrelated table should not invalidate, calls=%d", calls)
+	}
+
+	qc.Invalidate(ctx, "t_user")
+	_ = qc.load(ctx, []string{"t_user"}, sqlStr, map[string]any{"id": 1}, &got, query)
+	if calls != 2 {
+		t.Fatalf("invalidate should force a new query, calls=%d", calls)
+	}
+}
+
+func TestQueryCache_NoRowsAndSingleflight(t *testing.T) {
+	ctx := context.Background()
+	qc := NewQueryCache(NewLRUCache(16), time.Minute)
+
+	var calls int32
+	release := make(chan struct{})
+	query := func(dest any) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return false, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			row := struct{ Id int64 }{Id: 7}
+			if err := qc.load(ctx, []string{"t_user"}, "SELECT * FROM t_user", nil, &row, query); err != nil {
+				t.Error(err)
+			}
+			if row.Id != 7 {
+				t.Errorf("no rows should keep dest untouched, got %d", row.Id)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("concurrent misses should query once, calls=%d", calls)
+	}
+}
+
+func TestQueryCache_LeaderCanceled(t *testing.T) {
+	qc := NewQueryCache(NewLRUCache(16), time.Minute)
+	started := make(chan struct{})
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := func(dest any) (bool, error) {
+		close(started)
+		<-leaderCtx.Done()
+		return false, leaderCtx.Err()
+	}
+	var calls int32
+	waiter := func(dest any) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		dest.(*struct{ Id int64 }).Id = 1
+		return true, nil
+	}
+
+	done := make(chan error)
+	go func() {
+		var row struct{ Id int64 }
+		done <- qc.load(leaderCtx, []string{"t_user"}, "SELECT * FROM t_user", nil, &row, leader)
+	}()
+	<-started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	// 发起者被取消后，等待者用自己的 ctx 重新加载
+	var row struct{ Id int64 }
+	if err := qc.load(context.Background(), []string{"t_user"}, "SELECT * FROM t_user", nil, &row, waiter); err != nil || row.Id != 1 {
+		t.Fatalf("waiter: err = %v, id = %d", err, row.Id)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("leader: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("waiter should reload once, calls=%d", calls)
+	}
+}
+
+func TestQueryCache_SharedBackendAndCommit(t *testing.T) {
+	ctx := context.Background()
+	backend := NewLRUCache(16)
+	// 两个进程共用同一个后端
+	a, b := NewQueryCache(backend, time.Minute), NewQueryCache(backend, time.Minute)
+
+	var calls int32
+	query := func(dest any) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+		*(dest.(*[]int)) = []int{1}
+		return true, nil
+	}
+	var got []int
+	_ = a.load(ctx, []string{"t_user"}, "SELECT id FROM t_user", nil, &got, query)
+	_ = b.load(ctx, []string{"t_user"}, "SELECT id FROM t_user", nil, &got, query)
+	if calls != 1 {
+		t.Fatalf("shared backend should hit, calls=%d", calls)
+	}
+	b.Invalidate(ctx, "t_user")
+	_ = a.load(ctx, []string{"t_user"}, "SELECT id FROM t_user", nil, &got, query)
+	if calls != 2 {
+		t.Fatalf("invalidate from another cache should apply, calls=%d", calls)
+	}
+
+	// 事务中的写入在提交后才失效
+	cli := MysqlClient{cache: a}
+	txCtx, hooks := NewTxHooks(ctx)
+	cli.invalidateCache(txCtx, []string{"t_user"}, []*sqlx.Tx{{}})
+	_ = a.load(ctx, []string{"t_user"}, "SELECT id FROM t_user", nil, &got, query)
+	if calls != 2 {
+		t.Fatalf("invalidated before commit, calls=%d", calls)
+	}
+	hooks.RunCommit(ctx)
+	_ = a.load(ctx, []string{"t_user"}, "SELECT id FROM t_user", nil, &got, query)
+	if calls != 3 {
+		t.Fatalf("commit should invalidate, calls=%d", calls)
+	}
+}
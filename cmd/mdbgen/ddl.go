@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/migrations"
+)
+
+// ParseDDL 解析 SQL 脚本中的 CREATE TABLE 语句，其它语句忽略
+// 只解析生成代码需要的信息：列、主键/唯一索引、表注释
+func ParseDDL(script string) ([]*db.TableInfo, error) {
+	var tables []*db.TableInfo
+	for _, stmt := range migrations.SplitStatements(script) {
+		tokens := tokenize(stripComments(stmt))
+		if len(tokens) < 3 || !strings.EqualFold(tokens[0], "CREATE") {
+			continue
+		}
+		i := 1
+		if strings.EqualFold(tokens[i], "TEMPORARY") {
+			i++
+		}
+		if !strings.EqualFold(tokens[i], "TABLE") {
+			continue
+		}
+		i++
+		if i+2 < len(tokens) && strings.EqualFold(tokens[i], "IF") && strings.EqualFold(tokens[i+1], "NOT") && strings.EqualFold(tokens[i+2], "EXISTS") {
+			i += 3
+		}
+		if i+1 >= len(tokens) || !strings.HasPrefix(tokens[i+1], "(") {
+			return nil, fmt.Errorf("mdbgen: unsupported CREATE TABLE: %.80s", stmt)
+		}
+		name := unquoteIdent(tokens[i])
+		if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+			name = unquoteIdent(name[dot+1:])
+		}
+		t := &db.TableInfo{Name: name}
+		if err := parseTableBody(t, tokens[i+1]); err != nil {
+			return nil, fmt.Errorf("mdbgen: table %s: %w", name, err)
+		}
+		parseTableOptions(t, tokens[i+2:])
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+// parseTableBody 解析 (...) 中的列与索引定义
+func parseTableBody(t *db.TableInfo, body string) error {
+	body = body[1 : len(body)-1]
+	for _, def := range splitTopLevel(body, ',') {
+		tokens := tokenize(def)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch strings.ToUpper(tokens[0]) {
+		case "PRIMARY":
+			cols := indexColumns(tokens)
+			t.Indexes = append(t.Indexes, db.IndexInfo{Name: "PRIMARY", Primary: true, Unique: true, Columns: cols})
+			markKey(t, cols, "PRI")
+		case "UNIQUE":
+			idx := db.IndexInfo{Unique: true, Columns: indexColumns(tokens)}
+			idx.Name = indexName(tokens[1:])
+			t.Indexes = append(t.Indexes, idx)
+			if len(idx.Columns) == 1 {
+				markKey(t, idx.Columns, "UNI")
+			}
+		case "KEY", "INDEX", "FULLTEXT", "SPATIAL":
+			t.Indexes = append(t.Indexes, db.IndexInfo{Name: indexName(tokens[1:]), Columns: indexColumns(tokens)})
+		case "CONSTRAINT", "FOREIGN", "CHECK":
+			// 外键与 CHECK 约束不影响生成代码
+		default:
+			col, err := parseColumn(tokens)
+			if err != nil {
+				return err
+			}
+			col.Position = len(t.Columns) + 1
+			t.Columns = append(t.Columns, col)
+		}
+	}
+	return nil
+}
+
+// parseColumn 解析列定义：名称 类型 [UNSIGNED] [NOT NULL] [DEFAULT x] [AUTO_INCREMENT] [COMMENT 'x'] ...
+func parseColumn(tokens []string) (db.ColumnInfo, error) {
+	if len(tokens) < 2 {
+		return db.ColumnInfo{}, fmt.Errorf("bad column definition %q", strings.Join(tokens, " "))
+	}
+	col := db.ColumnInfo{Name: unquoteIdent(tokens[0]), Nullable: true}
+	col.DataType = strings.ToLower(tokens[1])
+	col.ColumnType = col.DataType
+	i := 2
+	if i < len(tokens) && strings.HasPrefix(tokens[i], "(") {
+		col.ColumnType += tokens[i]
+		i++
+	}
+	for ; i < len(tokens); i++ {
+		switch strings.ToUpper(tokens[i]) {
+		case "UNSIGNED":
+			col.Unsigned = true
+			col.ColumnType += " unsigned"
+		case "ZEROFILL":
+			col.ColumnType += " zerofill"
+		case "NOT":
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1], "NULL") {
+				col.Nullable = false
+				i++
+			}
+		case "DEFAULT":
+			if i+1 < len(tokens) {
+				i++
+				if !strings.EqualFold(tokens[i], "NULL") {
+					def := unquoteString(tokens[i])
+					col.Default = &def
+				}
+			}
+		case "AUTO_INCREMENT":
+			col.AutoIncrement = true
+			col.Extra = "auto_increment"
+		case "PRIMARY":
+			col.Key = "PRI"
+			col.Nullable = false
+		case "UNIQUE":
+			if col.Key == "" {
+				col.Key = "UNI"
+			}
+		case "COMMENT":
+			if i+1 < len(tokens) {
+				i++
+				col.Comment = unquoteString(tokens[i])
+			}
+		case "AS":
+			if i+1 < len(tokens) && strings.HasPrefix(tokens[i+1], "(") {
+				i++
+				col.Generated = true
+				col.GenerationExpr = strings.TrimSpace(tokens[i][1 : len(tokens[i])-1])
+			}
+		}
+	}
+	return col, nil
+}
+
+// parseTableOptions 解析表选项，例如 ENGINE=InnoDB COMMENT='用户表'
+func parseTableOptions(t *db.TableInfo, tokens []string) {
+	for i := 0; i+1 < len(tokens); i++ {
+		key, value := strings.ToUpper(tokens[i]), tokens[i+1]
+		if value == "=" {
+			if i+2 >= len(tokens) {
+				return
+			}
+			value = tokens[i+2]
+		} else if key != "COMMENT" {
+			continue
+		}
+		switch key {
+		case "ENGINE":
+			t.Engine = value
+		case "COMMENT":
+			t.Comment = unquoteString(value)
+		}
+	}
+}
+
+func markKey(t *db.TableInfo, cols []string, key string) {
+	for i := range t.Columns {
+		for _, c := range cols {
+			if t.Columns[i].Name == c && (t.Columns[i].Key == "" || key == "PRI") {
+				t.Columns[i].Key = key
+				if key == "PRI" {
+					t.Columns[i].Nullable = false
+				}
+			}
+		}
+	}
+}
+
+// indexName 返回 KEY/INDEX 后的索引名，没有名称时返回空字符串
+func indexName(tokens []string) string {
+	for _, tok := range tokens {
+		switch strings.ToUpper(tok) {
+		case "KEY", "INDEX":
+			continue
+		}
+		if strings.HasPrefix(tok, "(") {
+			return ""
+		}
+		return unquoteIdent(tok)
+	}
+	return ""
+}
+
+// indexColumns 返回索引定义中第一个括号内的列名（去掉前缀长度与排序）
+func indexColumns(tokens []string) []string {
+	for _, tok := range tokens {
+		if !strings.HasPrefix(tok, "(") {
+			continue
+		}
+		var cols []string
+		for _, part := range splitTopLevel(tok[1:len(tok)-1], ',') {
+			fields := tokenize(part)
+			if len(fields) > 0 {
+				cols = append(cols, unquoteIdent(fields[0]))
+			}
+		}
+		return cols
+	}
+	return nil
+}
+
+// tokenize 按空白拆分，引号字符串与括号组各作为一个 token，=、逗号前后不需要空白
+func tokenize(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end := closeQuote(s, i)
+			tokens = append(tokens, s[i:end])
+			i = end
+		case c == '(':
+			end := closeParen(s, i)
+			tokens = append(tokens, s[i:end])
+			i = end
+		case c == '=' || c == ',':
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r'\"`(=,", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// closeQuote 返回 s[start] 处引号的结束位置（不含）
+func closeQuote(s string, start int) int {
+	q := s[start]
+	for i := start + 1; i < len(s); i++ {
+		if s[i] == '\\' && q != '`' {
+			i++
+			continue
+		}
+		if s[i] == q {
+			if i+1 < len(s) && s[i+1] == q {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// closeParen 返回 s[start] 处括号匹配的结束位置（不含）
+func closeParen(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = closeQuote(s, i) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// splitTopLevel 按 sep 拆分，忽略括号与引号中的 sep
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = closeQuote(s, i) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[last:i]))
+				last = i + 1
+			}
+		}
+	}
+	if rest := strings.TrimSpace(s[last:]); rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+// stripComments 去掉 -- 与 # 行注释、/* */ 块注释
+func stripComments(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closeQuote(s, i)
+			b.WriteString(s[i:end])
+			i = end - 1
+		case c == '#' || (c == '-' && strings.HasPrefix(s[i:], "-- ")):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end - 1
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			b.WriteByte(' ')
+			i += end + 3
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && s[0] == '`' && s[len(s)-1] == '`' {
+		return strings.ReplaceAll(s[1:len(s)-1], "``", "`")
+	}
+	return s
+}
+
+func unquoteString(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		q := string(s[0])
+		s = s[1 : len(s)-1]
+		s = strings.ReplaceAll(s, q+q, q)
+		s = strings.ReplaceAll(s, `\`+q, q)
+		return strings.ReplaceAll(s, `\\`, `\`)
+	}
+	return s
+}
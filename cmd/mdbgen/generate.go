@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	db "github.com/preceeder/db"
+)
+
+// genField 生成代码中的一个字段
+type genField struct {
+	Name    string // Go 字段名
+	Column  string // 列名
+	Type    string // Go 类型
+	Comment string
+}
+
+type genTable struct {
+	Name    string // Go 类型名
+	Table   string // 表名
+	Comment string
+	Fields  []genField
+}
+
+// Generate 为 tables 生成模型结构体与列辅助类型，返回 gofmt 之后的源码
+func Generate(pkg string, tables []*db.TableInfo) ([]byte, error) {
+	sorted := append([]*db.TableInfo(nil), tables...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	data := struct {
+		Package   string
+		ImportsDb bool
+		Tables    []genTable
+	}{Package: pkg}
+	// 每个表生成 Name、NameColumns、NameT 三个标识符，例如 user 与 user_columns 会冲突
+	typeNames := map[string]bool{}
+	for _, t := range sorted {
+		gt := genTable{Name: goName(t.Name), Table: t.Name, Comment: oneLine(t.Comment)}
+		for typeNames[gt.Name] || typeNames[gt.Name+"Columns"] || typeNames[gt.Name+"T"] {
+			gt.Name += "_"
+		}
+		typeNames[gt.Name], typeNames[gt.Name+"Columns"], typeNames[gt.Name+"T"] = true, true, true
+		// 模型与列类型上已有的方法名不能再作为字段名
+		seen := map[string]bool{"TableName": true, "Table": true, "Columns": true}
+		for _, c := range t.Columns {
+			f := genField{Name: goName(c.Name), Column: c.Name, Type: goType(c), Comment: oneLine(c.Comment)}
+			for seen[f.Name] {
+				f.Name += "_"
+			}
+			seen[f.Name] = true
+			if strings.Contains(f.Type, "db.") {
+				data.ImportsDb = true
+			}
+			gt.Fields = append(gt.Fields, f)
+		}
+		data.Tables = append(data.Tables, gt)
+	}
+
+	var buf bytes.Buffer
+	if err := genTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("mdbgen: format generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+var genTemplate = template.Must(template.New("mdbgen").Parse(`// Code generated by mdbgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .ImportsDb}}
+	db "github.com/preceeder/db"
+{{- end}}
+	"github.com/preceeder/db/builder"
+)
+{{range .Tables}}
+// {{.Name}} {{.Table}} 表{{if .Comment}}：{{.Comment}}{{end}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `db:"{{.Column}}" json:"{{.Column}}"` + "`" + `{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+
+// TableName 返回表名
+func ({{.Name}}) TableName() string { return "{{.Table}}" }
+
+// {{.Name}}Columns {{.Table}} 表的列
+type {{.Name}}Columns struct {
+{{- range .Fields}}
+	{{.Name}} builder.Fd
+{{- end}}
+}
+
+// {{.Name}}T {{.Table}} 表的列{{if .Fields}}，例如 {{.Name}}T.Table().Where({{.Name}}T.{{(index .Fields 0).Name}}.Eq(x)){{end}}
+var {{.Name}}T = {{.Name}}Columns{
+{{- range .Fields}}
+	{{.Name}}: builder.NewField("{{.Column}}"),
+{{- end}}
+}
+
+// Table 返回 {{.Table}} 表的构建器
+func ({{.Name}}Columns) Table() *builder.SqlBuilder { return builder.Table("{{.Table}}") }
+
+// TableName 返回表名
+func ({{.Name}}Columns) TableName() string { return "{{.Table}}" }
+
+// Columns 返回所有列名（按表中顺序）
+func ({{.Name}}Columns) Columns() []string {
+	return []string{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}"{{$f.Column}}"{{end -}} }
+}
+{{end}}`))
+
+// goType 将列类型映射为 Go 类型，可为 NULL 的列使用指针（[]byte 除外）
+// decimal/numeric 映射为 string，避免 float64 丢失精度
+func goType(c db.ColumnInfo) string {
+	var t string
+	switch c.DataType {
+	case "tinyint":
+		if strings.HasPrefix(strings.ToLower(c.ColumnType), "tinyint(1)") {
+			t = "bool"
+		} else {
+			t = "int8"
+		}
+	case "smallint":
+		t = "int16"
+	case "mediumint", "int", "integer":
+		t = "int32"
+	case "bigint":
+		t = "int64"
+	case "year":
+		t = "int16"
+	case "float":
+		t = "float32"
+	case "double", "real":
+		t = "float64"
+	case "date":
+		t = "db.Date"
+	case "datetime", "timestamp":
+		t = "db.DateTime"
+	case "json":
+		t = "db.Json"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit":
+		return "[]byte"
+	default:
+		t = "string"
+	}
+	if c.Unsigned && strings.HasPrefix(t, "int") {
+		t = "u" + t
+	}
+	if c.Nullable {
+		t = "*" + t
+	}
+	return t
+}
+
+// goName 将 snake_case 转为 CamelCase，例如 user_id -> UserId
+func goName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteByte('X')
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	return b.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,155 @@
+// mdbgen 根据数据库或 CREATE TABLE DDL 生成模型结构体与带类型的列辅助代码
+//
+// 用法：
+//
+//	mdbgen -dsn "user:pass@tcp(127.0.0.1:3306)/app" -pkg models -out models/models_gen.go
+//	mdbgen -ddl schema.sql,migrations/ -tables user,order -out models/models_gen.go
+//
+// 生成的代码中每张表包含：
+//   - 模型结构体 User（db/json 标签，日期类型使用 db.Date/db.DateTime，JSON 列使用 db.Json）
+//   - 列辅助变量 UserT：UserT.Table() 返回 builder.Table("user")，UserT.UserId 为 builder.Fd
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+)
+
+func main() {
+	dsn := flag.String("dsn", "", "数据库连接串，例如 user:pass@tcp(127.0.0.1:3306)/app")
+	ddl := flag.String("ddl", "", "逗号分隔的 .sql 文件或目录，离线解析 CREATE TABLE")
+	only := flag.String("tables", "", "逗号分隔的表名，默认全部")
+	pkg := flag.String("pkg", "models", "生成代码的包名")
+	out := flag.String("out", "", "输出文件，默认输出到标准输出")
+	flag.Parse()
+
+	if err := run(*dsn, *ddl, *only, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dsn, ddl, only, pkg, out string) error {
+	var (
+		tables []*db.TableInfo
+		err    error
+	)
+	switch {
+	case dsn != "" && ddl != "":
+		return fmt.Errorf("mdbgen: -dsn and -ddl are mutually exclusive")
+	case dsn != "":
+		tables, err = loadFromDatabase(dsn)
+	case ddl != "":
+		tables, err = loadFromDDL(strings.Split(ddl, ","))
+	default:
+		return fmt.Errorf("mdbgen: one of -dsn or -ddl is required")
+	}
+	if err != nil {
+		return err
+	}
+	tables, err = filterTables(tables, only)
+	if err != nil {
+		return err
+	}
+	src, err := Generate(pkg, tables)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
+
+func loadFromDatabase(dsn string) ([]*db.TableInfo, error) {
+	conn, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	schema, err := db.MysqlClient{Db: conn}.Schema(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]*db.TableInfo, 0, len(schema.Tables))
+	for _, name := range schema.TableNames() {
+		if t := schema.Tables[name]; !t.View {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func loadFromDDL(paths []string) ([]*db.TableInfo, error) {
+	var files []string
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// 跳过 down 迁移，避免 DROP 之后的重建语句覆盖
+			if !d.IsDir() && strings.HasSuffix(path, ".sql") && !strings.HasSuffix(path, ".down.sql") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	byName := map[string]*db.TableInfo{}
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		tables, err := ParseDDL(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		for _, t := range tables {
+			byName[t.Name] = t
+		}
+	}
+	tables := make([]*db.TableInfo, 0, len(byName))
+	for _, t := range byName {
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func filterTables(tables []*db.TableInfo, only string) ([]*db.TableInfo, error) {
+	if only == "" {
+		return tables, nil
+	}
+	byName := make(map[string]*db.TableInfo, len(tables))
+	for _, t := range tables {
+		byName[t.Name] = t
+	}
+	var out []*db.TableInfo
+	for _, name := range strings.Split(only, ",") {
+		name = strings.TrimSpace(name)
+		t, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("mdbgen: table %q not found", name)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
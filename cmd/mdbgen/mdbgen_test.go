@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+const testDDL = `
+-- 用户表
+CREATE TABLE IF NOT EXISTS ` + "`user`" + ` (
+  ` + "`id`" + ` bigint unsigned NOT NULL AUTO_INCREMENT,
+  ` + "`user_id`" + ` varchar(64) NOT NULL DEFAULT '' COMMENT 'user''s id, external',
+  ` + "`nick_name`" + ` varchar(32) DEFAULT NULL,
+  ` + "`is_vip`" + ` tinyint(1) NOT NULL DEFAULT '0',
+  ` + "`balance`" + ` decimal(10,2) NOT NULL DEFAULT '0.00',
+  ` + "`birthday`" + ` date DEFAULT NULL,
+  ` + "`extra`" + ` json DEFAULT NULL,
+  ` + "`created_at`" + ` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ` + "`name_len`" + ` int GENERATED ALWAYS AS (char_length(` + "`nick_name`" + `)) VIRTUAL,
+  PRIMARY KEY (` + "`id`" + `),
+  UNIQUE KEY ` + "`uk_user_id`" + ` (` + "`user_id`" + `),
+  KEY ` + "`idx_created`" + ` (` + "`created_at`" + `, ` + "`nick_name`" + `(10))
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户表';
+
+INSERT INTO user (id) VALUES (1);
+`
+
+func TestParseDDL(t *testing.T) {
+	tables, err := ParseDDL(testDDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(tables))
+	}
+	tb := tables[0]
+	if tb.Name != "user" || tb.Engine != "InnoDB" || tb.Comment != "用户表" || len(tb.Columns) != 9 {
+		t.Fatalf("unexpected table: %+v", tb)
+	}
+	id, _ := tb.Column("id")
+	if !id.Unsigned || !id.AutoIncrement || id.Nullable || id.Key != "PRI" {
+		t.Errorf("unexpected id: %+v", id)
+	}
+	uid, _ := tb.Column("user_id")
+	if uid.Comment != "user's id, external" || uid.Key != "UNI" || uid.Default == nil || *uid.Default != "" {
+		t.Errorf("unexpected user_id: %+v", uid)
+	}
+	nick, _ := tb.Column("nick_name")
+	if !nick.Nullable || nick.Default != nil || nick.ColumnType != "varchar(32)" {
+		t.Errorf("unexpected nick_name: %+v", nick)
+	}
+	nameLen, _ := tb.Column("name_len")
+	if !nameLen.Generated || nameLen.GenerationExpr != "char_length(`nick_name`)" {
+		t.Errorf("unexpected name_len: %+v", nameLen)
+	}
+	if len(tb.Indexes) != 3 || tb.Indexes[2].Name != "idx_created" || strings.Join(tb.Indexes[2].Columns, ",") != "created_at,nick_name" {
+		t.Errorf("unexpected indexes: %+v", tb.Indexes)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tables, err := ParseDDL(testDDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate("models", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "models_gen.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	out := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"// Code generated by mdbgen. DO NOT EDIT.",
+		"type User struct {",
+		"Id       uint64       `db:\"id\" json:\"id\"`",
+		"UserId   string       `db:\"user_id\" json:\"user_id\"` // user's id, external",
+		"NickName *string",
+		"IsVip    bool",
+		"Balance  string",
+		"Birthday *db.Date",
+		"Extra    *db.Json",
+		"CreatedAt db.DateTime",
+		"NameLen  *int32",
+		"type UserColumns struct {",
+		"UserId:    builder.NewField(\"user_id\"),",
+		"func (UserColumns) Table() *builder.SqlBuilder { return builder.Table(\"user\") }",
+		"return []string{\"id\", \"user_id\", \"nick_name\", \"is_vip\", \"balance\", \"birthday\", \"extra\", \"created_at\", \"name_len\"}",
+	} {
+		if !strings.Contains(out, strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerate_ReservedNames(t *testing.T) {
+	tables, err := ParseDDL("CREATE TABLE `t` (`id` bigint NOT NULL, `table_name` varchar(64), `table` varchar(64), `columns` json, `created_at` datetime NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate("models", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "models_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("models", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, src)
+	}
+	out := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{"TableName_: builder.NewField(\"table_name\")", "Table_: builder.NewField(\"table\")", "Columns_: builder.NewField(\"columns\")"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerate_TypeNameCollision(t *testing.T) {
+	tables, err := ParseDDL("CREATE TABLE `user` (`id` bigint NOT NULL); CREATE TABLE `user_columns` (`id` bigint NOT NULL); CREATE TABLE `user_t` (`id` bigint NOT NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate("models", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "models_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("models", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, src)
+	}
+	for _, want := range []string{"type User struct", "type UserColumns_ struct", "type UserT_ struct"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code missing %q\n%s", want, src)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	cases := map[string]string{"user_id": "UserId", "id": "Id", "2fa_code": "X2faCode", "order-item": "OrderItem"}
+	for in, want := range cases {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
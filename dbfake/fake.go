@@ -0,0 +1,459 @@
+// Package dbfake 提供 db.Executor 的内存实现，用于业务代码的单元测试
+//
+//	f := dbfake.New()
+//	f.ExpectQuery("FROM `t_user`").WithParams(map[string]any{"id": 1}).
+//		WillReturnRows([]map[string]any{{"id": 1, "name": "nick"}})
+//	f.ExpectExec("^UPDATE `t_user`").WillReturnResult(dbfake.NewResult(0, 1))
+//
+//	svc := NewService(f) // 依赖 db.Executor
+//	...
+//	if err := f.ExpectationsWereMet(); err != nil { t.Fatal(err) }
+package dbfake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+)
+
+// ErrUnexpectedCall 没有匹配的预期
+var ErrUnexpectedCall = errors.New("dbfake: unexpected call")
+
+var _ db.Executor = (*Fake)(nil)
+
+// Kind 调用类型
+type Kind string
+
+const (
+	KindQuery Kind = "query" // QueryByBuilder、FetchByBuilder、QueryRaw
+	KindExec  Kind = "exec"  // ExecByBuilder、ExecExpect、ExecRaw
+)
+
+// Call 一次被记录的调用
+type Call struct {
+	Kind   Kind
+	Sql    string
+	Params map[string]any // builder 调用的命名参数
+	Args   []any          // Raw 调用的位置参数
+}
+
+// NewResult 创建预设的执行结果
+func NewResult(lastInsertId, rowsAffected int64) sql.Result {
+	return result{lastInsertId: lastInsertId, rowsAffected: rowsAffected}
+}
+
+type result struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertId, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// Fake 可编程的 db.Executor，按注册顺序匹配第一个未用完的预期
+type Fake struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	txDepth      int
+}
+
+// New 创建 Fake
+func New() *Fake {
+	return &Fake{}
+}
+
+// Expectation 一条预期：SQL 正则 + 可选的参数匹配 + 返回值
+type Expectation struct {
+	kind      Kind
+	sqlRe     *regexp.Regexp
+	params    map[string]any
+	args      []any
+	hasArgs   bool
+	match     func(Call) bool
+	rows      any
+	result    sql.Result
+	err       error
+	times     int // <=0 表示不限次数
+	used      int
+	unlimited bool
+}
+
+// ExpectQuery 预期一次查询，pattern 为匹配 SQL 的正则
+func (f *Fake) ExpectQuery(pattern string) *Expectation {
+	return f.expect(KindQuery, pattern)
+}
+
+// ExpectExec 预期一次执行，pattern 为匹配 SQL 的正则
+func (f *Fake) ExpectExec(pattern string) *Expectation {
+	return f.expect(KindExec, pattern)
+}
+
+func (f *Fake) expect(kind Kind, pattern string) *Expectation {
+	e := &Expectation{kind: kind, sqlRe: regexp.MustCompile(pattern), times: 1, result: result{}}
+	f.mu.Lock()
+	f.expectations = append(f.expectations, e)
+	f.mu.Unlock()
+	return e
+}
+
+// WithParams 要求 builder 调用的命名参数包含 params（按 reflect.DeepEqual 比较）
+func (e *Expectation) WithParams(params map[string]any) *Expectation {
+	e.params = params
+	return e
+}
+
+// WithArgs 要求 Raw 调用的位置参数完全一致
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args, e.hasArgs = args, true
+	return e
+}
+
+// Match 自定义匹配条件
+func (e *Expectation) Match(fn func(Call) bool) *Expectation {
+	e.match = fn
+	return e
+}
+
+// WillReturnRows 查询返回的行：
+//   - 与 dest 指向的类型相同的值（例如 []User、User），直接赋值
+//   - []map[string]any，按 db 标签映射到结构体（或直接赋给 *[]map[string]any）
+//
+// 没有设置或为空时：QueryByBuilder 与 MysqlClient 一样不返回错误且不修改 dest
+func (e *Expectation) WillReturnRows(rows any) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult 执行返回的结果
+func (e *Expectation) WillReturnResult(result sql.Result) *Expectation {
+	e.result = result
+	return e
+}
+
+// WillReturnError 返回错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times 允许匹配的次数，默认 1
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes 不限匹配次数，且不要求被调用
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times, e.unlimited = 0, true
+	return e
+}
+
+func (e *Expectation) String() string {
+	return fmt.Sprintf("%s %q", e.kind, e.sqlRe.String())
+}
+
+func (e *Expectation) matches(c Call) bool {
+	if e.kind != c.Kind || !e.sqlRe.MatchString(c.Sql) {
+		return false
+	}
+	if e.times > 0 && e.used >= e.times {
+		return false
+	}
+	for k, v := range e.params {
+		got, ok := c.Params[k]
+		if !ok || !reflect.DeepEqual(got, v) {
+			return false
+		}
+	}
+	if e.hasArgs && !reflect.DeepEqual(e.args, c.Args) && !(len(e.args) == 0 && len(c.Args) == 0) {
+		return false
+	}
+	return e.match == nil || e.match(c)
+}
+
+// find 记录调用并返回匹配的预期
+func (f *Fake) find(c Call) (*Expectation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, c)
+	for _, e := range f.expectations {
+		if e.matches(c) {
+			e.used++
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s %s params=%v args=%v", ErrUnexpectedCall, c.Kind, c.Sql, c.Params, c.Args)
+}
+
+// Calls 返回所有已记录的调用（包括未匹配的）
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// ExpectationsWereMet 检查所有预期是否都按次数被调用
+func (f *Fake) ExpectationsWereMet() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var missing []string
+	for _, e := range f.expectations {
+		if !e.unlimited && e.times > 0 && e.used < e.times {
+			missing = append(missing, fmt.Sprintf("%s (called %d/%d)", e, e.used, e.times))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("dbfake: unmet expectations: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+func (f *Fake) builderCall(ctx context.Context, kind Kind, b *builder.SqlBuilder) (Call, error) {
+	sqlStr, params, err := b.SqlContext(ctx)
+	if err != nil {
+		return Call{}, err
+	}
+	return Call{Kind: kind, Sql: sqlStr, Params: params}, nil
+}
+
+func (f *Fake) query(c Call, dest any, single bool) error {
+	e, err := f.find(c)
+	if err != nil {
+		return err
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return fill(dest, e.rows, single)
+}
+
+func (f *Fake) exec(c Call) (sql.Result, error) {
+	e, err := f.find(c)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.result, nil
+}
+
+func (f *Fake) QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
+	c, err := f.builderCall(ctx, KindQuery, b)
+	if err != nil {
+		return err
+	}
+	return f.query(c, dest, true)
+}
+
+func (f *Fake) FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
+	c, err := f.builderCall(ctx, KindQuery, b)
+	if err != nil {
+		return err
+	}
+	return f.query(c, dest, false)
+}
+
+func (f *Fake) ExecByBuilder(ctx context.Context, b *builder.SqlBuilder, tx ...*sqlx.Tx) (sql.Result, error) {
+	c, err := f.builderCall(ctx, KindExec, b)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := f.exec(c)
+	if err != nil {
+		return rs, err
+	}
+	if b.HasVersion() {
+		if n, _ := rs.RowsAffected(); n == 0 {
+			return rs, &db.StaleObjectError{Table: strings.Join(b.Tables(), ",")}
+		}
+	}
+	return rs, nil
+}
+
+func (f *Fake) ExecExpect(ctx context.Context, b *builder.SqlBuilder, n int64, tx ...*sqlx.Tx) (sql.Result, error) {
+	rs, err := f.ExecByBuilder(ctx, b, tx...)
+	if err != nil {
+		return rs, err
+	}
+	if affected, _ := rs.RowsAffected(); affected != n {
+		return rs, &db.AffectedRowsError{Expected: n, Actual: affected}
+	}
+	return rs, nil
+}
+
+func (f *Fake) ExecRaw(ctx context.Context, query string, args []any, tx ...*sqlx.Tx) (sql.Result, error) {
+	return f.exec(Call{Kind: KindExec, Sql: query, Args: args})
+}
+
+func (f *Fake) QueryRaw(ctx context.Context, dest any, query string, args []any, tx ...*sqlx.Tx) error {
+	return f.query(Call{Kind: KindQuery, Sql: query, Args: args}, dest, false)
+}
+
+// RunInTx 直接以 Fake 自身执行 fn，并记录 BEGIN 与 COMMIT/ROLLBACK（Kind 为 exec 的调用，不需要预期）
+// 与 MysqlClient 一样按结果执行 OnCommit/OnRollback 回调，嵌套调用的回调随最外层执行
+func (f *Fake) RunInTx(ctx context.Context, fn func(ctx context.Context, ex db.Executor) error) (err error) {
+	f.mu.Lock()
+	f.txDepth++
+	outer := f.txDepth == 1
+	if outer {
+		f.calls = append(f.calls, Call{Kind: KindExec, Sql: "BEGIN"})
+	}
+	f.mu.Unlock()
+	hookCtx := ctx
+	ctx, hooks := db.NewTxHooks(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mdb: transaction panic: %v", p)
+		}
+		f.mu.Lock()
+		f.txDepth--
+		if outer {
+			if err != nil {
+				f.calls = append(f.calls, Call{Kind: KindExec, Sql: "ROLLBACK"})
+			} else {
+				f.calls = append(f.calls, Call{Kind: KindExec, Sql: "COMMIT"})
+			}
+		}
+		f.mu.Unlock()
+		switch {
+		case err != nil:
+			hooks.RunRollback(hookCtx, err)
+		case outer:
+			hooks.RunCommit(hookCtx)
+		default:
+			// 嵌套调用成功时回调随外层事务执行
+			_ = db.OnCommit(hookCtx, hooks.RunCommit)
+			_ = db.OnRollback(hookCtx, hooks.RunRollback)
+		}
+	}()
+	return fn(ctx, f)
+}
+
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// fill 将预设的行写入 dest
+func fill(dest any, rows any, single bool) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return fmt.Errorf("dbfake: dest must be a non-nil pointer, got %T", dest)
+	}
+	target := dv.Elem()
+	if rows == nil {
+		if single {
+			return nil
+		}
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(rows)
+	if rv.Type().AssignableTo(target.Type()) {
+		target.Set(rv)
+		return nil
+	}
+	maps, ok := rows.([]map[string]any)
+	if !ok {
+		// 单行查询允许直接给出切片，取第一行
+		if single && rv.Kind() == reflect.Slice && rv.Type().Elem().AssignableTo(target.Type()) {
+			if rv.Len() > 0 {
+				target.Set(rv.Index(0))
+			}
+			return nil
+		}
+		return fmt.Errorf("dbfake: cannot assign %T to %s", rows, target.Type())
+	}
+	if single {
+		if len(maps) == 0 {
+			return nil
+		}
+		return fillRow(target, maps[0])
+	}
+	if target.Kind() != reflect.Slice {
+		return fmt.Errorf("dbfake: dest must be a pointer to slice, got %T", dest)
+	}
+	out := reflect.MakeSlice(target.Type(), len(maps), len(maps))
+	for i, m := range maps {
+		if err := fillRow(out.Index(i), m); err != nil {
+			return err
+		}
+	}
+	target.Set(out)
+	return nil
+}
+
+// fillRow 按 db 标签将一行写入结构体（或指针、map、单列标量）
+func fillRow(v reflect.Value, row map[string]any) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Map:
+		v.Set(reflect.ValueOf(row))
+		return nil
+	case v.Kind() != reflect.Struct || reflect.PointerTo(v.Type()).Implements(scannerType):
+		if len(row) != 1 {
+			return fmt.Errorf("dbfake: scalar dest %s needs exactly one column, got %d", v.Type(), len(row))
+		}
+		for _, val := range row {
+			return assign(v, val)
+		}
+	}
+	fields := mapper.TypeMap(v.Type())
+	for col, val := range row {
+		fi := fields.GetByPath(col)
+		if fi == nil {
+			return fmt.Errorf("dbfake: missing destination name %s in %s", col, v.Type())
+		}
+		if err := assign(reflectx.FieldByIndexes(v, fi.Index), val); err != nil {
+			return fmt.Errorf("dbfake: column %s: %w", col, err)
+		}
+	}
+	return nil
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// assign 赋值，支持可转换类型、指针与 sql.Scanner
+func assign(field reflect.Value, val any) error {
+	if val == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(val)
+	}
+	rv := reflect.ValueOf(val)
+	if field.Kind() == reflect.Pointer && rv.Kind() != reflect.Pointer {
+		p := reflect.New(field.Type().Elem())
+		if err := assign(p.Elem(), val); err != nil {
+			return err
+		}
+		field.Set(p)
+		return nil
+	}
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case rv.Type().ConvertibleTo(field.Type()) && rv.Kind() != reflect.String && field.Kind() != reflect.String:
+		field.Set(rv.Convert(field.Type()))
+	case rv.Kind() == reflect.String && field.Kind() == reflect.String:
+		field.SetString(rv.String())
+	default:
+		return fmt.Errorf("cannot assign %T to %s", val, field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package dbfake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+)
+
+type user struct {
+	Id   int64   `db:"id"`
+	Name string  `db:"name"`
+	Nick *string `db:"nick"`
+}
+
+// rename 模拟依赖 db.Executor 的业务代码
+func rename(ctx context.Context, ex db.Executor, id int64, name string) (user, error) {
+	var u user
+	err := ex.RunInTx(ctx, func(ctx context.Context, tx db.Executor) error {
+		tb := builder.Table("t_user")
+		if err := tx.QueryByBuilder(ctx, tb.Where(tb.Field("id").Eq(id, "id")).First(), &u); err != nil {
+			return err
+		}
+		upd := builder.Table("t_user")
+		_, err := tx.ExecExpect(ctx, upd.Where(upd.Field("id").Eq(id, "id")).UpdateMap(map[string]any{"name": name}), 1)
+		return err
+	})
+	return u, err
+}
+
+func TestFake_BuilderCalls(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+	f.ExpectQuery("FROM `t_user`").WithParams(map[string]any{"id": int64(7)}).
+		WillReturnRows([]map[string]any{{"id": 7, "name": "old", "nick": "n"}})
+	f.ExpectExec("^UPDATE `t_user`").WithParams(map[string]any{"name": "new"}).WillReturnResult(NewResult(0, 1))
+
+	u, err := rename(ctx, f, 7, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Id != 7 || u.Name != "old" || u.Nick == nil || *u.Nick != "n" {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if err := f.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	calls := f.Calls()
+	if len(calls) != 4 || calls[0].Sql != "BEGIN" || calls[3].Sql != "COMMIT" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+
+	// 参数不匹配时返回 ErrUnexpectedCall，并回滚
+	f = New()
+	f.ExpectQuery("FROM `t_user`").WithParams(map[string]any{"id": int64(8)})
+	if _, err := rename(ctx, f, 7, "new"); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("expected ErrUnexpectedCall, got %v", err)
+	}
+	if calls := f.Calls(); calls[len(calls)-1].Sql != "ROLLBACK" {
+		t.Errorf("expected rollback, got %+v", calls)
+	}
+	if err := f.ExpectationsWereMet(); err == nil {
+		t.Error("expected unmet expectation")
+	}
+
+	// 影响行数不符
+	f = New()
+	f.ExpectQuery("FROM `t_user`").AnyTimes()
+	f.ExpectExec("UPDATE").WillReturnResult(NewResult(0, 0))
+	var rowsErr *db.AffectedRowsError
+	if _, err := rename(ctx, f, 7, "new"); !errors.As(err, &rowsErr) {
+		t.Errorf("expected AffectedRowsError, got %v", err)
+	}
+}
+
+func TestFake_RawAndTypedRows(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+	boom := errors.New("boom")
+	f.ExpectQuery(`^SELECT \* FROM t_user WHERE id IN`).WithArgs(1, 2).
+		WillReturnRows([]user{{Id: 1}, {Id: 2}})
+	f.ExpectQuery(`COUNT`).WillReturnRows([]map[string]any{{"n": 3}})
+	f.ExpectExec(`^DELETE`).WillReturnError(boom).Times(2)
+
+	var users []user
+	if err := f.QueryRaw(ctx, &users, "SELECT * FROM t_user WHERE id IN (?, ?)", []any{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(users, []user{{Id: 1}, {Id: 2}}) {
+		t.Errorf("users = %+v", users)
+	}
+	var counts []int64
+	if err := f.QueryRaw(ctx, &counts, "SELECT COUNT(*) AS n FROM t_user", nil); err != nil || !reflect.DeepEqual(counts, []int64{3}) {
+		t.Errorf("counts = %v, err = %v", counts, err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := f.ExecRaw(ctx, "DELETE FROM t_user", nil); !errors.Is(err, boom) {
+			t.Errorf("expected boom, got %v", err)
+		}
+	}
+	if _, err := f.ExecRaw(ctx, "DELETE FROM t_user", nil); !errors.Is(err, ErrUnexpectedCall) {
+		t.Errorf("expected ErrUnexpectedCall after Times(2), got %v", err)
+	}
+	if err := f.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFake_TxHooks(t *testing.T) {
+	ctx := context.Background()
+	f := New()
+	var events []string
+	err := f.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		_ = db.OnCommit(ctx, func(context.Context) { events = append(events, "outer") })
+		_ = ex.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+			_ = db.OnRollback(ctx, func(context.Context, error) { events = append(events, "inner1 rollback") })
+			return errors.New("inner failed")
+		})
+		return ex.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+			return db.OnCommit(ctx, func(context.Context) { events = append(events, "inner2") })
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(events); got != "[inner1 rollback outer inner2]" {
+		t.Fatalf("events = %s", got)
+	}
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStaleObject 乐观锁更新失败：记录已被其他人修改（版本号不匹配）或已不存在
+var ErrStaleObject = errors.New("mdb: stale object")
+
+// StaleObjectError 带乐观锁的更新没有影响任何行，可以用 errors.Is(err, ErrStaleObject) 判断
+type StaleObjectError struct {
+	Table string
+}
+
+func (e *StaleObjectError) Error() string {
+	return fmt.Sprintf("mdb: stale object in %s: version mismatch or row not found", e.Table)
+}
+
+func (e *StaleObjectError) Is(target error) bool {
+	return target == ErrStaleObject
+}
+
+// AffectedRowsError ExecExpect 的影响行数与预期不符
+type AffectedRowsError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *AffectedRowsError) Error() string {
+	return fmt.Sprintf("mdb: expected %d affected rows, got %d", e.Expected, e.Actual)
+}
+
+// ErrClientClosed 客户端已调用 Shutdown，不再接受新的调用
+var ErrClientClosed = errors.New("mdb: client closed")
+
+// ClientClosedError Shutdown 之后发起的调用，可以用 errors.Is(err, ErrClientClosed) 判断
+type ClientClosedError struct {
+	Op string
+}
+
+func (e *ClientClosedError) Error() string {
+	return fmt.Sprintf("mdb: %s rejected: client closed", e.Op)
+}
+
+func (e *ClientClosedError) Is(target error) bool {
+	return target == ErrClientClosed
+}
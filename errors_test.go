@@ -0,0 +1,18 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStaleObjectError_Is(t *testing.T) {
+	err := fmt.Errorf("save user: %w", &StaleObjectError{Table: "t_user"})
+	if !errors.Is(err, ErrStaleObject) {
+		t.Fatal("StaleObjectError should match ErrStaleObject")
+	}
+	var ae *AffectedRowsError
+	if errors.As(err, &ae) {
+		t.Fatal("StaleObjectError should not match AffectedRowsError")
+	}
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/preceeder/db/builder"
+)
+
+// Executor MysqlClient 的 Builder、Raw 与事务方法，业务代码依赖该接口即可替换为 dbfake.Fake 或包装实现
+//
+// tx 参数与 MysqlClient 保持一致；在 RunInTx 的回调中使用传入的 Executor 时不需要再传 tx
+type Executor interface {
+	QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error
+	FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error
+	ExecByBuilder(ctx context.Context, b *builder.SqlBuilder, tx ...*sqlx.Tx) (sql.Result, error)
+	ExecExpect(ctx context.Context, b *builder.SqlBuilder, n int64, tx ...*sqlx.Tx) (sql.Result, error)
+	ExecRaw(ctx context.Context, query string, args []any, tx ...*sqlx.Tx) (sql.Result, error)
+	QueryRaw(ctx context.Context, dest any, query string, args []any, tx ...*sqlx.Tx) error
+
+	// RunInTx 在事务中执行 fn，fn 中通过 ex 执行的语句都在该事务内
+	// fn 返回错误或 panic 时回滚，否则提交；已在事务中时在当前事务内使用保存点
+	// fn 的 ctx 可用于 OnCommit/OnRollback 注册回调
+	RunInTx(ctx context.Context, fn func(ctx context.Context, ex Executor) error) error
+}
+
+var (
+	_ Executor = MysqlClient{}
+	_ Executor = TxExecutor{}
+)
+
+// RunInTx 开启事务并以事务内的 Executor 执行 fn
+func (s MysqlClient) RunInTx(ctx context.Context, fn func(ctx context.Context, ex Executor) error) error {
+	return s.runTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		return fn(ctx, TxExecutor{client: s, tx: tx})
+	})
+}
+
+// runTx 开启事务执行 fn：返回错误或 panic 时回滚，否则提交
+// panic 会被转换为错误返回（与原 Transaction 一样不会向上传播）
+// fn 的 ctx 带有 TxHooks，结果确定后执行 OnCommit/OnRollback 回调
+func (s MysqlClient) runTx(ctx context.Context, fn func(ctx context.Context, tx *sqlx.Tx) error) (err error) {
+	t, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mdb: transaction panic: %v", p)
+		}
+		if err != nil {
+			t.rollback(err)
+			return
+		}
+		err = t.commit()
+	}()
+	return fn(t.ctx, t.tx)
+}
+
+// clientTx 通过 beginTx 开启的事务，结束时必须调用 commit 或 rollback 之一
+type clientTx struct {
+	client  MysqlClient
+	tx      *sqlx.Tx
+	ctx     context.Context // 传给回调的 ctx：带有 TxHooks，可被强制关闭取消
+	hookCtx context.Context // 执行 OnCommit/OnRollback 回调的 ctx（调用方传入的 ctx）
+	hooks   *TxHooks
+	done    func(error)
+}
+
+// beginTx 经过 begin 的检查（Shutdown、熔断、并发限制）后开启事务，并在 ctx 中放入新的 TxHooks
+func (s MysqlClient) beginTx(ctx context.Context) (*clientTx, error) {
+	hookCtx := ctx
+	ctx, done, err := s.begin(ctx, "Transaction", "", 0)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.Db.BeginTxx(ctx, nil)
+	if err != nil {
+		s.logger().log(ctx, LogRollback, slog.LevelError, "mdb transaction begin failed", errorAttr(err))
+		done(err)
+		return nil, err
+	}
+	ctx, hooks := NewTxHooks(ctx)
+	hooks.log = s.logger()
+	// 提交/回滚回调在释放名额之前执行，同样复用事务的名额
+	hookCtx = s.bulkhead.hold(hookCtx)
+	return &clientTx{client: s, tx: tx, ctx: ctx, hookCtx: hookCtx, hooks: hooks, done: done}, nil
+}
+
+// rollback 以 cause 回滚并执行 OnRollback 回调
+func (t *clientTx) rollback(cause error) {
+	l := t.client.logger()
+	if err := t.tx.Rollback(); err != nil {
+		l.log(t.ctx, LogRollback, slog.LevelError, "mdb transaction rollback failed", errorAttr(err), slog.String("cause", cause.Error()))
+	} else {
+		l.log(t.ctx, LogRollback, slog.LevelError, "mdb transaction rolled back", errorAttr(cause))
+	}
+	t.hooks.RunRollback(t.hookCtx, cause)
+	t.done(cause)
+}
+
+// commit 提交并执行 OnCommit 回调；提交失败时执行 OnRollback 回调并返回错误
+func (t *clientTx) commit() error {
+	if err := t.tx.Commit(); err != nil {
+		t.client.logger().log(t.ctx, LogRollback, slog.LevelError, "mdb transaction commit failed", errorAttr(err))
+		t.hooks.RunRollback(t.hookCtx, err)
+		t.done(err)
+		return err
+	}
+	t.hooks.RunCommit(t.hookCtx)
+	t.done(nil)
+	return nil
+}
+
+// TxExecutor 绑定到一个事务的 Executor，所有方法都在该事务内执行（显式传入的 tx 优先）
+type TxExecutor struct {
+	client MysqlClient
+	tx     *sqlx.Tx
+}
+
+// NewTxExecutor 将已有事务包装为 Executor
+func NewTxExecutor(client MysqlClient, tx *sqlx.Tx) TxExecutor {
+	return TxExecutor{client: client, tx: tx}
+}
+
+// Tx 返回绑定的事务
+func (e TxExecutor) Tx() *sqlx.Tx {
+	return e.tx
+}
+
+func (e TxExecutor) txs(tx []*sqlx.Tx) []*sqlx.Tx {
+	if len(tx) > 0 && tx[0] != nil {
+		return tx
+	}
+	return []*sqlx.Tx{e.tx}
+}
+
+func (e TxExecutor) QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
+	return e.client.QueryByBuilder(ctx, b, dest, e.txs(tx)...)
+}
+
+func (e TxExecutor) FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
+	return e.client.FetchByBuilder(ctx, b, dest, e.txs(tx)...)
+}
+
+func (e TxExecutor) ExecByBuilder(ctx context.Context, b *builder.SqlBuilder, tx ...*sqlx.Tx) (sql.Result, error) {
+	return e.client.ExecByBuilder(ctx, b, e.txs(tx)...)
+}
+
+func (e TxExecutor) ExecExpect(ctx context.Context, b *builder.SqlBuilder, n int64, tx ...*sqlx.Tx) (sql.Result, error) {
+	return e.client.ExecExpect(ctx, b, n, e.txs(tx)...)
+}
+
+func (e TxExecutor) ExecRaw(ctx context.Context, query string, args []any, tx ...*sqlx.Tx) (sql.Result, error) {
+	return e.client.ExecRaw(ctx, query, args, e.txs(tx)...)
+}
+
+func (e TxExecutor) QueryRaw(ctx context.Context, dest any, query string, args []any, tx ...*sqlx.Tx) error {
+	return e.client.QueryRaw(ctx, dest, query, args, e.txs(tx)...)
+}
+
+// savepointSeq 保存点名称序号
+var savepointSeq atomic.Uint64
+
+// RunInTx 已在事务中，在当前事务内以保存点执行 fn：fn 返回错误或 panic 时回滚到保存点并返回该错误，否则释放保存点
+// 外层事务仍可以在处理该错误后继续并提交；保存点内注册的回调见 TxHooks
+func (e TxExecutor) RunInTx(ctx context.Context, fn func(ctx context.Context, ex Executor) error) (err error) {
+	name := fmt.Sprintf("mdb_sp_%d", savepointSeq.Add(1))
+	if _, err := e.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	ctx, hooks := nestedTxHooks(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mdb: transaction panic: %v", p)
+		}
+		if err != nil {
+			if _, rbErr := e.tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+				e.client.logger().log(ctx, LogRollback, slog.LevelError, "mdb rollback to savepoint failed", slog.String("savepoint", name), errorAttr(rbErr), slog.String("cause", err.Error()))
+			}
+			if hooks != nil {
+				hooks.RunRollback(ctx, err)
+			}
+			return
+		}
+		if _, err = e.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+			if hooks != nil {
+				hooks.RunRollback(ctx, err)
+			}
+			return
+		}
+		if hooks != nil {
+			hooks.release()
+		}
+	}()
+	return fn(ctx, e)
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// newTestClient 连接 MYSQL_* 环境变量指定的真实 MySQL，未设置时跳过
+// 其余用例都在 mysqltest/integration 中运行，这里只保留内存库不支持的特性
+func newTestClient(t *testing.T) *MysqlClient {
+	host := os.Getenv("MYSQL_HOST")
+	port := os.Getenv("MYSQL_PORT")
+	user := os.Getenv("MYSQL_USER")
+	db := os.Getenv("MYSQL_DB")
+	if host == "" || port == "" || user == "" || db == "" {
+		t.Skip("skip: MYSQL_HOST/PORT/USER/PASS/DB not set")
+	}
+	return NewMysqlClient(MysqlConfig{
+		Host:        host,
+		Port:        port,
+		User:        user,
+		Password:    os.Getenv("MYSQL_PASS"),
+		Database:    db,
+		MaxIdleCons: 2,
+		MaxOpenCons: 5,
+	})
+}
+
+// 需要真实 MySQL（mysqltest 的内存库不支持保存点）
+func TestRunInTx_Savepoint(t *testing.T) {
+	s := newTestClient(t)
+	defer s.MysqlPoolClose()
+	ctx := context.Background()
+
+	if _, err := s.ExecRaw(ctx, "CREATE TABLE IF NOT EXISTS tmp_mdb_savepoint (id BIGINT PRIMARY KEY)", nil); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _, _ = s.ExecRaw(ctx, "DROP TABLE tmp_mdb_savepoint", nil) }()
+
+	var events []string
+	err := s.RunInTx(ctx, func(ctx context.Context, ex Executor) error {
+		if _, err := ex.ExecRaw(ctx, "INSERT INTO tmp_mdb_savepoint (id) VALUES (1)", nil); err != nil {
+			return err
+		}
+		// 失败的保存点只回滚自身
+		inner := ex.RunInTx(ctx, func(ctx context.Context, ex Executor) error {
+			_ = OnCommit(ctx, func(context.Context) { events = append(events, "inner1 commit") })
+			_ = OnRollback(ctx, func(context.Context, error) { events = append(events, "inner1 rollback") })
+			if _, err := ex.ExecRaw(ctx, "INSERT INTO tmp_mdb_savepoint (id) VALUES (2)", nil); err != nil {
+				return err
+			}
+			return errors.New("inner failed")
+		})
+		if inner == nil {
+			t.Error("inner error lost")
+		}
+		return ex.RunInTx(ctx, func(ctx context.Context, ex Executor) error {
+			_ = OnCommit(ctx, func(context.Context) { events = append(events, "inner2 commit") })
+			_, err := ex.ExecRaw(ctx, "INSERT INTO tmp_mdb_savepoint (id) VALUES (3)", nil)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	if err := s.QueryRaw(ctx, &ids, "SELECT id FROM tmp_mdb_savepoint ORDER BY id", nil); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[1 3]" || fmt.Sprint(events) != "[inner1 rollback inner2 commit]" {
+		t.Fatalf("ids = %v, events = %v", ids, events)
+	}
+}
+
+func TestTxHooks_Nested(t *testing.T) {
+	var events []string
+	ctx, root := NewTxHooks(context.Background())
+	add := func(ctx context.Context, name string) {
+		if err := OnCommit(ctx, func(context.Context) { events = append(events, name+" commit") }); err != nil {
+			t.Fatal(err)
+		}
+		if err := OnRollback(ctx, func(_ context.Context, err error) { events = append(events, name+" rollback: "+err.Error()) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(ctx, "outer")
+
+	// 回滚到保存点：立即执行其 OnRollback
+	spCtx, sp := nestedTxHooks(ctx)
+	add(spCtx, "sp1")
+	sp.RunRollback(spCtx, errors.New("sp1 failed"))
+	if err := OnCommit(spCtx, func(context.Context) {}); !errors.Is(err, ErrNotInTransaction) {
+		t.Fatalf("register after rollback: %v", err)
+	}
+
+	// 释放保存点：回调并入外层，两层嵌套同样逐级合并
+	spCtx, sp = nestedTxHooks(ctx)
+	add(spCtx, "sp2")
+	deepCtx, deep := nestedTxHooks(spCtx)
+	add(deepCtx, "sp3")
+	deep.release()
+	sp.release()
+	if fmt.Sprint(events) != "[sp1 rollback: sp1 failed]" {
+		t.Fatalf("events before commit = %v", events)
+	}
+
+	root.RunCommit(context.Background())
+	want := "[sp1 rollback: sp1 failed outer commit sp2 commit sp3 commit]"
+	if fmt.Sprint(events) != want {
+		t.Fatalf("events = %v, want %s", events, want)
+	}
+	// 已结束的事务不能再注册
+	if err := OnRollback(ctx, func(context.Context, error) {}); !errors.Is(err, ErrNotInTransaction) {
+		t.Fatalf("register after commit: %v", err)
+	}
+}
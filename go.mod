@@ -6,6 +6,7 @@ require (
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/jmoiron/sqlx v1.4.0
 	github.com/pkg/errors v0.9.1
+	golang.org/x/sync v0.16.0
 )
 
 require filippo.io/edwards25519 v1.1.0 // indirect
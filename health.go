@@ -0,0 +1,339 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// HealthStatus 一次健康检查的结果
+type HealthStatus struct {
+	Healthy   bool          `json:"healthy"`
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Latency   time.Duration `json:"latency"` // Ping 耗时
+	Version   string        `json:"version,omitempty"`
+	ReadOnly  bool          `json:"read_only"`
+	Replica   bool          `json:"replica"` // 是否为从库（SHOW REPLICA STATUS 有结果）
+	// ReplicationLag 从库延迟（Seconds_Behind_Source），不是从库或复制线程未运行时为 nil
+	ReplicationLag *time.Duration `json:"replication_lag,omitempty"`
+	Pool           PoolStats      `json:"pool"`
+}
+
+// PoolStats 连接池状态
+type PoolStats struct {
+	sql.DBStats
+	// Saturation 使用中的连接占 MaxOpenConnections 的比例，未限制最大连接数时为 0
+	Saturation float64 `json:"saturation"`
+}
+
+func poolStats(stats sql.DBStats) PoolStats {
+	p := PoolStats{DBStats: stats}
+	if stats.MaxOpenConnections > 0 {
+		p.Saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+	return p
+}
+
+// HealthOptions 健康判定条件，零值只要求 Ping 成功
+type HealthOptions struct {
+	RequireWritable bool          // read_only 为 ON 时视为不健康（写库使用）
+	MaxLag          time.Duration // 从库延迟超过该值（或复制中断）时视为不健康，0 表示不检查
+	MaxSaturation   float64       // 连接池饱和度超过该值时视为不健康，0 表示不检查
+}
+
+// Health 检查数据库状态：Ping 延迟、版本、read_only、从库延迟以及连接池状态
+// 返回的 error 与 HealthStatus.Error 一致，Healthy 为 false 时不为 nil
+func (s MysqlClient) Health(ctx context.Context) (HealthStatus, error) {
+	return s.health(ctx, HealthOptions{}, nil)
+}
+
+// health 执行一次检查；noReplication 不为 nil 时记录没有权限读取从库状态，之后的检查不再读取
+func (s MysqlClient) health(ctx context.Context, opts HealthOptions, noReplication *atomic.Bool) (HealthStatus, error) {
+	st := HealthStatus{CheckedAt: time.Now()}
+	fail := func(err error) (HealthStatus, error) {
+		st.Healthy = false
+		st.Error = err.Error()
+		st.Pool = poolStats(s.Db.Stats())
+		return st, err
+	}
+
+	if s.inflight != nil && s.inflight.isClosing() {
+		return fail(&ClientClosedError{Op: "Health"})
+	}
+	start := time.Now()
+	if err := s.Db.PingContext(ctx); err != nil {
+		return fail(err)
+	}
+	st.Latency = time.Since(start)
+
+	var readOnly sql.NullString
+	if err := s.Db.QueryRowContext(ctx, "SELECT VERSION(), @@read_only").Scan(&st.Version, &readOnly); err != nil {
+		return fail(err)
+	}
+	st.ReadOnly = readOnly.String == "1" || strings.EqualFold(readOnly.String, "ON")
+
+	// 没有 REPLICATION CLIENT 权限时不影响健康判定
+	if noReplication == nil || !noReplication.Load() {
+		if err := s.replicationStatus(ctx, &st); err != nil {
+			if noReplication != nil && isAccessDenied(err) {
+				noReplication.Store(true)
+				s.logger().log(ctx, LogClient, slog.LevelWarn, "mdb health: replication status unavailable, skipped from now on", errorAttr(err))
+			} else {
+				s.logger().log(ctx, LogClient, slog.LevelWarn, "mdb health: replication status unavailable", errorAttr(err))
+			}
+		}
+	}
+
+	st.Pool = poolStats(s.Db.Stats())
+	switch {
+	case opts.RequireWritable && st.ReadOnly:
+		return fail(errors.New("mdb: server is read only"))
+	case opts.MaxLag > 0 && st.Replica && st.ReplicationLag == nil:
+		return fail(errors.New("mdb: replication is not running"))
+	case opts.MaxLag > 0 && st.ReplicationLag != nil && *st.ReplicationLag > opts.MaxLag:
+		return fail(fmt.Errorf("mdb: replication lag %s exceeds %s", *st.ReplicationLag, opts.MaxLag))
+	case opts.MaxSaturation > 0 && st.Pool.Saturation > opts.MaxSaturation:
+		return fail(fmt.Errorf("mdb: connection pool saturation %.2f exceeds %.2f", st.Pool.Saturation, opts.MaxSaturation))
+	}
+	st.Healthy = true
+	return st, nil
+}
+
+// replicationStatus 读取从库状态，MySQL 8.0.22 之前使用 SHOW SLAVE STATUS
+func (s MysqlClient) replicationStatus(ctx context.Context, st *HealthStatus) error {
+	row, err := s.showStatusRow(ctx, "SHOW REPLICA STATUS")
+	if err != nil && !isAccessDenied(err) {
+		row, err = s.showStatusRow(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil || row == nil {
+		return err
+	}
+	st.Replica = true
+	for _, key := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+		if v, ok := row[key]; ok && v != "" {
+			if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+				lag := time.Duration(secs) * time.Second
+				st.ReplicationLag = &lag
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// isAccessDenied 是否为缺少权限（1227，例如没有 REPLICATION CLIENT）
+func isAccessDenied(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1227
+}
+
+// showStatusRow 读取 SHOW ... STATUS 的第一行，没有结果时返回 nil
+func (s MysqlClient) showStatusRow(ctx context.Context, query string) (map[string]string, error) {
+	rows, err := s.Db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]string, len(cols))
+	for i, col := range cols {
+		row[col] = string(values[i])
+	}
+	return row, nil
+}
+
+// -------------------- 后台检查 --------------------
+
+// HealthCheckerConfig 后台健康检查配置
+type HealthCheckerConfig struct {
+	HealthOptions
+	Interval         time.Duration // 检查间隔，默认 10s
+	Timeout          time.Duration // 单次检查超时，默认 3s
+	FailureThreshold int           // 连续失败多少次后变为未就绪，默认 3
+}
+
+// HealthChecker 在后台定期检查数据库状态，连续失败 FailureThreshold 次后变为未就绪，成功一次即恢复
+// 第一次检查成功之前为未就绪
+type HealthChecker struct {
+	client MysqlClient
+	cfg    HealthCheckerConfig
+
+	mu       sync.RWMutex
+	last     HealthStatus
+	ready    bool
+	failures int
+	started  bool
+
+	noReplication atomic.Bool // 没有权限读取从库状态，后续检查跳过
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewHealthChecker 创建健康检查器，需要调用 Start 开始后台检查
+func NewHealthChecker(client *MysqlClient, cfg HealthCheckerConfig) *HealthChecker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	return &HealthChecker{
+		client: *client,
+		cfg:    cfg,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start 立即检查一次，然后按 Interval 在后台检查，直到 Stop 或 ctx 结束
+func (h *HealthChecker) Start(ctx context.Context) {
+	h.mu.Lock()
+	if h.started {
+		h.mu.Unlock()
+		return
+	}
+	h.started = true
+	h.mu.Unlock()
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			h.Check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台检查并等待其退出
+func (h *HealthChecker) Stop() {
+	h.stopOnce.Do(func() { close(h.stop) })
+	h.mu.RLock()
+	started := h.started
+	h.mu.RUnlock()
+	if started {
+		<-h.done
+	}
+}
+
+// Check 同步执行一次检查并更新就绪状态
+func (h *HealthChecker) Check(ctx context.Context) HealthStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+	st, err := h.client.health(ctx, h.cfg.HealthOptions, &h.noReplication)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = st
+	if err == nil {
+		if !h.ready {
+			h.client.logger().log(ctx, LogClient, slog.LevelInfo, "mdb health: ready", durationAttr(st.Latency))
+		}
+		h.failures, h.ready = 0, true
+		return st
+	}
+	h.failures++
+	if h.ready && h.failures >= h.cfg.FailureThreshold {
+		h.ready = false
+		h.client.logger().log(ctx, LogClient, slog.LevelError, "mdb health: not ready", slog.Int("failures", h.failures), errorAttr(err))
+	}
+	return st
+}
+
+// Ready 当前是否就绪
+func (h *HealthChecker) Ready() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.ready
+}
+
+// Last 最近一次检查的结果
+func (h *HealthChecker) Last() HealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.last
+}
+
+// -------------------- HTTP --------------------
+
+// Handler 返回同时处理 /healthz 与 /readyz 的 http.Handler（按路径后缀匹配，便于挂在任意前缀下）
+func (h *HealthChecker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/healthz"):
+			h.HealthzHandler().ServeHTTP(w, r)
+		case strings.HasSuffix(r.URL.Path, "/readyz"):
+			h.ReadyzHandler().ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// HealthzHandler 使用客户端连接池实时检查一次，健康返回 200，否则 503；不影响就绪状态
+func (h *HealthChecker) HealthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.cfg.Timeout)
+		defer cancel()
+		st, _ := h.client.health(ctx, h.cfg.HealthOptions, &h.noReplication)
+		writeHealth(w, st.Healthy, st)
+	})
+}
+
+// ReadyzHandler 返回后台检查得到的就绪状态，就绪返回 200，否则 503；不访问数据库
+func (h *HealthChecker) ReadyzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.mu.RLock()
+		ready, last := h.ready, h.last
+		h.mu.RUnlock()
+		writeHealth(w, ready, last)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, ok bool, st HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(st)
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker_ReplicationAccessDenied(t *testing.T) {
+	// 两次检查：第一次 SHOW REPLICA STATUS 因权限失败，第二次不再读取从库状态
+	version := `{"seq":%d,"op":"query","sql":"SELECT VERSION(), @@read_only","columns":["VERSION()","@@read_only"],"rows":[[{"t":"bytes","v":"8.0.36"},{"t":"bytes","v":"0"}]]}`
+	fixture := strings.Join([]string{
+		fmt.Sprintf(version, 1),
+		`{"seq":2,"op":"query","sql":"SHOW REPLICA STATUS","error":"Access denied; you need (at least one of) the SUPER, REPLICATION CLIENT privilege(s) for this operation","error_number":1227}`,
+		fmt.Sprintf(version, 3),
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "health.jsonl")
+	if err := os.WriteFile(path, []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	cli, rep, err := NewReplayClient(MysqlConfig{}, path, WithLogger(LoggerConfig{Handler: slog.NewJSONHandler(&buf, nil)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.Db.Close()
+
+	h := NewHealthChecker(cli, HealthCheckerConfig{Interval: time.Hour})
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if st := h.Check(ctx); !st.Healthy || st.Replica {
+			t.Fatalf("check %d: %+v", i+1, st)
+		}
+	}
+	if err := rep.Done(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "replication status unavailable"); n != 1 {
+		t.Fatalf("warned %d times, want 1\n%s", n, buf.String())
+	}
+}
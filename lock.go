@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	// ErrLockNotAcquired 在等待时间内没有获取到命名锁
+	ErrLockNotAcquired = errors.New("mdb: lock not acquired")
+	// ErrLockLost 持锁期间会话断开或锁被释放（例如连接被 KILL）
+	ErrLockLost = errors.New("mdb: lock lost")
+)
+
+// 命名锁基于 MySQL 的 GET_LOCK/RELEASE_LOCK，锁与会话绑定：
+// 持锁期间固定占用一个连接，并按 Heartbeat 在该连接上检查锁是否仍由本会话持有（同时保持会话活跃，避免 wait_timeout）。
+// fn 的 ctx 在锁丢失或调用方 ctx 结束时取消，fn 返回（包括 panic）后释放锁。
+// 注意：GET_LOCK 只在单个 MySQL 实例内互斥，主从切换后锁不会保留
+
+// LockOptions 命名锁选项
+type LockOptions struct {
+	Wait      time.Duration // 获取锁的最长等待时间，0 表示不等待，<0 表示一直等待
+	Heartbeat time.Duration // 持锁期间检查锁的间隔，默认 5s
+}
+
+// Lock 获取命名锁 name（最多等待 wait）并在持锁期间执行 fn
+// 没有获取到锁返回 ErrLockNotAcquired；持锁期间锁丢失时取消 fn 的 ctx，并返回 ErrLockLost（与 fn 的错误合并）
+func (s MysqlClient) Lock(ctx context.Context, name string, wait time.Duration, fn func(ctx context.Context) error) error {
+	return s.LockWithOptions(ctx, name, LockOptions{Wait: wait}, fn)
+}
+
+// TryLock 尝试获取命名锁，不等待；没有获取到时返回 false 且不执行 fn
+// 只有获取到锁（执行了 fn）时返回 true；获取锁之前失败（例如连接错误）返回 false 与错误
+func (s MysqlClient) TryLock(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	acquired, err := s.lockWithOptions(ctx, name, LockOptions{}, fn)
+	if errors.Is(err, ErrLockNotAcquired) {
+		return false, nil
+	}
+	return acquired, err
+}
+
+// LockWithOptions 同 Lock，可以指定心跳间隔
+func (s MysqlClient) LockWithOptions(ctx context.Context, name string, opts LockOptions, fn func(ctx context.Context) error) error {
+	_, err := s.lockWithOptions(ctx, name, opts, fn)
+	return err
+}
+
+func (s MysqlClient) lockWithOptions(ctx context.Context, name string, opts LockOptions, fn func(ctx context.Context) error) (bool, error) {
+	ctx, untrack, err := s.inflight.add(ctx, "Lock", name)
+	if err != nil {
+		return false, err
+	}
+	defer untrack()
+	return s.holdLock(ctx, name, opts, fn)
+}
+
+// LeaseOptions LeaderLock 选项
+type LeaseOptions struct {
+	Heartbeat time.Duration // 持锁期间检查（续租）的间隔，默认 5s
+	Retry     time.Duration // 没有获取到锁或锁丢失后重新竞争的间隔，默认 10s
+}
+
+// LeaderLock 用于定时任务等场景的选主：不断尝试获取命名锁，获取到后执行 fn，直到 ctx 结束
+//   - fn 返回 nil 或错误时释放锁并返回该结果
+//   - 锁丢失时取消 fn 的 ctx，等待 fn 返回后重新竞争
+//   - ctx 结束时返回 ctx.Err()
+//
+// LeaderLock 不计入 Shutdown 等待的调用，关闭客户端前应先取消 ctx
+func (s MysqlClient) LeaderLock(ctx context.Context, name string, opts LeaseOptions, fn func(ctx context.Context) error) error {
+	if opts.Retry <= 0 {
+		opts.Retry = 10 * time.Second
+	}
+	for {
+		if s.inflight.isClosing() {
+			return &ClientClosedError{Op: "LeaderLock"}
+		}
+		_, err := s.holdLock(ctx, name, LockOptions{Heartbeat: opts.Heartbeat}, fn)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, ErrLockLost):
+			s.logger().log(ctx, LogClient, slog.LevelWarn, "mdb leader lock lost", slog.String("lock", name), errorAttr(err))
+		case errors.Is(err, ErrLockNotAcquired):
+		case err != nil && isConnectionError(err):
+			s.logger().log(ctx, LogClient, slog.LevelWarn, "mdb leader lock failed", slog.String("lock", name), errorAttr(err))
+		default:
+			return err
+		}
+		timer := time.NewTimer(opts.Retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// holdLock 在固定连接上获取锁、执行 fn 并释放，acquired 表示是否获取到了锁
+func (s MysqlClient) holdLock(ctx context.Context, name string, opts LockOptions, fn func(ctx context.Context) error) (acquired bool, err error) {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 5 * time.Second
+	}
+	conn, err := s.Db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	// ctx 取消时驱动会关闭连接，锁随会话释放
+	if err := getLock(ctx, conn, name, opts.Wait); err != nil {
+		_ = conn.Close()
+		return false, err
+	}
+	acquired = true // fn panic 时同样视为已获取
+	defer func() { releaseLock(conn, name, s.logger()) }()
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := make(chan struct{})
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		watchLock(fnCtx, conn, name, opts.Heartbeat, stop, cancel)
+	}()
+	defer func() {
+		close(stop)
+		<-watching
+		if cause := context.Cause(fnCtx); errors.Is(cause, ErrLockLost) {
+			err = errors.Join(cause, err)
+		}
+	}()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mdb: lock callback panic: %v", p)
+		}
+	}()
+	return true, fn(fnCtx)
+}
+
+func getLock(ctx context.Context, conn *sql.Conn, name string, wait time.Duration) error {
+	timeout := wait.Seconds()
+	if wait < 0 {
+		timeout = -1
+	}
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, timeout).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid {
+		return fmt.Errorf("mdb: GET_LOCK(%s) returned NULL", name)
+	}
+	if got.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrLockNotAcquired, name)
+	}
+	return nil
+}
+
+// watchLock 定期检查锁是否仍由当前会话持有，丢失时以 ErrLockLost 取消 fn 的 ctx
+func watchLock(ctx context.Context, conn *sql.Conn, name string, interval time.Duration, stop <-chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancelCheck := context.WithTimeout(context.Background(), interval)
+		var held sql.NullBool
+		err := conn.QueryRowContext(checkCtx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", name).Scan(&held)
+		cancelCheck()
+		if err != nil || !held.Bool {
+			if err == nil {
+				err = errors.New("held by another session")
+			}
+			cancel(fmt.Errorf("%w: %s: %v", ErrLockLost, name, err))
+			return
+		}
+	}
+}
+
+// releaseLock 释放锁并归还连接；释放失败时丢弃该连接，断开会话以确保锁被释放
+func releaseLock(conn *sql.Conn, name string, l *dbLogger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name); err != nil {
+		l.log(ctx, LogClient, slog.LevelError, "mdb release lock failed", slog.String("lock", name), errorAttr(err))
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogEvent 日志事件类别，可以按类别设置输出级别与采样
+type LogEvent string
+
+const (
+	LogConnect    LogEvent = "connect"     // 建立连接
+	LogQueryError LogEvent = "query_error" // 查询失败（包括构建 SQL 失败与重试）
+	LogSlowQuery  LogEvent = "slow_query"  // 慢查询
+	LogRollback   LogEvent = "rollback"    // 事务回滚、提交失败
+	LogClose      LogEvent = "close"       // 关闭连接池
+	LogClient     LogEvent = "client"      // 其他：锁、健康检查、审计、事务回调等
+)
+
+// LevelOff 设置为某类事件的级别时关闭该类日志
+const LevelOff = slog.Level(1 << 30)
+
+// 日志属性键
+const (
+	AttrStatement = "db.statement"
+	AttrOperation = "db.operation"
+	AttrTable     = "db.table"
+	AttrParams    = "db.params"
+	AttrDuration  = "duration_ms"
+	AttrEvent     = "db.event"
+)
+
+// LogSampling 采样：每个 Tick 周期内同一事件（类别与消息相同）先输出 First 条，之后每 Thereafter 条输出 1 条
+type LogSampling struct {
+	Tick       time.Duration // 默认 1s
+	First      int
+	Thereafter int // 0 表示超过 First 后全部丢弃
+}
+
+// LoggerConfig 日志配置
+type LoggerConfig struct {
+	// Handler 日志输出，nil 时使用 slog.Default() 的 Handler
+	Handler slog.Handler
+	// Levels 每类事件的最低输出级别，低于该级别的日志不输出；LevelOff 关闭该类事件
+	Levels map[LogEvent]slog.Level
+	// SlowQuery 执行时间达到该值的查询以 Warn 级别记录为慢查询，0 表示不记录
+	SlowQuery time.Duration
+	// Sampling 按事件类别采样，用于高频事件（例如慢查询、查询失败）
+	Sampling map[LogEvent]LogSampling
+}
+
+// WithLogger 设置客户端的日志输出、各类事件的级别与采样
+func WithLogger(cfg LoggerConfig) Option {
+	return func(c *MysqlClient) {
+		c.log = newDbLogger(cfg)
+	}
+}
+
+// dbLogger 客户端日志
+type dbLogger struct {
+	cfg LoggerConfig
+
+	mu      sync.Mutex
+	windows map[string]*sampleWindow
+	now     func() time.Time
+}
+
+type sampleWindow struct {
+	start time.Time
+	n     int
+}
+
+func newDbLogger(cfg LoggerConfig) *dbLogger {
+	return &dbLogger{cfg: cfg, windows: map[string]*sampleWindow{}, now: time.Now}
+}
+
+var defaultLogger = newDbLogger(LoggerConfig{})
+
+// logger 返回客户端使用的日志，没有设置时使用 slog.Default()
+func (s MysqlClient) logger() *dbLogger {
+	if s.log != nil {
+		return s.log
+	}
+	return defaultLogger
+}
+
+// Log 通过客户端的日志输出一条 ev 类别的日志，遵循 WithLogger 设置的级别与采样
+// 供 outbox、migrations 等基于客户端的包使用
+func (s MysqlClient) Log(ctx context.Context, ev LogEvent, level slog.Level, msg string, attrs ...slog.Attr) {
+	s.logger().log(ctx, ev, level, msg, attrs...)
+}
+
+func (l *dbLogger) handler() slog.Handler {
+	if l.cfg.Handler != nil {
+		return l.cfg.Handler
+	}
+	return slog.Default().Handler()
+}
+
+// log 输出一条日志，附加 db.event 属性
+func (l *dbLogger) log(ctx context.Context, ev LogEvent, level slog.Level, msg string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if min, ok := l.cfg.Levels[ev]; ok && level < min {
+		return
+	}
+	h := l.handler()
+	if !h.Enabled(ctx, level) || !l.sample(ev, msg) {
+		return
+	}
+	r := slog.NewRecord(l.now(), level, msg, 0)
+	r.AddAttrs(slog.String(AttrEvent, string(ev)))
+	r.AddAttrs(attrs...)
+	_ = h.Handle(ctx, r)
+}
+
+// sample 是否输出本条日志
+func (l *dbLogger) sample(ev LogEvent, msg string) bool {
+	cfg, ok := l.cfg.Sampling[ev]
+	if !ok {
+		return true
+	}
+	if cfg.Tick <= 0 {
+		cfg.Tick = time.Second
+	}
+	key := string(ev) + "\x00" + msg
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w := l.windows[key]
+	if w == nil || now.Sub(w.start) >= cfg.Tick {
+		w = &sampleWindow{start: now}
+		l.windows[key] = w
+	}
+	w.n++
+	if w.n <= cfg.First {
+		return true
+	}
+	return cfg.Thereafter > 0 && (w.n-cfg.First)%cfg.Thereafter == 0
+}
+
+// logQuery 记录失败的查询与慢查询；sql.ErrNoRows 等不视为失败的错误应在调用前置为 nil
+func (s MysqlClient) logQuery(ctx context.Context, op, statement string, tables []string, params map[string]any, args []any, start time.Time, err error) {
+	l := s.logger()
+	elapsed := time.Since(start)
+	slow := l.cfg.SlowQuery > 0 && elapsed >= l.cfg.SlowQuery
+	if err == nil && !slow {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String(AttrOperation, op),
+		slog.String(AttrStatement, s.redactor().Statement(statement)),
+	}
+	if len(tables) > 0 {
+		attrs = append(attrs, slog.String(AttrTable, strings.Join(tables, ",")))
+	}
+	if params != nil {
+		attrs = append(attrs, slog.Any(AttrParams, s.redactor().Params(params)))
+	} else if args != nil {
+		attrs = append(attrs, slog.Any(AttrParams, s.redactor().Args(statement, args)))
+	}
+	attrs = append(attrs, durationAttr(elapsed))
+	if err != nil {
+		l.log(ctx, LogQueryError, slog.LevelError, "mdb query failed", append(attrs, slog.String("error", err.Error()))...)
+		return
+	}
+	l.log(ctx, LogSlowQuery, slog.LevelWarn, "mdb slow query", attrs...)
+}
+
+func durationAttr(d time.Duration) slog.Attr {
+	return slog.Float64(AttrDuration, float64(d.Microseconds())/1000)
+}
+
+func errorAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
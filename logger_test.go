@@ -0,0 +1,111 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeLogs(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestDbLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := newDbLogger(LoggerConfig{
+		Handler: slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		Levels:  map[LogEvent]slog.Level{LogConnect: slog.LevelWarn, LogClose: LevelOff},
+	})
+	ctx := context.Background()
+	l.log(ctx, LogConnect, slog.LevelInfo, "connecting")
+	l.log(ctx, LogConnect, slog.LevelError, "connect failed")
+	l.log(ctx, LogClose, slog.LevelError, "close failed")
+	l.log(ctx, LogRollback, slog.LevelDebug, "rolled back")
+
+	logs := decodeLogs(t, &buf)
+	if len(logs) != 2 || logs[0]["msg"] != "connect failed" || logs[1]["msg"] != "rolled back" {
+		t.Fatalf("logs = %v", logs)
+	}
+	if logs[0][AttrEvent] != string(LogConnect) {
+		t.Fatalf("missing event attr: %v", logs[0])
+	}
+}
+
+func TestDbLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newDbLogger(LoggerConfig{
+		Handler:  slog.NewJSONHandler(&buf, nil),
+		Sampling: map[LogEvent]LogSampling{LogSlowQuery: {Tick: time.Second, First: 2, Thereafter: 3}},
+	})
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+	for range 8 {
+		l.log(ctx, LogSlowQuery, slog.LevelWarn, "slow")
+	}
+	// 第 1、2 条以及之后每 3 条中的 1 条（第 5、8 条）
+	if n := len(decodeLogs(t, &buf)); n != 4 {
+		t.Fatalf("sampled %d logs", n)
+	}
+	buf.Reset()
+	now = now.Add(time.Second)
+	l.log(ctx, LogSlowQuery, slog.LevelWarn, "slow")
+	// 其他类别不采样
+	for range 5 {
+		l.log(ctx, LogQueryError, slog.LevelError, "failed")
+	}
+	if n := len(decodeLogs(t, &buf)); n != 6 {
+		t.Fatalf("logs after new window = %d", n)
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	var buf bytes.Buffer
+	s := MysqlClient{log: newDbLogger(LoggerConfig{Handler: slog.NewJSONHandler(&buf, nil), SlowQuery: 50 * time.Millisecond})}
+	ctx := context.Background()
+
+	s.logQuery(ctx, "ExecByBuilder", "UPDATE `user` SET `password` = :password WHERE `id` = :id", []string{"user"},
+		map[string]any{"password": "s3cret", "id": 1}, nil, time.Now(), errors.New("boom"))
+	s.logQuery(ctx, "QueryRaw", "SELECT * FROM user WHERE phone = ?", nil, nil, []any{"13800000000"}, time.Now().Add(-time.Second), nil)
+	s.logQuery(ctx, "QueryByBuilder", "SELECT * FROM `user` WHERE `user`.`password` = 'hunter2'", []string{"user"}, nil, nil, time.Now(), errors.New("boom"))
+	s.logQuery(ctx, "QueryRaw", "SELECT 1", nil, nil, nil, time.Now(), nil)
+
+	logs := decodeLogs(t, &buf)
+	if len(logs) != 3 {
+		t.Fatalf("logs = %v", logs)
+	}
+	failed, slow := logs[0], logs[1]
+	if stmt := logs[2][AttrStatement].(string); strings.Contains(stmt, "hunter2") {
+		t.Fatalf("inline literal not redacted: %s", stmt)
+	}
+	if failed["level"] != "ERROR" || failed[AttrEvent] != string(LogQueryError) || failed[AttrOperation] != "ExecByBuilder" ||
+		failed[AttrTable] != "user" || failed["error"] != "boom" || !strings.HasPrefix(failed[AttrStatement].(string), "UPDATE") {
+		t.Fatalf("query error log = %v", failed)
+	}
+	if params := failed[AttrParams].(map[string]any); params["password"] != "***" {
+		t.Fatalf("params not redacted: %v", params)
+	}
+	if slow["level"] != "WARN" || slow[AttrEvent] != string(LogSlowQuery) || slow[AttrDuration].(float64) < 1000 {
+		t.Fatalf("slow query log = %v", slow)
+	}
+	if args := slow[AttrParams].([]any); args[0] != "***" {
+		t.Fatalf("args not redacted: %v", args)
+	}
+}
@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrDuplicateVersion 存在重复的迁移版本号
+	ErrDuplicateVersion = errors.New("migrations: duplicate version")
+	// ErrChecksumMismatch 已执行的迁移文件被修改
+	ErrChecksumMismatch = errors.New("migrations: checksum mismatch")
+	// ErrNoDown 迁移没有提供 down
+	ErrNoDown = errors.New("migrations: down migration not defined")
+	// ErrUnknownVersion 目标版本不存在
+	ErrUnknownVersion = errors.New("migrations: unknown version")
+	// ErrLockTimeout 等待迁移锁超时（其他实例正在迁移）
+	ErrLockTimeout = errors.New("migrations: lock timeout")
+	// ErrInvalidCount Down 的回滚个数为负数
+	ErrInvalidCount = errors.New("migrations: invalid count")
+)
+
+// Migration 一个版本化迁移，SQL 与 Go 函数二选一（同时提供时先执行 SQL 再执行函数）
+type Migration struct {
+	Version int64
+	Name    string
+
+	UpSQL   string
+	DownSQL string
+
+	Up   func(ctx context.Context, tx *sqlx.Tx) error
+	Down func(ctx context.Context, tx *sqlx.Tx) error
+}
+
+// Checksum 迁移内容的校验和，用于发现已执行的迁移被修改
+// Go 函数迁移无法计算内容，只对名称计算
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.UpSQL))
+	h.Write([]byte{0})
+	h.Write([]byte(m.DownSQL))
+	if m.UpSQL == "" && m.DownSQL == "" {
+		h.Write([]byte("go:" + m.Name))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m Migration) hasDown() bool {
+	return m.DownSQL != "" || m.Down != nil
+}
+
+// fileRe 迁移文件名：<版本>_<名称>.up.sql / <版本>_<名称>.down.sql
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// LoadFS 从 fs.FS（一般为 embed.FS）的 dir 目录中加载 SQL 迁移文件
+// 文件名格式：0001_create_user.up.sql、0001_create_user.down.sql
+func LoadFS(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := fileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrations: bad version in %s: %w", e.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("%w: %d (%s, %s)", ErrDuplicateVersion, version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.UpSQL = string(content)
+		} else {
+			mig.DownSQL = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// sortMigrations 按版本排序并检查重复
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, sorted[i].Version)
+		}
+	}
+	return sorted, nil
+}
+
+// SplitStatements 按分号拆分 SQL 语句，忽略引号与注释中的分号，去掉空语句
+func SplitStatements(script string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+		quote byte
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" && !isCommentOnly(s) {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		if quote != 0 {
+			cur.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(script) {
+				i++
+				cur.WriteByte(script[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			cur.WriteByte(c)
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "-- ")):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			cur.WriteString(script[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 2
+			} else {
+				end += 2
+			}
+			cur.WriteString(script[i : i+2+end])
+			i += 2 + end - 1
+		case c == ';':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// isCommentOnly 判断语句是否只包含注释
+func isCommentOnly(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "/*") && strings.HasSuffix(line, "*/") {
+			continue
+		}
+		return false
+	}
+	return true
+}
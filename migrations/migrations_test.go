@@ -0,0 +1,165 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_add_email.up.sql":     {Data: []byte("ALTER TABLE user ADD email VARCHAR(64);")},
+		"sql/0002_add_email.down.sql":   {Data: []byte("ALTER TABLE user DROP email;")},
+		"sql/0001_create_user.up.sql":   {Data: []byte("CREATE TABLE user (id INT);")},
+		"sql/0001_create_user.down.sql": {Data: []byte("DROP TABLE user;")},
+		"sql/README.md":                 {Data: []byte("ignored")},
+	}
+	migs, err := LoadFS(fsys, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 2 || migs[0].Version != 1 || migs[1].Version != 2 {
+		t.Fatalf("unexpected migrations: %+v", migs)
+	}
+	if migs[0].Name != "create_user" || migs[0].DownSQL != "DROP TABLE user;" {
+		t.Errorf("unexpected migration: %+v", migs[0])
+	}
+
+	fsys["sql/0001_other.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	if _, err := LoadFS(fsys, "sql"); !errors.Is(err, ErrDuplicateVersion) {
+		t.Errorf("expected ErrDuplicateVersion, got %v", err)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `
+-- create table; with comment
+CREATE TABLE a (name VARCHAR(10) DEFAULT 'x;y');
+/* block; comment */
+INSERT INTO a VALUES ("it\"s;"), ('o''k;');
+# trailing comment;
+UPDATE ` + "`a;b`" + ` SET name = 1
+`
+	got := SplitStatements(script)
+	want := []string{
+		"-- create table; with comment\nCREATE TABLE a (name VARCHAR(10) DEFAULT 'x;y')",
+		"/* block; comment */\nINSERT INTO a VALUES (\"it\\\"s;\"), ('o''k;')",
+		"# trailing comment;\nUPDATE `a;b` SET name = 1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitStatements:\n got %q\nwant %q", got, want)
+	}
+	if got := SplitStatements("-- only comment\n;\n  ;"); len(got) != 0 {
+		t.Errorf("expected no statements, got %q", got)
+	}
+}
+
+func testMigrations() []Migration {
+	noop := func(context.Context, *sqlx.Tx) error { return nil }
+	return []Migration{
+		{Version: 1, Name: "one", UpSQL: "CREATE TABLE one (id INT)", DownSQL: "DROP TABLE one"},
+		{Version: 2, Name: "two", Up: noop, Down: noop},
+		{Version: 3, Name: "three", UpSQL: "CREATE TABLE three (id INT)"},
+	}
+}
+
+func appliedOf(migs []Migration, versions ...int64) map[int64]Record {
+	applied := map[int64]Record{}
+	for _, mig := range migs {
+		for _, v := range versions {
+			if mig.Version == v {
+				applied[v] = Record{Version: v, Name: mig.Name, Checksum: mig.Checksum()}
+			}
+		}
+	}
+	return applied
+}
+
+func versions(steps []step) []int64 {
+	out := []int64{}
+	for _, st := range steps {
+		v := st.Migration.Version
+		if !st.Up {
+			v = -v
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	migs := testMigrations()
+
+	if got := versions(planUp(migs, appliedOf(migs, 2), -1)); !reflect.DeepEqual(got, []int64{1, 3}) {
+		t.Errorf("planUp = %v", got)
+	}
+
+	steps, err := planDown(migs, appliedOf(migs, 1, 2), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(steps); !reflect.DeepEqual(got, []int64{-2, -1}) {
+		t.Errorf("planDown = %v", got)
+	}
+	if _, err := planDown(migs, appliedOf(migs, 1, 2, 3), 1); !errors.Is(err, ErrNoDown) {
+		t.Errorf("expected ErrNoDown, got %v", err)
+	}
+	if steps, err := planDown(migs, appliedOf(migs, 1, 2), 0); err != nil || len(steps) != 0 {
+		t.Errorf("planDown(0) = %v, %v", versions(steps), err)
+	}
+	if _, err := planDown(migs, appliedOf(migs, 1, 2), -1); !errors.Is(err, ErrInvalidCount) {
+		t.Errorf("expected ErrInvalidCount, got %v", err)
+	}
+
+	steps, err = planGoto(migs, appliedOf(migs, 2), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(steps); !reflect.DeepEqual(got, []int64{-2, 1}) {
+		t.Errorf("planGoto = %v", got)
+	}
+	steps, err = planGoto(migs, appliedOf(migs, 1, 2), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(steps); !reflect.DeepEqual(got, []int64{-2, -1}) {
+		t.Errorf("planGoto(0) = %v", got)
+	}
+	if _, err := planGoto(migs, nil, 9); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestValidateAndStatus(t *testing.T) {
+	migs := testMigrations()
+	applied := appliedOf(migs, 1, 2)
+	if err := validate(migs, applied); err != nil {
+		t.Fatal(err)
+	}
+	applied[1] = Record{Version: 1, Name: "one", Checksum: "edited"}
+	applied[7] = Record{Version: 7, Name: "gone"}
+	if err := validate(migs, applied); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+
+	st := status(migs, applied)
+	if len(st) != 4 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if !st[0].Applied || !st[0].Modified || !st[1].Applied || st[1].Modified || st[2].Applied || !st[3].Missing {
+		t.Errorf("unexpected status: %+v", st)
+	}
+}
+
+func TestChecksumChangesWithContent(t *testing.T) {
+	a := Migration{Version: 1, Name: "a", UpSQL: "SELECT 1"}
+	b := a
+	b.UpSQL = "SELECT 2"
+	if a.Checksum() == b.Checksum() || len(a.Checksum()) != 64 {
+		t.Errorf("unexpected checksums %s %s", a.Checksum(), b.Checksum())
+	}
+}
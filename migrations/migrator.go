@@ -0,0 +1,408 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+)
+
+// Option 配置 Migrator
+type Option func(*Migrator)
+
+// WithTable 指定迁移历史表，默认 schema_migrations
+func WithTable(table string) Option {
+	return func(m *Migrator) { m.table = table }
+}
+
+// WithLock 指定 GET_LOCK 的锁名与等待时间，默认 锁名为 "<库名>.<历史表>"，等待 60s
+func WithLock(name string, timeout time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockName = name
+		m.lockTimeout = timeout
+	}
+}
+
+// WithDryRun 只将要执行的语句输出到 out（默认 os.Stdout），不执行也不修改历史表
+func WithDryRun(out io.Writer) Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+		if out != nil {
+			m.out = out
+		}
+	}
+}
+
+// Migrator 针对一个 MysqlClient 执行版本化迁移
+type Migrator struct {
+	client     *db.MysqlClient
+	migrations []Migration
+
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+	dryRun      bool
+	out         io.Writer
+}
+
+// New 创建 Migrator，migrations 可来自 LoadFS 与 Go 函数迁移的组合
+func New(client *db.MysqlClient, migrations []Migration, opts ...Option) (*Migrator, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{
+		client:      client,
+		migrations:  sorted,
+		table:       "schema_migrations",
+		lockTimeout: 60 * time.Second,
+		out:         os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.lockName == "" {
+		m.lockName = client.MysqlConfig.Database + "." + m.table
+	}
+	return m, nil
+}
+
+// Record 历史表中的一条记录
+type Record struct {
+	Version   int64       `db:"version"`
+	Name      string      `db:"name"`
+	Checksum  string      `db:"checksum"`
+	AppliedAt db.DateTime `db:"applied_at"`
+}
+
+// Status 单个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt db.DateTime
+	// Modified 已执行的迁移内容与历史表中的校验和不一致
+	Modified bool
+	// Missing 历史表中存在，但当前迁移集合中已没有该版本
+	Missing bool
+}
+
+// Up 执行所有未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(applied map[int64]Record) ([]step, error) {
+		return planUp(m.migrations, applied, -1), nil
+	})
+}
+
+// Down 回滚最近执行的 n 个迁移，n 超过已执行的个数时回滚全部
+// n 为 0 时不做任何回滚，n 为负数返回 ErrInvalidCount；回滚全部请使用 Goto(ctx, 0)
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	return m.run(ctx, func(applied map[int64]Record) ([]step, error) {
+		return planDown(m.migrations, applied, n)
+	})
+}
+
+// Goto 迁移到指定版本：执行 <= version 的未执行迁移，回滚 > version 的已执行迁移
+// version 为 0 表示回滚全部
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	return m.run(ctx, func(applied map[int64]Record) ([]step, error) {
+		return planGoto(m.migrations, applied, version)
+	})
+}
+
+// Status 返回所有迁移的状态（按版本排序），包括历史表中存在但已缺失的版本
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx, m.client.Db)
+	if err != nil {
+		return nil, err
+	}
+	return status(m.migrations, applied), nil
+}
+
+// Validate 校验已执行迁移的校验和，迁移被修改时返回 ErrChecksumMismatch
+func (m *Migrator) Validate(ctx context.Context) error {
+	applied, err := m.applied(ctx, m.client.Db)
+	if err != nil {
+		return err
+	}
+	return validate(m.migrations, applied)
+}
+
+type queryer interface {
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// applied 读取历史表，表不存在时视为没有执行过任何迁移
+// applied_at 以字符串读取，不依赖 DSN 的 parseTime 参数
+func (m *Migrator) applied(ctx context.Context, q queryer) (map[int64]Record, error) {
+	var records []Record
+	err := q.SelectContext(ctx, &records, "SELECT `version`, `name`, `checksum`, DATE_FORMAT(`applied_at`, '%Y-%m-%d %H:%i:%s') AS `applied_at` FROM `"+m.table+"` ORDER BY `version`")
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1146 {
+		return map[int64]Record{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+func (m *Migrator) createTableSql() string {
+	return "CREATE TABLE IF NOT EXISTS `" + m.table + "` (" +
+		"`version` BIGINT NOT NULL PRIMARY KEY, " +
+		"`name` VARCHAR(255) NOT NULL, " +
+		"`checksum` CHAR(64) NOT NULL, " +
+		"`applied_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP" +
+		")"
+}
+
+// run 在迁移锁内读取历史、校验并执行计划
+func (m *Migrator) run(ctx context.Context, plan func(map[int64]Record) ([]step, error)) error {
+	conn, err := m.client.Db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if !m.dryRun {
+		if err := m.lock(ctx, conn); err != nil {
+			return err
+		}
+		defer m.unlock(conn)
+		if _, err := conn.ExecContext(ctx, m.createTableSql()); err != nil {
+			return err
+		}
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if err := validate(m.migrations, applied); err != nil {
+		return err
+	}
+	steps, err := plan(applied)
+	if err != nil {
+		return err
+	}
+	for _, st := range steps {
+		if m.dryRun {
+			m.print(st)
+			continue
+		}
+		start := time.Now()
+		if err := m.apply(ctx, conn, st); err != nil {
+			return fmt.Errorf("migrations: %s %d_%s: %w", st.direction(), st.Migration.Version, st.Migration.Name, err)
+		}
+		m.client.Log(ctx, db.LogClient, slog.LevelInfo, "migration applied",
+			slog.Int64("version", st.Migration.Version), slog.String("name", st.Migration.Name), slog.String("direction", st.direction()),
+			slog.Float64(db.AttrDuration, float64(time.Since(start).Microseconds())/1000))
+	}
+	return nil
+}
+
+// lock 获取 GET_LOCK 咨询锁，锁与连接绑定，所以整个迁移过程使用同一连接
+func (m *Migrator) lock(ctx context.Context, conn *sqlx.Conn) error {
+	var got sql.NullInt64
+	if err := conn.QueryRowxContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName, int(m.lockTimeout.Seconds())).Scan(&got); err != nil {
+		return err
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrLockTimeout, m.lockName)
+	}
+	return nil
+}
+
+func (m *Migrator) unlock(conn *sqlx.Conn) {
+	if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName); err != nil {
+		m.client.Log(context.Background(), db.LogClient, slog.LevelError, "migration release lock failed",
+			slog.String("lock", m.lockName), slog.String("error", err.Error()))
+	}
+}
+
+// apply 在事务中执行一个迁移步骤并更新历史表
+// 注意：MySQL 的 DDL 会隐式提交，包含 DDL 的迁移失败时可能处于部分执行状态
+func (m *Migrator) apply(ctx context.Context, conn *sqlx.Conn, st step) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range st.statements() {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if fn := st.fn(); fn != nil {
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+	}
+	if st.Up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO `"+m.table+"` (`version`, `name`, `checksum`) VALUES (?, ?, ?)",
+			st.Migration.Version, st.Migration.Name, st.Migration.Checksum())
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM `"+m.table+"` WHERE `version` = ?", st.Migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// print dry-run 模式下输出步骤
+func (m *Migrator) print(st step) {
+	fmt.Fprintf(m.out, "-- %s %d_%s\n", st.direction(), st.Migration.Version, st.Migration.Name)
+	for _, stmt := range st.statements() {
+		fmt.Fprintf(m.out, "%s;\n", stmt)
+	}
+	if st.fn() != nil {
+		fmt.Fprintf(m.out, "-- (go function)\n")
+	}
+}
+
+// step 计划中的一个步骤
+type step struct {
+	Migration Migration
+	Up        bool
+}
+
+func (st step) direction() string {
+	if st.Up {
+		return "up"
+	}
+	return "down"
+}
+
+func (st step) statements() []string {
+	if st.Up {
+		return SplitStatements(st.Migration.UpSQL)
+	}
+	return SplitStatements(st.Migration.DownSQL)
+}
+
+func (st step) fn() func(context.Context, *sqlx.Tx) error {
+	if st.Up {
+		return st.Migration.Up
+	}
+	return st.Migration.Down
+}
+
+// planUp 未执行且版本 <= target 的迁移，按版本升序；target < 0 表示不限制
+func planUp(migrations []Migration, applied map[int64]Record, target int64) []step {
+	var steps []step
+	for _, mig := range migrations {
+		if target >= 0 && mig.Version > target {
+			break
+		}
+		if _, ok := applied[mig.Version]; !ok {
+			steps = append(steps, step{Migration: mig, Up: true})
+		}
+	}
+	return steps
+}
+
+// planDown 最近执行的 n 个迁移，按版本降序
+func planDown(migrations []Migration, applied map[int64]Record, n int) ([]step, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCount, n)
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	if n < len(versions) {
+		versions = versions[:n]
+	}
+	steps := make([]step, 0, len(versions))
+	for _, v := range versions {
+		mig, ok := byVersion[v]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d is applied but missing", ErrUnknownVersion, v)
+		}
+		if !mig.hasDown() {
+			return nil, fmt.Errorf("%w: %d_%s", ErrNoDown, mig.Version, mig.Name)
+		}
+		steps = append(steps, step{Migration: mig})
+	}
+	return steps, nil
+}
+
+// planGoto 先回滚 > version 的已执行迁移，再执行 <= version 的未执行迁移
+func planGoto(migrations []Migration, applied map[int64]Record, version int64) ([]step, error) {
+	if version != 0 {
+		found := false
+		for _, mig := range migrations {
+			if mig.Version == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	n := 0
+	for v := range applied {
+		if v > version {
+			n++
+		}
+	}
+	steps, err := planDown(migrations, applied, n)
+	if err != nil {
+		return nil, err
+	}
+	return append(steps, planUp(migrations, applied, version)...), nil
+}
+
+// validate 校验已执行迁移的校验和
+func validate(migrations []Migration, applied map[int64]Record) error {
+	for _, mig := range migrations {
+		r, ok := applied[mig.Version]
+		if ok && r.Checksum != mig.Checksum() {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func status(migrations []Migration, applied map[int64]Record) []Status {
+	out := make([]Status, 0, len(migrations))
+	known := make(map[int64]bool, len(migrations))
+	for _, mig := range migrations {
+		known[mig.Version] = true
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = r.AppliedAt
+			st.Modified = r.Checksum != mig.Checksum()
+		}
+		out = append(out, st)
+	}
+	for v, r := range applied {
+		if !known[v] {
+			out = append(out, Status{Version: v, Name: r.Name, Applied: true, AppliedAt: r.AppliedAt, Missing: true})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
@@ -10,11 +10,33 @@ import (
 	"github.com/preceeder/db/builder"
 	"log/slog"
 	"strings"
+	"time"
 )
 
 type MysqlClient struct {
 	MysqlConfig MysqlConfig
 	Db          *sqlx.DB
+
+	cache      *QueryCache              // 查询缓存（可选）
+	timestamps *builder.TimestampConfig // 客户端级别的时间戳约定（可选）
+	schema     *schemaCache             // 表结构缓存
+	inflight   *inflight                // 正在执行的调用，Shutdown 使用
+	bulkhead   *bulkhead                // 并发限制（可选）
+	breaker    *breaker                 // 熔断器（可选）
+	retry      *RetryPolicy             // 只读查询重试策略（可选）
+	audit      *AuditConfig             // 审计（可选）
+	redaction  *RedactionPolicy         // 日志脱敏策略，nil 时使用 DefaultRedactionPolicy
+	log        *dbLogger                // 日志，nil 时使用 slog.Default()
+}
+
+// Option 用于在 NewMysqlClient 时配置可选功能
+type Option func(*MysqlClient)
+
+// WithQueryCache 为 QueryByBuilder/FetchByBuilder 开启读穿透缓存
+func WithQueryCache(cache *QueryCache) Option {
+	return func(c *MysqlClient) {
+		c.cache = cache
+	}
 }
 
 type MysqlConfig struct {
@@ -28,24 +50,49 @@ type MysqlConfig struct {
 	Params      string `json:"params" json:"params"` // 其他配置数据, 放在链接后面的参数重
 }
 
-func NewMysqlClient(config MysqlConfig) *MysqlClient {
-	db := initMySQL(config)
+// WithTimestamps 为该客户端执行的所有 builder 设置 created_at/updated_at 自动填充约定
+// 优先级低于 SqlBuilder.Timestamps，高于 builder.SetTimestamps 的全局配置
+func WithTimestamps(cfg builder.TimestampConfig) Option {
+	return func(c *MysqlClient) {
+		c.timestamps = &cfg
+	}
+}
+
+func NewMysqlClient(config MysqlConfig, opts ...Option) *MysqlClient {
+	client := newMysqlClient(config, nil, opts...)
+	client.Db = initMySQL(config, client.logger())
+	return client
+}
 
-	return &MysqlClient{
+// newMysqlClient 使用已建立的连接池创建客户端
+func newMysqlClient(config MysqlConfig, db *sqlx.DB, opts ...Option) *MysqlClient {
+	client := &MysqlClient{
 		Db:          db,
 		MysqlConfig: config,
+		schema:      &schemaCache{},
+		inflight:    &inflight{},
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
 
-// 初始化数据库
-func initMySQL(config MysqlConfig) *sqlx.DB {
-
+// dsn 返回 go-sql-driver/mysql 格式的连接串
+func (config MysqlConfig) dsn() string {
 	//dsn := "root:password@tcp(127.0.0.1:3306)/database"
 	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v", config.User, config.Password, config.Host, config.Port, config.Database)
 	if config.Params != "" {
 		dsn = strings.Join([]string{dsn, config.Params}, "?")
 	}
-	slog.Info("链接数据库", "db", dsn)
+	return dsn
+}
+
+// 初始化数据库
+func initMySQL(config MysqlConfig, l *dbLogger) *sqlx.DB {
+
+	dsn := config.dsn()
+	l.log(context.Background(), LogConnect, slog.LevelInfo, "mdb connecting", slog.String("dsn", config.redactedDSN()))
 	// 安全链接  内部已经ping 了
 	db := sqlx.MustConnect("mysql", dsn)
 	db.SetMaxOpenConns(config.MaxOpenCons)
@@ -53,13 +100,22 @@ func initMySQL(config MysqlConfig) *sqlx.DB {
 	return db
 }
 
+// txCount 传入的有效事务数
+func txCount(tx []*sqlx.Tx) int {
+	if len(tx) > 0 && tx[0] != nil {
+		return 1
+	}
+	return 0
+}
+
+// MysqlPoolClose 立即关闭连接池，需要等待正在执行的调用时使用 Shutdown
 func (s MysqlClient) MysqlPoolClose() {
 	err := s.Db.Close()
 	if err != nil {
-		slog.Error("关闭数据库错误", "error", err.Error())
+		s.logger().log(context.Background(), LogClose, slog.LevelError, "mdb close failed", errorAttr(err))
 		return
 	}
-	slog.Info("close mdb", "config", s.MysqlConfig)
+	s.logger().log(context.Background(), LogClose, slog.LevelInfo, "mdb closed", slog.Any("config", s.MysqlConfig))
 }
 
 // 参数解析（安全版本）
@@ -67,12 +123,13 @@ func (s MysqlClient) MysqlPoolClose() {
 func (s MysqlClient) sqlParseSafe(ctx context.Context, osql string, params map[string]any) (string, []any, error) {
 	q, args, err := sqlx.Named(osql, params)
 	if err != nil {
-		slog.ErrorContext(ctx, "sqlx.Named", "error", err.Error())
+		s.logger().log(ctx, LogQueryError, slog.LevelError, "mdb bind named params failed", slog.String(AttrStatement, s.redactor().Statement(osql)), errorAttr(err))
 		return "", nil, err
 	}
 	q, args, err = sqlx.In(q, args...)
 	if err != nil {
-		slog.ErrorContext(ctx, "sqlx.In", "error", err.Error(), "params", params, "sql", q)
+		s.logger().log(ctx, LogQueryError, slog.LevelError, "mdb expand IN params failed", slog.String(AttrStatement, s.redactor().Statement(q)),
+			slog.Any(AttrParams, s.redactor().Params(params)), errorAttr(err))
 		return "", nil, err
 	}
 	q = s.Db.Rebind(q)
@@ -81,81 +138,206 @@ func (s MysqlClient) sqlParseSafe(ctx context.Context, osql string, params map[s
 
 // -------------------- Builder 集成 --------------------
 
+// buildSql 生成 builder 的 SQL，并带上客户端级别的构建配置
+func (s MysqlClient) buildSql(ctx context.Context, b *builder.SqlBuilder) (string, map[string]any, error) {
+	sqlStr, params, err := b.SqlContext(s.buildContext(ctx))
+	if err != nil {
+		s.logger().log(ctx, LogQueryError, slog.LevelError, "mdb build sql failed", slog.String(AttrTable, strings.Join(b.Tables(), ",")), errorAttr(err))
+		return "", nil, err
+	}
+	return sqlStr, params, nil
+}
+
+// buildContext 返回带有客户端级别构建配置的 ctx
+func (s MysqlClient) buildContext(ctx context.Context) context.Context {
+	if s.timestamps != nil {
+		ctx = builder.WithTimestamps(ctx, *s.timestamps)
+	}
+	return ctx
+}
+
 // QueryByBuilder 执行由 builder 生成的单行查询
-func (s MysqlClient) QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
-	sqlStr, params := b.Sql()
-	q, args, err := s.sqlParseSafe(ctx, sqlStr, params)
+func (s MysqlClient) QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) (err error) {
+	sqlStr, params, err := s.buildSql(ctx, b)
 	if err != nil {
 		return err
 	}
-	if len(tx) > 0 && tx[0] != nil {
-		err = tx[0].Get(dest, q, args...)
-	} else {
-		err = sqlx.Get(s.Db, dest, q, args...)
+	q, args, err := s.sqlParseSafe(ctx, sqlStr, params)
+	if err != nil {
+		return err
 	}
-	switch {
-	case errors.Is(err, sql.ErrNoRows):
-		return nil
-	case err != nil:
-		slog.ErrorContext(ctx, "mdb QueryByBuilder failed", "error", err, "sql", sqlStr, "data", params)
+	ctx, done, err := s.begin(ctx, "QueryByBuilder", q, txCount(tx))
+	if err != nil {
 		return err
 	}
-	return nil
+	defer func() { done(err) }()
+	query := func(dest any) (bool, error) {
+		var err error
+		start := time.Now()
+		if len(tx) > 0 && tx[0] != nil {
+			err = tx[0].GetContext(ctx, dest, q, args...)
+		} else {
+			err = s.retryRead(ctx, "QueryByBuilder", dest, func() error {
+				return sqlx.GetContext(ctx, s.Db, dest, q, args...)
+			})
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			s.logQuery(ctx, "QueryByBuilder", sqlStr, b.Tables(), params, nil, start, nil)
+			return false, nil
+		}
+		s.logQuery(ctx, "QueryByBuilder", sqlStr, b.Tables(), params, nil, start, err)
+		return err == nil, err
+	}
+	if c := s.queryCache(ctx, tx); c != nil {
+		return c.load(ctx, b.Tables(), sqlStr, params, dest, query)
+	}
+	_, err = query(dest)
+	return err
 }
 
 // FetchByBuilder 执行由 builder 生成的多行查询
-func (s MysqlClient) FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) error {
-	sqlStr, params := b.Sql()
+func (s MysqlClient) FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any, tx ...*sqlx.Tx) (err error) {
+	sqlStr, params, err := s.buildSql(ctx, b)
+	if err != nil {
+		return err
+	}
 	q, args, err := s.sqlParseSafe(ctx, sqlStr, params)
 	if err != nil {
 		return err
 	}
-	if len(tx) > 0 && tx[0] != nil {
-		if err = tx[0].Select(dest, q, args...); err != nil {
-			slog.ErrorContext(ctx, "mdb FetchByBuilder failed", "error", err, "sql", sqlStr, "data", params)
-			return err
-		}
-	} else if err = sqlx.Select(s.Db, dest, q, args...); err != nil {
-		slog.ErrorContext(ctx, "mdb FetchByBuilder failed", "error", err, "sql", sqlStr, "data", params)
+	ctx, done, err := s.begin(ctx, "FetchByBuilder", q, txCount(tx))
+	if err != nil {
 		return err
 	}
-	return nil
+	defer func() { done(err) }()
+	query := func(dest any) (bool, error) {
+		var err error
+		start := time.Now()
+		if len(tx) > 0 && tx[0] != nil {
+			err = tx[0].SelectContext(ctx, dest, q, args...)
+		} else {
+			err = s.retryRead(ctx, "FetchByBuilder", dest, func() error {
+				return sqlx.SelectContext(ctx, s.Db, dest, q, args...)
+			})
+		}
+		s.logQuery(ctx, "FetchByBuilder", sqlStr, b.Tables(), params, nil, start, err)
+		return err == nil, err
+	}
+	if c := s.queryCache(ctx, tx); c != nil {
+		return c.load(ctx, b.Tables(), sqlStr, params, dest, query)
+	}
+	_, err = query(dest)
+	return err
+}
+
+// queryCache 返回本次查询可用的缓存；事务内或 ctx 指定跳过时返回 nil
+func (s MysqlClient) queryCache(ctx context.Context, tx []*sqlx.Tx) *QueryCache {
+	if s.cache == nil || (len(tx) > 0 && tx[0] != nil) {
+		return nil
+	}
+	if skip, _ := ctx.Value(skipCacheKey{}).(bool); skip {
+		return nil
+	}
+	return s.cache
 }
 
 // ExecByBuilder 执行由 builder 生成的 DML 语句（Insert/Update/Delete）
 // 参数与 FetchByBuilder 保持一致，接受 *builder.SqlBuilder
+// 开启审计（WithAudit）时在事务中执行并生成审计记录，没有传入事务时会自动开启事务
 func (s MysqlClient) ExecByBuilder(ctx context.Context, b *builder.SqlBuilder, tx ...*sqlx.Tx) (sql.Result, error) {
-	sqlStr, params := b.Sql()
+	m, audited, err := s.auditMutation(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	if audited {
+		return s.execAudited(ctx, b, m, tx...)
+	}
+	return s.execByBuilder(ctx, b, tx...)
+}
+
+func (s MysqlClient) execByBuilder(ctx context.Context, b *builder.SqlBuilder, tx ...*sqlx.Tx) (_ sql.Result, err error) {
+	sqlStr, params, err := s.buildSql(ctx, b)
+	if err != nil {
+		return nil, err
+	}
 	q, args, err := s.sqlParseSafe(ctx, sqlStr, params)
 	if err != nil {
 		return nil, err
 	}
+	ctx, done, err := s.begin(ctx, "ExecByBuilder", q, txCount(tx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(err) }()
 	var rs sql.Result
+	start := time.Now()
 	if len(tx) > 0 && tx[0] != nil {
-		rs, err = tx[0].Exec(q, args...)
+		rs, err = tx[0].ExecContext(ctx, q, args...)
 	} else {
-		rs, err = s.Db.Exec(q, args...)
+		rs, err = s.Db.ExecContext(ctx, q, args...)
 	}
+	s.logQuery(ctx, "ExecByBuilder", sqlStr, b.Tables(), params, nil, start, err)
 	if err != nil {
-		slog.ErrorContext(ctx, "mdb ExecByBuilder failed", "error", err, "sql", q, "data", params)
 		return nil, err
 	}
+	s.invalidateCache(ctx, b.Tables(), tx)
+	if b.HasVersion() {
+		if n, err := rs.RowsAffected(); err == nil && n == 0 {
+			return rs, &StaleObjectError{Table: strings.Join(b.Tables(), ",")}
+		}
+	}
+	return rs, nil
+}
+
+// invalidateCache 写入后使相关表的查询缓存失效
+// 在事务中时注册为 OnCommit 回调，提交后再失效，避免提交前的读取把旧数据重新写入缓存；
+// ctx 中没有事务回调（调用方自行开启的事务）时立即失效，调用方需要在提交后自行调用 QueryCache.Invalidate
+func (s MysqlClient) invalidateCache(ctx context.Context, tables []string, tx []*sqlx.Tx) {
+	if s.cache == nil || len(tables) == 0 {
+		return
+	}
+	if len(tx) > 0 && tx[0] != nil {
+		err := OnCommit(ctx, func(ctx context.Context) { s.cache.Invalidate(ctx, tables...) })
+		if err == nil {
+			return
+		}
+	}
+	s.cache.Invalidate(ctx, tables...)
+}
+
+// ExecExpect 执行 DML 并断言影响行数必须等于 n，否则返回 *AffectedRowsError
+// 注意：MySQL 默认返回的是实际发生变化的行数，需要匹配行数时请在 DSN 中加上 clientFoundRows=true
+func (s MysqlClient) ExecExpect(ctx context.Context, b *builder.SqlBuilder, n int64, tx ...*sqlx.Tx) (sql.Result, error) {
+	rs, err := s.ExecByBuilder(ctx, b, tx...)
+	if err != nil {
+		return rs, err
+	}
+	affected, err := rs.RowsAffected()
+	if err != nil {
+		return rs, err
+	}
+	if affected != n {
+		return rs, &AffectedRowsError{Expected: n, Actual: affected}
+	}
 	return rs, nil
 }
 
 // ExecRaw 直接执行原生 SQL，支持可选事务
-func (s MysqlClient) ExecRaw(ctx context.Context, query string, args []any, tx ...*sqlx.Tx) (sql.Result, error) {
-	var (
-		rs  sql.Result
-		err error
-	)
+func (s MysqlClient) ExecRaw(ctx context.Context, query string, args []any, tx ...*sqlx.Tx) (_ sql.Result, err error) {
+	ctx, done, err := s.begin(ctx, "ExecRaw", query, txCount(tx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { done(err) }()
+	var rs sql.Result
+	start := time.Now()
 	if len(tx) > 0 && tx[0] != nil {
-		rs, err = tx[0].Exec(query, args...)
+		rs, err = tx[0].ExecContext(ctx, query, args...)
 	} else {
-		rs, err = s.Db.Exec(query, args...)
+		rs, err = s.Db.ExecContext(ctx, query, args...)
 	}
+	s.logQuery(ctx, "ExecRaw", query, nil, nil, args, start, err)
 	if err != nil {
-		slog.ErrorContext(ctx, "mdb ExecRaw failed", "error", err, "sql", query, "args", args)
 		return nil, err
 	}
 	return rs, nil
@@ -163,55 +345,36 @@ func (s MysqlClient) ExecRaw(ctx context.Context, query string, args []any, tx .
 
 // QueryRaw 执行原生查询 SQL，将结果填充到 dest
 // dest 必须是可被 sqlx.Select 接受的类型，例如 *[]struct 或 *[]map[string]any
-func (s MysqlClient) QueryRaw(ctx context.Context, dest any, query string, args []any, tx ...*sqlx.Tx) error {
-	var err error
+func (s MysqlClient) QueryRaw(ctx context.Context, dest any, query string, args []any, tx ...*sqlx.Tx) (err error) {
+	ctx, done, err := s.begin(ctx, "QueryRaw", query, txCount(tx))
+	if err != nil {
+		return err
+	}
+	defer func() { done(err) }()
+	start := time.Now()
 	if len(tx) > 0 && tx[0] != nil {
-		err = tx[0].Select(dest, query, args...)
+		err = tx[0].SelectContext(ctx, dest, query, args...)
 	} else {
-		err = sqlx.Select(s.Db, dest, query, args...)
+		err = s.retryRead(ctx, "QueryRaw", dest, func() error {
+			return sqlx.SelectContext(ctx, s.Db, dest, query, args...)
+		})
 	}
-	if err != nil {
-		if !errors.Is(err, sql.ErrNoRows) {
-			slog.ErrorContext(ctx, "mdb QueryRaw failed", "error", err, "sql", query, "args", args)
-		}
+	if errors.Is(err, sql.ErrNoRows) {
+		s.logQuery(ctx, "QueryRaw", query, nil, nil, args, start, nil)
 		return err
 	}
-	return nil
+	s.logQuery(ctx, "QueryRaw", query, nil, nil, args, start, err)
+	return err
 }
 
 // map[string]any{"tableName": "t_user",  "Set":map[string]any{"nick": "nihao"}, "Where":map[string]any{"userId": "1111"}}
 // 下面你的跟新方法 可以按照户指定顺序更新字段,  有些时候需要指定更新顺序的 就用下买你的方法传入参数
 // map[string]any{"tableName": "t_user",  "Set":[]map[string]any{{"nick": "nihao"}, {"name": []string{"if(s=0, 1, 0)"}}}, "Where":map[string]any{"userId": "1111"}}
 
-func (s MysqlClient) Transaction(ctx context.Context, queryObj func(context.Context, MysqlClient, *sqlx.Tx) error) (err error) {
-	beginx, err := s.Db.Beginx()
-
-	if err != nil {
-		slog.ErrorContext(ctx, "begin trans failed", "error", err.Error())
-		return
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			err = beginx.Rollback()
-			slog.ErrorContext(ctx, "事务回滚", "error", err.Error())
-			if err != nil {
-				return
-			}
-		} else {
-			err = beginx.Commit()
-			if err != nil {
-				slog.ErrorContext(ctx, "提交失败", "error", err)
-				return
-			}
-		}
-	}()
-	if er := queryObj(ctx, s, beginx); er != nil {
-		err = beginx.Rollback()
-		slog.ErrorContext(ctx, "事务回滚", "error", er)
-		if er != nil {
-			return
-		}
-
-	}
-	return
+// Transaction 在事务中执行 queryObj：返回错误或 panic 时回滚并返回该错误，否则提交并返回提交结果
+// queryObj 的 ctx 可用于 OnCommit/OnRollback 注册回调
+func (s MysqlClient) Transaction(ctx context.Context, queryObj func(context.Context, MysqlClient, *sqlx.Tx) error) error {
+	return s.runTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		return queryObj(ctx, s, tx)
+	})
 }
@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/mysqltest"
+)
+
+const auditFixture = `
+CREATE TABLE t_account (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(64) NOT NULL,
+  balance INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (id)
+);
+CREATE TABLE t_plain (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  note VARCHAR(64) NOT NULL,
+  PRIMARY KEY (id)
+);
+`
+
+type auditCollector struct {
+	records []db.AuditRecord
+}
+
+func (c *auditCollector) WriteAudit(ctx context.Context, tx *sqlx.Tx, records []db.AuditRecord) error {
+	c.records = append(c.records, records...)
+	return nil
+}
+
+func TestAudit_Changes(t *testing.T) {
+	sink := &auditCollector{}
+	cli := mysqltest.NewClientWithOptions(t, []db.Option{db.WithAudit(db.AuditConfig{Sink: sink, Tables: []string{"t_account"}})}, auditFixture)
+	ctx := db.WithRequestId(db.WithAuditActor(context.Background(), "alice"), "req-1")
+
+	tb := builder.Table("t_account")
+	if _, err := cli.ExecByBuilder(ctx, tb.InsertMany([]map[string]any{{"name": "a", "balance": 10}, {"name": "b", "balance": 20}})); err != nil {
+		t.Fatal(err)
+	}
+	upd := builder.Table("t_account")
+	if _, err := cli.ExecByBuilder(ctx, upd.Where(upd.Field("balance").Gte(0, "min_balance")).UpdateMap(map[string]any{"balance": 20})); err != nil {
+		t.Fatal(err)
+	}
+	del := builder.Table("t_account")
+	if _, err := cli.ExecByBuilder(ctx, del.Where(del.Field("name").Eq("b", "del_name")).Delete()); err != nil {
+		t.Fatal(err)
+	}
+	// 不在 Tables 中的表不审计
+	if _, err := cli.ExecByBuilder(ctx, builder.Table("t_plain").InsertMany([]map[string]any{{"note": "x"}})); err != nil {
+		t.Fatal(err)
+	}
+
+	recs := sink.records
+	if len(recs) != 4 {
+		t.Fatalf("records = %+v", recs)
+	}
+	for _, r := range recs {
+		if r.Actor != "alice" || r.RequestId != "req-1" || r.Table != "t_account" || r.At.IsZero() {
+			t.Fatalf("unexpected record: %+v", r)
+		}
+	}
+	if recs[0].Op != "insert" || pkOf(recs[0]) != 1 || pkOf(recs[1]) != 2 || recs[1].New["name"] != "b" {
+		t.Fatalf("insert records: %+v %+v", recs[0], recs[1])
+	}
+	// 只有 a 的 balance 发生变化
+	u := recs[2]
+	if u.Op != "update" || pkOf(u) != 1 || len(u.Changed) != 1 || u.Changed[0] != "balance" || toInt(u.Old["balance"]) != 10 || toInt(u.New["balance"]) != 20 {
+		t.Fatalf("update record: %+v", u)
+	}
+	d := recs[3]
+	if d.Op != "delete" || pkOf(d) != 2 || d.Old["name"] != "b" || d.New != nil {
+		t.Fatalf("delete record: %+v", d)
+	}
+}
+
+func TestAudit_TableSinkRollsBackWithTx(t *testing.T) {
+	table := db.NewAuditTable("")
+	cli := mysqltest.NewClientWithOptions(t, []db.Option{db.WithAudit(db.AuditConfig{Sink: table})}, auditFixture)
+	ctx := db.WithAuditActor(context.Background(), "bob")
+	if err := mysqltest.Exec(ctx, cli, table.CreateTableSQL()); err != nil {
+		t.Fatal(err)
+	}
+
+	boom := errors.New("boom")
+	err := cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		if _, err := ex.ExecByBuilder(ctx, builder.Table("t_account").InsertMany([]map[string]any{{"name": "a", "balance": 1}})); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatal(err)
+	}
+	err = cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		_, err := ex.ExecByBuilder(ctx, builder.Table("t_account").InsertMany([]map[string]any{{"name": "c", "balance": 3}}))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []struct {
+		Op         string `db:"op"`
+		PrimaryKey string `db:"primary_key"`
+		NewValues  string `db:"new_values"`
+		Actor      string `db:"actor"`
+	}
+	if err := cli.QueryRaw(ctx, &rows, "SELECT op, primary_key, new_values, actor FROM audit_log ORDER BY id", nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Op != "insert" || rows[0].Actor != "bob" {
+		t.Fatalf("audit rows = %+v", rows)
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(rows[0].NewValues), &values); err != nil || values["name"] != "c" {
+		t.Fatalf("new_values = %s (%v)", rows[0].NewValues, err)
+	}
+}
+
+func TestAudit_AfterCommit(t *testing.T) {
+	var sent [][]db.AuditRecord
+	sink := db.AuditAfterCommit(func(ctx context.Context, records []db.AuditRecord) error {
+		sent = append(sent, records)
+		return nil
+	})
+	cli := mysqltest.NewClientWithOptions(t, []db.Option{db.WithAudit(db.AuditConfig{Sink: sink})}, auditFixture)
+	ctx := context.Background()
+
+	_ = cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		if _, err := ex.ExecByBuilder(ctx, builder.Table("t_plain").InsertMany([]map[string]any{{"note": "x"}})); err != nil {
+			return err
+		}
+		if len(sent) != 0 {
+			t.Error("records sent before commit")
+		}
+		return errors.New("rollback")
+	})
+	if len(sent) != 0 {
+		t.Fatalf("records sent after rollback: %+v", sent)
+	}
+	// 没有事务时自动开启事务，提交后发送
+	if _, err := cli.ExecByBuilder(ctx, builder.Table("t_plain").InsertMany([]map[string]any{{"note": "y"}})); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0][0].New["note"] != "y" {
+		t.Fatalf("sent = %+v", sent)
+	}
+}
+
+func pkOf(r db.AuditRecord) int64 {
+	return toInt(r.PrimaryKey["id"])
+}
+
+func toInt(v any) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case string:
+		var i int64
+		_ = json.Unmarshal([]byte(n), &i)
+		return i
+	}
+	return -1
+}
+
+func TestAudit_RedactionOptIn(t *testing.T) {
+	const fixture = "CREATE TABLE t_contact (id BIGINT NOT NULL AUTO_INCREMENT, phone VARCHAR(32) NOT NULL, PRIMARY KEY (id))"
+	ctx := context.Background()
+	insert := builder.Table("t_contact").InsertMany([]map[string]any{{"phone": "13800000000"}})
+
+	// 默认保存原值，日志脱敏策略不作用于审计记录
+	sink := &auditCollector{}
+	cli := mysqltest.NewClientWithOptions(t, []db.Option{db.WithAudit(db.AuditConfig{Sink: sink})}, fixture)
+	if _, err := cli.ExecByBuilder(ctx, insert); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.records) != 1 || sink.records[0].New["phone"] != "13800000000" {
+		t.Fatalf("records = %+v", sink.records)
+	}
+
+	policy := db.DefaultRedactionPolicy()
+	sink = &auditCollector{}
+	cli = mysqltest.NewClientWithOptions(t, []db.Option{db.WithAudit(db.AuditConfig{Sink: sink, Redaction: &policy})}, fixture)
+	if _, err := cli.ExecByBuilder(ctx, insert); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.records) != 1 || sink.records[0].New["phone"] != "***" {
+		t.Fatalf("redacted records = %+v", sink.records)
+	}
+}
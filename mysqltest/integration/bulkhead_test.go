@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestBulkhead_CallsInsideTransaction(t *testing.T) {
+	cli := mysqltest.NewClientWithOptions(t,
+		[]db.Option{db.WithBulkhead(db.BulkheadConfig{MaxConcurrent: 1})},
+		"CREATE TABLE t_bh (id INT PRIMARY KEY)")
+	ctx := context.Background()
+
+	// 事务占用唯一的名额，回调与 OnCommit 中不带 tx 的调用复用该名额
+	var hookErr error
+	err := cli.Transaction(ctx, func(ctx context.Context, m db.MysqlClient, tx *sqlx.Tx) error {
+		if _, err := m.ExecRaw(ctx, "INSERT INTO t_bh (id) VALUES (1)", nil, tx); err != nil {
+			return err
+		}
+		_ = db.OnCommit(ctx, func(ctx context.Context) {
+			var n []int
+			hookErr = cli.QueryRaw(ctx, &n, "SELECT COUNT(*) FROM t_bh", nil)
+		})
+		var n []int
+		return m.QueryRaw(ctx, &n, "SELECT COUNT(*) FROM t_bh", nil)
+	})
+	if err != nil || hookErr != nil {
+		t.Fatalf("err = %v, hook err = %v", err, hookErr)
+	}
+	if st := cli.BulkheadStats(); st.InUse != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	// 名额释放后，其他调用仍然受限制
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		_ = cli.Transaction(ctx, func(context.Context, db.MysqlClient, *sqlx.Tx) error {
+			close(held)
+			<-release
+			return nil
+		})
+	}()
+	<-held
+	var n []int
+	if err := cli.QueryRaw(ctx, &n, "SELECT 1", nil); err == nil {
+		t.Fatal("call outside the transaction should be rejected")
+	}
+	close(release)
+}
@@ -0,0 +1,97 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestQueryByBuilder_Simple(t *testing.T) {
+	cli := mysqltest.NewClient(t,
+		"CREATE TABLE t_user (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64))",
+		"INSERT INTO t_user (id, name) VALUES (1, 'a'), (2, 'b')")
+	ctx := context.Background()
+
+	tu := builder.Table("t_user")
+	b := tu.Select(tu.Field("id")).Order(tu.Field("id").Asc()).First()
+	var row struct {
+		Id int64 `db:"id"`
+	}
+	if err := cli.QueryByBuilder(ctx, b, &row); err != nil || row.Id != 1 {
+		t.Fatalf("QueryByBuilder: err = %v, id = %d", err, row.Id)
+	}
+}
+
+func TestFetchByBuilder_List(t *testing.T) {
+	cli := mysqltest.NewClient(t,
+		"CREATE TABLE t_user (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64))",
+		"INSERT INTO t_user (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c')")
+	ctx := context.Background()
+
+	tu := builder.Table("t_user")
+	b := tu.Select(tu.Field("id")).Limit(2)
+	var rows []struct {
+		Id int64 `db:"id"`
+	}
+	if err := cli.FetchByBuilder(ctx, b, &rows); err != nil || len(rows) != 2 {
+		t.Fatalf("FetchByBuilder: err = %v, rows = %v", err, rows)
+	}
+}
+
+func TestExecByBuilder_DML(t *testing.T) {
+	cli := mysqltest.NewClient(t, "CREATE TABLE t_dml (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64), age INT)")
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	err := cli.Transaction(ctx, func(ctx context.Context, m db.MysqlClient, tx *sqlx.Tx) error {
+		tb := builder.Table("t_dml")
+		if _, err := m.ExecByBuilder(ctx, tb.InsertMap(map[string]any{"name": "n1", "age": 18}), tx); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+
+		tb = builder.Table("t_dml")
+		if _, err := m.ExecByBuilder(ctx, tb.Where(tb.Field("name").Eq("n1", "n")).UpdateMap(map[string]any{"age": 19}), tx); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+
+		tb = builder.Table("t_dml")
+		var row struct {
+			Age int `db:"age"`
+		}
+		if err := m.QueryByBuilder(ctx, tb.Select(tb.Field("age")).Where(tb.Field("name").Eq("n1", "n")).First(), &row, tx); err != nil || row.Age != 19 {
+			t.Fatalf("query in tx: err = %v, age = %d", err, row.Age)
+		}
+
+		tb = builder.Table("t_dml")
+		if _, err := m.ExecByBuilder(ctx, tb.Where(tb.Field("age").Eq(19, "a")).Delete(), tx); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+
+		tb = builder.Table("t_dml")
+		var list []struct {
+			Id int64 `db:"id"`
+		}
+		if err := m.FetchByBuilder(ctx, tb.Select(tb.Field("id")).Limit(10), &list, tx); err != nil || len(list) != 0 {
+			t.Fatalf("fetch in tx: err = %v, rows = %v", err, list)
+		}
+
+		// 回滚后事务内的写入都不应保留
+		if _, err := m.ExecByBuilder(ctx, builder.Table("t_dml").InsertMap(map[string]any{"name": "n2", "age": 20}), tx); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("transaction: %v", err)
+	}
+
+	var n []int
+	if err := cli.QueryRaw(ctx, &n, "SELECT COUNT(*) FROM t_dml", nil); err != nil || n[0] != 0 {
+		t.Fatalf("after rollback: err = %v, count = %v", err, n)
+	}
+}
@@ -0,0 +1,4 @@
+// Package integration 基于 mysqltest 的集成测试，覆盖 db 及 outbox 等包中需要真实数据库的功能
+//
+// 为了不让根模块依赖 go-mysql-server，这些测试放在 mysqltest 模块中
+package integration
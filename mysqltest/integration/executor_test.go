@@ -0,0 +1,56 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestRunInTx(t *testing.T) {
+	cli := mysqltest.NewClient(t, "CREATE TABLE t_executor (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64))")
+	ctx := context.Background()
+
+	var ex db.Executor = *cli
+	count := func() int {
+		var n []int
+		if err := ex.QueryRaw(ctx, &n, "SELECT COUNT(*) FROM t_executor", nil); err != nil {
+			t.Fatal(err)
+		}
+		return n[0]
+	}
+
+	errRollback := errors.New("rollback")
+	err := ex.RunInTx(ctx, func(ctx context.Context, tx db.Executor) error {
+		if _, err := tx.ExecByBuilder(ctx, builder.Table("t_executor").InsertMap(map[string]any{"name": "a"})); err != nil {
+			return err
+		}
+		// 嵌套调用使用保存点，内存库不支持，见根模块的 TestRunInTx_Savepoint
+		var one []int
+		if err := tx.QueryRaw(ctx, &one, "SELECT 1", nil); err != nil {
+			t.Errorf("query in tx failed: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || count() != 0 {
+		t.Fatalf("expected rollback, err=%v count=%d", err, count())
+	}
+
+	err = ex.RunInTx(ctx, func(ctx context.Context, tx db.Executor) error {
+		_, err := tx.ExecByBuilder(ctx, builder.Table("t_executor").InsertMap(map[string]any{"name": "b"}))
+		return err
+	})
+	if err != nil || count() != 1 {
+		t.Fatalf("expected commit, err=%v count=%d", err, count())
+	}
+
+	err = ex.RunInTx(ctx, func(ctx context.Context, tx db.Executor) error {
+		panic("boom")
+	})
+	if err == nil || count() != 1 {
+		t.Fatalf("expected panic to roll back, err=%v count=%d", err, count())
+	}
+}
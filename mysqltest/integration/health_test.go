@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestHealth(t *testing.T) {
+	cli := mysqltest.NewClient(t)
+	st, err := cli.Health(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Healthy || st.Version == "" || st.Latency <= 0 {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if st.Replica || st.ReplicationLag != nil {
+		t.Errorf("unexpected replica status: %+v", st)
+	}
+	if st.Pool.MaxOpenConnections != 10 {
+		t.Errorf("pool stats: %+v", st.Pool)
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	srv, err := mysqltest.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli := db.NewMysqlClient(srv.Config())
+	defer cli.Db.Close()
+
+	ctx := context.Background()
+	h := db.NewHealthChecker(cli, db.HealthCheckerConfig{Interval: time.Hour, Timeout: time.Second, FailureThreshold: 2})
+	handler := h.Handler()
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if h.Ready() || get("/readyz") != http.StatusServiceUnavailable {
+		t.Fatal("ready before first check")
+	}
+	if st := h.Check(ctx); !st.Healthy || !h.Ready() {
+		t.Fatalf("first check: %+v", st)
+	}
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Fatalf("/readyz = %d", code)
+	}
+	if code := get("/probe/healthz"); code != http.StatusOK {
+		t.Fatalf("/healthz = %d", code)
+	}
+
+	_ = srv.Close()
+	cli.Db.SetMaxIdleConns(0) // 丢弃已建立的连接
+	h.Check(ctx)
+	if !h.Ready() {
+		t.Fatal("not ready after a single failure")
+	}
+	h.Check(ctx)
+	if h.Ready() || h.Last().Healthy || h.Last().Error == "" {
+		t.Fatalf("ready after %d failures: %+v", 2, h.Last())
+	}
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz = %d", code)
+	}
+	if code := get("/healthz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("/healthz = %d", code)
+	}
+	if code := get("/other"); code != http.StatusNotFound {
+		t.Fatalf("/other = %d", code)
+	}
+}
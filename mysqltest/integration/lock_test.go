@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestLock(t *testing.T) {
+	cli := mysqltest.NewClient(t)
+	ctx := context.Background()
+
+	err := cli.Lock(ctx, "job", time.Second, func(ctx context.Context) error {
+		// 其他会话拿不到锁
+		ok, err := cli.TryLock(ctx, "job", func(context.Context) error {
+			t.Error("acquired a held lock")
+			return nil
+		})
+		if ok || err != nil {
+			t.Errorf("TryLock = %v, %v", ok, err)
+		}
+		err = cli.Lock(ctx, "job", 50*time.Millisecond, func(context.Context) error { return nil })
+		if !errors.Is(err, db.ErrLockNotAcquired) {
+			t.Errorf("Lock = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 释放后可以再次获取，panic 时也会释放
+	err = cli.Lock(ctx, "job", 0, func(context.Context) error { panic("boom") })
+	if err == nil {
+		t.Fatal("panic not reported")
+	}
+	ok, err := cli.TryLock(ctx, "job", func(context.Context) error { return nil })
+	if !ok || err != nil {
+		t.Fatalf("TryLock after release = %v, %v", ok, err)
+	}
+	// fn 的错误：已获取到锁
+	boom := errors.New("boom")
+	if ok, err := cli.TryLock(ctx, "job", func(context.Context) error { return boom }); !ok || !errors.Is(err, boom) {
+		t.Fatalf("TryLock with fn error = %v, %v", ok, err)
+	}
+	// 获取锁之前失败：没有获取到锁
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if ok, err := cli.TryLock(canceled, "job", func(context.Context) error { return nil }); ok || err == nil {
+		t.Fatalf("TryLock before acquire = %v, %v", ok, err)
+	}
+}
+
+func TestLock_Lost(t *testing.T) {
+	cli := mysqltest.NewClient(t)
+	ctx := context.Background()
+
+	err := cli.LockWithOptions(ctx, "job", db.LockOptions{Heartbeat: 20 * time.Millisecond}, func(ctx context.Context) error {
+		var holder []int64
+		if err := cli.QueryRaw(ctx, &holder, "SELECT IS_USED_LOCK('job')", nil); err != nil {
+			return err
+		}
+		if _, err := cli.ExecRaw(ctx, fmt.Sprintf("KILL %d", holder[0]), nil); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+			return errors.New("lock loss not detected")
+		}
+	})
+	if !errors.Is(err, db.ErrLockLost) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLeaderLock(t *testing.T) {
+	cli := mysqltest.NewClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var leaders atomic.Int32
+	elected := make(chan struct{}, 2)
+	run := func() error {
+		return cli.LeaderLock(ctx, "leader", db.LeaseOptions{Heartbeat: 20 * time.Millisecond, Retry: 20 * time.Millisecond}, func(ctx context.Context) error {
+			if leaders.Add(1) > 1 {
+				t.Error("two leaders")
+			}
+			defer leaders.Add(-1)
+			elected <- struct{}{}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+	errs := make(chan error, 2)
+	go func() { errs <- run() }()
+	go func() { errs <- run() }()
+
+	<-elected
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, context.Canceled) {
+			t.Fatalf("LeaderLock = %v", err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/mysqltest"
+	"github.com/preceeder/db/outbox"
+)
+
+func newOutbox(t *testing.T) (*db.MysqlClient, *outbox.Outbox, *time.Time) {
+	t.Helper()
+	cli := mysqltest.NewClient(t)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ob := outbox.New(cli, outbox.WithClock(func() time.Time { return now }))
+	if err := mysqltest.Exec(context.Background(), cli, ob.CreateTableSQL()); err != nil {
+		t.Fatal(err)
+	}
+	return cli, ob, &now
+}
+
+func TestEnqueue_Transactional(t *testing.T) {
+	cli, ob, _ := newOutbox(t)
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	err := cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		if err := ob.Enqueue(ctx, ex, outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("x")}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("RunInTx: %v", err)
+	}
+	counts, err := ob.Counts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts[outbox.StatusPending] != 0 {
+		t.Fatalf("rolled back enqueue is visible: %v", counts)
+	}
+
+	err = cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		return ob.Enqueue(ctx, ex, outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("x"), Headers: map[string]string{"k": "v"}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts, _ = ob.Counts(ctx); counts[outbox.StatusPending] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+	if err := ob.Enqueue(ctx, cli, outbox.Message{AggregateKey: "a"}); err == nil {
+		t.Fatal("expected error for message without topic")
+	}
+}
+
+func TestRelay_OrderPerAggregate(t *testing.T) {
+	cli, ob, _ := newOutbox(t)
+	ctx := context.Background()
+
+	err := ob.Enqueue(ctx, cli,
+		outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("a1"), Headers: map[string]string{"trace": "1"}},
+		outbox.Message{AggregateKey: "b", Topic: "t", Payload: []byte("b1")},
+		outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("a2")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := &outbox.MemoryPublisher{}
+	relay := ob.Relay(pub, outbox.RelayConfig{})
+	// 每批每个聚合键只投递最早的一个事件
+	n, err := relay.RelayOnce(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	n, err = relay.RelayOnce(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if n, _ = relay.RelayOnce(ctx); n != 0 {
+		t.Fatalf("RelayOnce on empty outbox = %d", n)
+	}
+
+	var got []string
+	for _, e := range pub.Events() {
+		got = append(got, string(e.Payload))
+	}
+	if len(got) != 3 || got[0] != "a1" || got[1] != "b1" || got[2] != "a2" {
+		t.Fatalf("delivered %v", got)
+	}
+	if e := pub.Events()[0]; e.Headers["trace"] != "1" || e.Id == 0 || e.CreatedAt.IsZero() {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	if counts, _ := ob.Counts(ctx); counts[outbox.StatusDispatched] != 3 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+func TestRelay_RetryAndDeadLetter(t *testing.T) {
+	cli, ob, now := newOutbox(t)
+	ctx := context.Background()
+
+	if err := ob.Enqueue(ctx, cli, outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("a1")}, outbox.Message{AggregateKey: "a", Topic: "t", Payload: []byte("a2")}); err != nil {
+		t.Fatal(err)
+	}
+	fail := errors.New("broker down")
+	pub := &outbox.MemoryPublisher{Fail: func(e outbox.Event) error {
+		if string(e.Payload) == "a1" {
+			return fail
+		}
+		return nil
+	}}
+	var dead []outbox.Event
+	relay := ob.Relay(pub, outbox.RelayConfig{
+		MaxAttempts:  2,
+		Backoff:      func(int) time.Duration { return time.Minute },
+		OnDeadLetter: func(ctx context.Context, e outbox.Event, err error) { dead = append(dead, e) },
+	})
+
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	// 退避期间不投递，后续事件也不会越过失败的事件
+	if n, _ := relay.RelayOnce(ctx); n != 0 {
+		t.Fatalf("RelayOnce during backoff = %d", n)
+	}
+	*now = now.Add(time.Minute)
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if len(dead) != 1 || string(dead[0].Payload) != "a1" || dead[0].Attempts != 1 {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+	// 进入死信后投递同一聚合键的下一个事件
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if events := pub.Events(); len(events) != 1 || string(events[0].Payload) != "a2" {
+		t.Fatalf("delivered %+v", events)
+	}
+	if counts, _ := ob.Counts(ctx); counts[outbox.StatusDead] != 1 || counts[outbox.StatusDispatched] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+
+	pub.Fail = nil
+	if n, err := ob.Requeue(ctx, dead[0].Id); err != nil || n != 1 {
+		t.Fatalf("Requeue = %d, %v", n, err)
+	}
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if counts, _ := ob.Counts(ctx); counts[outbox.StatusDispatched] != 2 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+func TestRelay_PublisherPanic(t *testing.T) {
+	cli, ob, _ := newOutbox(t)
+	ctx := context.Background()
+	if err := ob.Enqueue(ctx, cli, outbox.Message{AggregateKey: "a", Topic: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	relay := ob.Relay(outbox.PublisherFunc(func(context.Context, outbox.Event) error { panic("bad") }), outbox.RelayConfig{})
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if counts, _ := ob.Counts(ctx); counts[outbox.StatusPending] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+}
+
+func TestRelay_IgnoresScopesAndUsesClientLogger(t *testing.T) {
+	builder.SetGlobalSoftDelete("deleted_at")
+	builder.RegisterTenantTables("tenant_id", "outbox_events")
+	defer builder.SetGlobalSoftDelete("")
+	defer builder.UnregisterTenantTables("outbox_events")
+
+	var logs bytes.Buffer
+	cli := mysqltest.NewClientWithOptions(t, []db.Option{db.WithLogger(db.LoggerConfig{Handler: slog.NewJSONHandler(&logs, nil)})})
+	ob := outbox.New(cli)
+	ctx := context.Background()
+	if err := mysqltest.Exec(ctx, cli, ob.CreateTableSQL()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ob.Enqueue(ctx, cli, outbox.Message{AggregateKey: "a", Topic: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	relay := ob.Relay(outbox.PublisherFunc(func(context.Context, outbox.Event) error { return errors.New("broker down") }), outbox.RelayConfig{})
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v", n, err)
+	}
+	if !strings.Contains(logs.String(), `"db.event":"client"`) || !strings.Contains(logs.String(), "outbox publish failed") {
+		t.Fatalf("logs = %s", logs.String())
+	}
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/mysqltest"
+)
+
+type replayUser struct {
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+// replayScenario 录制与回放共用的一组操作
+func replayScenario(ctx context.Context, cli *db.MysqlClient) ([]replayUser, int64, error) {
+	rs, err := cli.ExecByBuilder(ctx, builder.Table("t_user").InsertMap(map[string]any{"name": "c", "age": 30}))
+	if err != nil {
+		return nil, 0, err
+	}
+	id, _ := rs.LastInsertId()
+	err = cli.Transaction(ctx, func(ctx context.Context, c db.MysqlClient, tx *sqlx.Tx) error {
+		upd := builder.Table("t_user")
+		_, err := c.ExecByBuilder(ctx, upd.Where(upd.Field("id").Eq(id, "id")).UpdateMap(map[string]any{"name": "cc", "age": 31, "id": id}), tx)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var users []replayUser
+	tb := builder.Table("t_user")
+	err = cli.FetchByBuilder(ctx, tb.Select(tb.Field("id"), tb.Field("name"), tb.Field("age")).Order(tb.Field("id").Asc()), &users)
+	return users, id, err
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fixture.jsonl")
+
+	srv, err := mysqltest.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, rec, err := db.NewRecordingClient(srv.Config(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mysqltest.Exec(ctx, cli, "CREATE TABLE t_user (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, name VARCHAR(64) NOT NULL, age INT NOT NULL); INSERT INTO t_user (name, age) VALUES ('a', 18), ('b', 20)"); err != nil {
+		t.Fatal(err)
+	}
+	recorded, recordedId, err := replayScenario(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = cli.Db.Close()
+	_ = srv.Close()
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 回放不需要数据库
+	rcli, rep, err := db.NewReplayClient(srv.Config(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rcli.Db.Close()
+	if err := mysqltest.Exec(ctx, rcli, "CREATE TABLE t_user (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, name VARCHAR(64) NOT NULL, age INT NOT NULL); INSERT INTO t_user (name, age) VALUES ('a', 18), ('b', 20)"); err != nil {
+		t.Fatal(err)
+	}
+	replayed, replayedId, err := replayScenario(ctx, rcli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayedId != recordedId || len(replayed) != 3 || len(replayed) != len(recorded) {
+		t.Fatalf("replayed %d %+v, recorded %d %+v", replayedId, replayed, recordedId, recorded)
+	}
+	for i := range recorded {
+		if replayed[i] != recorded[i] {
+			t.Errorf("row %d: replayed %+v, recorded %+v", i, replayed[i], recorded[i])
+		}
+	}
+	if replayed[2].Age != 31 || replayed[2].Name != "cc" {
+		t.Errorf("unexpected age: %+v", replayed[2])
+	}
+	if err := rep.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 多出的语句
+	if _, err := rcli.ExecRaw(ctx, "DELETE FROM t_user", nil); !errors.Is(err, db.ErrReplayMismatch) {
+		t.Fatalf("unexpected statement: err = %v", err)
+	}
+}
+
+func TestReplay_Mismatch(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fixture.jsonl")
+
+	srv, err := mysqltest.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, rec, err := db.NewRecordingClient(srv.Config(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mysqltest.Exec(ctx, cli, "CREATE TABLE t_a (id INT PRIMARY KEY)", "CREATE TABLE t_b (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = cli.Db.Close()
+	_ = srv.Close()
+	_ = rec.Close()
+
+	rcli, rep, err := db.NewReplayClient(srv.Config(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rcli.Db.Close()
+	// 顺序不同
+	if err := mysqltest.Exec(ctx, rcli, "CREATE TABLE t_b (id INT PRIMARY KEY)"); !errors.Is(err, db.ErrReplayMismatch) {
+		t.Fatalf("reordered statement: err = %v", err)
+	}
+	if rep.Remaining() != 2 {
+		t.Fatalf("Remaining = %d", rep.Remaining())
+	}
+	if err := rep.Done(); !errors.Is(err, db.ErrReplayMismatch) {
+		t.Fatalf("Done = %v", err)
+	}
+}
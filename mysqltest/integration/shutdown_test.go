@@ -0,0 +1,112 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func newShutdownClient(t *testing.T) *db.MysqlClient {
+	srv, err := mysqltest.Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	cli := db.NewMysqlClient(srv.Config())
+	if err := mysqltest.Exec(context.Background(), cli, "CREATE TABLE t_log (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
+
+func TestShutdown_Drains(t *testing.T) {
+	cli := newShutdownClient(t)
+	ctx := context.Background()
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	txErr := make(chan error, 1)
+	go func() {
+		txErr <- cli.Transaction(ctx, func(ctx context.Context, c db.MysqlClient, tx *sqlx.Tx) error {
+			close(entered)
+			<-release
+			// 事务内的语句在 Shutdown 期间仍可执行
+			_, err := c.ExecRaw(ctx, "INSERT INTO t_log (id) VALUES (1)", nil, tx)
+			return err
+		})
+	}()
+	<-entered
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- cli.Shutdown(ctx) }()
+	for len(cli.InFlight()) != 1 || !closed(cli) {
+		time.Sleep(time.Millisecond)
+	}
+	var cnt []int
+	err := cli.QueryRaw(ctx, &cnt, "SELECT COUNT(*) FROM t_log", nil)
+	if !errors.Is(err, db.ErrClientClosed) {
+		t.Fatalf("new call during shutdown: err = %v", err)
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned before transaction finished: %v", err)
+	default:
+	}
+
+	close(release)
+	if err := <-txErr; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.Health(ctx); !errors.Is(err, db.ErrClientClosed) {
+		t.Fatalf("Health after shutdown: err = %v", err)
+	}
+}
+
+// closed 通过 Health 判断客户端是否已进入关闭流程
+func closed(cli *db.MysqlClient) bool {
+	_, err := cli.Health(context.Background())
+	return errors.Is(err, db.ErrClientClosed)
+}
+
+func TestShutdown_Force(t *testing.T) {
+	cli := newShutdownClient(t)
+	ctx := context.Background()
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	txErr := make(chan error, 1)
+	go func() {
+		txErr <- cli.Transaction(ctx, func(ctx context.Context, c db.MysqlClient, tx *sqlx.Tx) error {
+			close(entered)
+			<-release
+			_, err := c.ExecRaw(ctx, "INSERT INTO t_log (id) VALUES (1)", nil, tx)
+			return err
+		})
+	}()
+	<-entered
+
+	sctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := cli.Shutdown(sctx)
+	var se *db.ShutdownError
+	if !errors.As(err, &se) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if len(se.Pending) != 1 || se.Pending[0].Op != "Transaction" {
+		t.Fatalf("pending = %+v", se.Pending)
+	}
+
+	close(release)
+	if err := <-txErr; err == nil {
+		t.Fatal("transaction committed after forced shutdown")
+	}
+	if _, err := cli.ExecRaw(ctx, "INSERT INTO t_log (id) VALUES (2)", nil); !errors.Is(err, db.ErrClientClosed) {
+		t.Fatalf("call after shutdown: err = %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/mysqltest"
+)
+
+func TestTxHooks(t *testing.T) {
+	cli := mysqltest.NewClient(t, "CREATE TABLE t_event (id INT PRIMARY KEY)")
+	ctx := context.Background()
+	var events []string
+	record := func(s string) func(context.Context) {
+		return func(context.Context) { events = append(events, s) }
+	}
+
+	if err := db.OnCommit(ctx, record("x")); !errors.Is(err, db.ErrNotInTransaction) {
+		t.Fatalf("OnCommit outside transaction: %v", err)
+	}
+
+	// 提交后按注册顺序执行，panic 不影响后续回调与事务结果
+	err := cli.Transaction(ctx, func(ctx context.Context, m db.MysqlClient, tx *sqlx.Tx) error {
+		_ = db.OnCommit(ctx, record("a"))
+		_ = db.OnCommit(ctx, func(context.Context) { panic("boom") })
+		_ = db.OnCommit(ctx, record("b"))
+		_ = db.OnRollback(ctx, func(context.Context, error) { events = append(events, "rollback") })
+		if len(events) != 0 {
+			t.Error("hook ran before commit")
+		}
+		_, err := m.ExecRaw(ctx, "INSERT INTO t_event (id) VALUES (1)", nil, tx)
+		return err
+	})
+	if err != nil || fmt.Sprint(events) != "[a b]" {
+		t.Fatalf("commit: err = %v, events = %v", err, events)
+	}
+
+	// 回滚时以导致回滚的错误执行 OnRollback
+	events = nil
+	errFail := errors.New("fail")
+	err = cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		_ = db.OnCommit(ctx, record("commit"))
+		_ = db.OnRollback(ctx, func(_ context.Context, err error) { events = append(events, "rollback:"+err.Error()) })
+		return errFail
+	})
+	if !errors.Is(err, errFail) || fmt.Sprint(events) != "[rollback:fail]" {
+		t.Fatalf("rollback: err = %v, events = %v", err, events)
+	}
+}
+
+func TestTransactionAll_Hooks(t *testing.T) {
+	ctx := context.Background()
+	shards := map[string]*db.MysqlClient{
+		"db0": mysqltest.NewClient(t, "CREATE TABLE t_event (id INT PRIMARY KEY)"),
+		"db1": mysqltest.NewClient(t, "CREATE TABLE t_event (id INT PRIMARY KEY)"),
+	}
+	c := db.NewShardedClient(shards, db.ShardedConfig{AllowCrossShardTx: true})
+	var events []string
+	id := 0
+	run := func(fail error) error {
+		id++
+		return c.TransactionAll(ctx, func(ctx context.Context, shard string, m db.MysqlClient, tx *sqlx.Tx) error {
+			_ = db.OnCommit(ctx, func(context.Context) { events = append(events, "commit:"+shard) })
+			_ = db.OnRollback(ctx, func(context.Context, error) { events = append(events, "rollback:"+shard) })
+			if _, err := m.ExecRaw(ctx, "INSERT INTO t_event (id) VALUES (?)", []any{id}, tx); err != nil {
+				return err
+			}
+			if shard == "db1" {
+				return fail
+			}
+			return nil
+		})
+	}
+
+	if err := run(nil); err != nil || fmt.Sprint(events) != "[commit:db0 commit:db1]" {
+		t.Fatalf("commit: err = %v, events = %v", err, events)
+	}
+
+	events = nil
+	errFail := errors.New("fail")
+	err := run(errFail)
+	sort.Strings(events)
+	if !errors.Is(err, errFail) || fmt.Sprint(events) != "[rollback:db0 rollback:db1]" {
+		t.Fatalf("rollback: err = %v, events = %v", err, events)
+	}
+
+	// 开启事务经过客户端的检查
+	if err := shards["db1"].Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(nil); !errors.Is(err, db.ErrClientClosed) {
+		t.Fatalf("closed shard: err = %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+// Package mysqltest 在进程内启动一个兼容 MySQL 协议的内存数据库（go-mysql-server），
+// 用于在没有外部 MySQL 的情况下端到端测试 MysqlClient 与 builder 生成的 SQL
+//
+//	func TestUser(t *testing.T) {
+//		cli := mysqltest.NewClient(t, "CREATE TABLE t_user (id BIGINT PRIMARY KEY AUTO_INCREMENT, name VARCHAR(64))")
+//		_, err := cli.ExecByBuilder(ctx, builder.Table("t_user").InsertMap(map[string]any{"name": "nick"}))
+//		...
+//	}
+//
+// mysqltest 是独立的 Go 模块（github.com/preceeder/db/mysqltest），根模块不依赖 go-mysql-server；
+// 使用它的测试需要放在依赖该模块的模块中，本仓库的集成测试在 integration 目录；
+// 仓库内开发时 go.mod 中的 replace 让 mysqltest 使用本地的根模块
+//
+// 注意：go-mysql-server 与 MySQL 并不完全一致（例如锁、保存点、部分函数与 information_schema 细节），
+// 依赖这些特性的用例仍需要真实的 MySQL
+package mysqltest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	sqle "github.com/dolthub/go-mysql-server"
+	"github.com/dolthub/go-mysql-server/memory"
+	"github.com/dolthub/go-mysql-server/server"
+	"github.com/dolthub/go-mysql-server/sql"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/migrations"
+)
+
+// DefaultDatabase 默认数据库名
+const DefaultDatabase = "mdb_test"
+
+// Server 进程内的内存 MySQL 服务
+type Server struct {
+	database string
+	listener net.Listener
+	srv      *server.Server
+	done     chan error
+}
+
+// Start 在 127.0.0.1 的随机端口上启动服务，并创建名为 database 的空数据库
+func Start(database string) (*Server, error) {
+	if database == "" {
+		database = DefaultDatabase
+	}
+	memDb := memory.NewDatabase(database)
+	memDb.EnablePrimaryKeyIndexes()
+	provider := memory.NewDBProvider(memDb)
+	engine := sqle.NewDefault(provider)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	srv, err := server.NewServer(server.Config{Protocol: "tcp", Listener: listener}, engine, sql.NewContext, memory.NewSessionBuilder(provider), nil)
+	if err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	s := &Server{database: database, listener: listener, srv: srv, done: make(chan error, 1)}
+	go func() { s.done <- srv.Start() }()
+	return s, nil
+}
+
+// Addr 返回监听地址，例如 127.0.0.1:54321
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Config 返回连接该服务的 MysqlConfig
+func (s *Server) Config() db.MysqlConfig {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	return db.MysqlConfig{
+		Host:        host,
+		Port:        port,
+		User:        "root",
+		Database:    s.database,
+		MaxOpenCons: 10,
+		MaxIdleCons: 2,
+	}
+}
+
+// DSN 返回 go-sql-driver/mysql 格式的连接串
+func (s *Server) DSN() string {
+	return fmt.Sprintf("root@tcp(%s)/%s", s.Addr(), s.database)
+}
+
+// Close 关闭服务
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// Exec 依次执行 script 中的语句（按分号拆分），用于加载 fixture DDL 与数据
+func Exec(ctx context.Context, cli *db.MysqlClient, scripts ...string) error {
+	for _, script := range scripts {
+		for i, stmt := range migrations.SplitStatements(script) {
+			if _, err := cli.Db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("mysqltest: statement %d: %w\n%s", i+1, err, stmt)
+			}
+		}
+	}
+	return nil
+}
+
+// NewClient 启动服务、执行 fixtures，并返回已连接的 MysqlClient；测试结束时自动关闭
+func NewClient(t testing.TB, fixtures ...string) *db.MysqlClient {
+	t.Helper()
+	return NewClientWithOptions(t, nil, fixtures...)
+}
+
+// NewClientWithOptions 同 NewClient，并为 MysqlClient 传入 Option
+func NewClientWithOptions(t testing.TB, opts []db.Option, fixtures ...string) *db.MysqlClient {
+	t.Helper()
+	s, err := Start(DefaultDatabase)
+	if err != nil {
+		t.Fatalf("mysqltest: start server: %v", err)
+	}
+	cli := db.NewMysqlClient(s.Config(), opts...)
+	t.Cleanup(func() {
+		_ = cli.Db.Close()
+		_ = s.Close()
+	})
+	if err := Exec(context.Background(), cli, fixtures...); err != nil {
+		t.Fatal(err)
+	}
+	return cli
+}
@@ -0,0 +1,162 @@
+package mysqltest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+	"github.com/preceeder/db/migrations"
+)
+
+const fixture = `
+CREATE TABLE t_user (
+  id BIGINT NOT NULL AUTO_INCREMENT,
+  name VARCHAR(64) NOT NULL,
+  age INT NOT NULL DEFAULT 0,
+  version INT NOT NULL DEFAULT 0,
+  PRIMARY KEY (id)
+);
+INSERT INTO t_user (name, age) VALUES ('a', 18), ('b', 20);
+`
+
+type user struct {
+	Id      int64  `db:"id"`
+	Name    string `db:"name"`
+	Age     int    `db:"age"`
+	Version int    `db:"version"`
+}
+
+func TestClient_Builder(t *testing.T) {
+	cli := NewClient(t, fixture)
+	ctx := context.Background()
+
+	tb := builder.Table("t_user")
+	var u user
+	if err := cli.QueryByBuilder(ctx, tb.Where(tb.Field("name").Eq("b", "name")).First(), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Id != 2 || u.Age != 20 {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	rs, err := cli.ExecByBuilder(ctx, builder.Table("t_user").InsertMap(map[string]any{"name": "c", "age": 30}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := rs.LastInsertId(); id != 3 {
+		t.Errorf("LastInsertId = %d", id)
+	}
+
+	upd := builder.Table("t_user")
+	if _, err := cli.ExecExpect(ctx, upd.Where(upd.Field("age").Gte(20, "min_age")).UpdateMap(map[string]any{"age": 40}), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []user
+	list := builder.Table("t_user")
+	if err := cli.FetchByBuilder(ctx, list.Select(list.Field("id"), list.Field("name"), list.Field("age")).Where(list.Field("age").Eq(40, "want_age")).Order(list.Field("id").Asc()), &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "b" || users[1].Name != "c" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	// 乐观锁：版本不匹配时返回 ErrStaleObject
+	ver := builder.Table("t_user")
+	_, err = cli.ExecByBuilder(ctx, ver.Where(ver.Field("id").Eq(1, "id")).UpdateMap(map[string]any{"age": 1}).Version("version", 5))
+	if !errors.Is(err, db.ErrStaleObject) {
+		t.Fatalf("expected ErrStaleObject, got %v", err)
+	}
+}
+
+func TestClient_Transaction(t *testing.T) {
+	cli := NewClient(t, fixture)
+	ctx := context.Background()
+	count := func() int {
+		var n []int
+		if err := cli.QueryRaw(ctx, &n, "SELECT COUNT(*) FROM t_user", nil); err != nil {
+			t.Fatal(err)
+		}
+		return n[0]
+	}
+
+	errRollback := errors.New("rollback")
+	err := cli.Transaction(ctx, func(ctx context.Context, m db.MysqlClient, tx *sqlx.Tx) error {
+		if _, err := m.ExecByBuilder(ctx, builder.Table("t_user").InsertMap(map[string]any{"name": "x"}), tx); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) || count() != 2 {
+		t.Fatalf("expected rollback, err=%v count=%d", err, count())
+	}
+
+	err = cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		_, err := ex.ExecByBuilder(ctx, builder.Table("t_user").InsertMap(map[string]any{"name": "y"}))
+		return err
+	})
+	if err != nil || count() != 3 {
+		t.Fatalf("expected commit, err=%v count=%d", err, count())
+	}
+}
+
+func TestServer_DSN(t *testing.T) {
+	s, err := Start("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn, err := sqlx.Connect("mysql", s.DSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var name string
+	if err := conn.Get(&name, "SELECT DATABASE()"); err != nil || name != DefaultDatabase {
+		t.Fatalf("DATABASE() = %q, err = %v", name, err)
+	}
+}
+
+func TestClient_Migrations(t *testing.T) {
+	var logs bytes.Buffer
+	cli := NewClientWithOptions(t, []db.Option{db.WithLogger(db.LoggerConfig{Handler: slog.NewJSONHandler(&logs, nil)})})
+	ctx := context.Background()
+	m, err := migrations.New(cli, []migrations.Migration{
+		{Version: 1, Name: "create_user", UpSQL: "CREATE TABLE t_user (id BIGINT PRIMARY KEY, name VARCHAR(64))", DownSQL: "DROP TABLE t_user"},
+		{Version: 2, Name: "add_age", UpSQL: builder.AlterTable("t_user").AddColumn(builder.Col("age", "INT").NotNull().Default(0)).Sql(),
+			DownSQL: builder.AlterTable("t_user").DropColumn("age").Sql()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 迁移日志通过客户端的日志输出
+	if out := logs.String(); strings.Count(out, `"msg":"migration applied"`) != 2 || !strings.Contains(out, `"db.event":"client"`) {
+		t.Fatalf("logs = %s", out)
+	}
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 2 || !status[0].Applied || !status[1].Applied {
+		t.Fatalf("status = %+v, err = %v", status, err)
+	}
+	schema, err := cli.RefreshSchema(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb, ok := schema.Table("t_user"); !ok || len(tb.Columns) != 3 {
+		t.Fatalf("unexpected schema: %+v", tb)
+	}
+	if err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := m.Status(ctx); status[1].Applied {
+		t.Fatalf("expected version 2 to be rolled back: %+v", status)
+	}
+}
@@ -0,0 +1,191 @@
+// Package outbox 实现事务性发件箱（transactional outbox）：
+// 业务事务内把领域事件写入发件箱表，由 Relay 在事务提交后异步投递到消息系统，保证“数据变更”与“事件发布”同时成功或同时失败
+//
+//	ob := outbox.New(cli)
+//	err := cli.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+//		... // 业务写入
+//		return ob.Enqueue(ctx, ex, outbox.Message{AggregateKey: "order:42", Topic: "order.paid", Payload: data})
+//	})
+//
+//	relay := ob.Relay(publisher, outbox.RelayConfig{})
+//	go relay.Run(ctx)
+//
+// 投递语义为至少一次（at least once），消费方需要按 Event.Id 幂等处理
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+)
+
+// 事件状态
+const (
+	StatusPending    = 0 // 等待投递（包括等待重试）
+	StatusDispatched = 1 // 已投递
+	StatusDead       = 2 // 超过最大重试次数，进入死信
+)
+
+// Message 待写入发件箱的事件
+type Message struct {
+	// AggregateKey 聚合键，同一聚合键的事件按写入顺序逐个投递，前一个投递成功（或进入死信）后才会投递下一个
+	AggregateKey string
+	Topic        string
+	Payload      []byte
+	Headers      map[string]string
+}
+
+// Event 从发件箱读出的事件
+type Event struct {
+	Message
+	Id        int64
+	Attempts  int // 之前失败的次数
+	CreatedAt time.Time
+}
+
+// Outbox 发件箱
+type Outbox struct {
+	client *db.MysqlClient
+	table  string
+	now    func() time.Time
+}
+
+// Option 发件箱选项
+type Option func(*Outbox)
+
+// WithTable 指定发件箱表名，默认 outbox_events
+func WithTable(name string) Option {
+	return func(o *Outbox) {
+		o.table = name
+	}
+}
+
+// WithClock 指定获取当前时间的函数，默认 time.Now，用于测试
+func WithClock(now func() time.Time) Option {
+	return func(o *Outbox) {
+		o.now = now
+	}
+}
+
+// New 创建发件箱
+func New(client *db.MysqlClient, opts ...Option) *Outbox {
+	o := &Outbox{client: client, table: "outbox_events", now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Table 返回发件箱表名
+func (o *Outbox) Table() string {
+	return o.table
+}
+
+// from 发件箱表的构建器；发件箱是内部表，不应用软删除与租户作用域
+func (o *Outbox) from() *builder.SqlBuilder {
+	return builder.Table(o.table).WithTrashed().CrossTenant()
+}
+
+// CreateTableSQL 返回发件箱表的建表语句
+func (o *Outbox) CreateTableSQL() string {
+	return builder.CreateTable(o.table).IfNotExists().
+		Column(builder.Col("id", "bigint").Unsigned().NotNull().AutoIncrement()).
+		Column(builder.Col("aggregate_key", "varchar(191)").NotNull()).
+		Column(builder.Col("topic", "varchar(191)").NotNull()).
+		Column(builder.Col("payload", "mediumblob").NotNull()).
+		Column(builder.Col("headers", "json").Null()).
+		Column(builder.Col("status", "tinyint").NotNull().Default(StatusPending).Comment("0 待投递 1 已投递 2 死信")).
+		Column(builder.Col("attempts", "int").NotNull().Default(0)).
+		Column(builder.Col("last_error", "varchar(1024)").Null()).
+		Column(builder.Col("available_at", "datetime(3)").NotNull().Comment("下一次可投递的时间（UTC）")).
+		Column(builder.Col("created_at", "datetime(3)").NotNull()).
+		Column(builder.Col("dispatched_at", "datetime(3)").Null()).
+		PrimaryKey("id").
+		Index(builder.Index("idx_status_available", "status", "available_at", "id")).
+		Index(builder.Index("idx_aggregate_status", "aggregate_key", "status", "id")).
+		Engine("InnoDB").
+		Charset("utf8mb4").
+		Sql()
+}
+
+// Enqueue 写入事件；ex 应当是事务内的 Executor（RunInTx 的回调参数，或 db.NewTxExecutor(m, tx)），
+// 这样事件与业务数据在同一事务中提交
+func (o *Outbox) Enqueue(ctx context.Context, ex db.Executor, msgs ...Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	now := o.now().UTC()
+	rows := make([]map[string]any, len(msgs))
+	for i, m := range msgs {
+		if m.Topic == "" {
+			return errors.New("outbox: message without topic")
+		}
+		var headers any
+		if len(m.Headers) > 0 {
+			data, err := json.Marshal(m.Headers)
+			if err != nil {
+				return err
+			}
+			headers = string(data)
+		}
+		payload := m.Payload
+		if payload == nil {
+			payload = []byte{}
+		}
+		rows[i] = map[string]any{
+			"aggregate_key": m.AggregateKey,
+			"topic":         m.Topic,
+			"payload":       payload,
+			"headers":       headers,
+			"status":        StatusPending,
+			"attempts":      0,
+			"available_at":  now,
+			"created_at":    now,
+		}
+	}
+	_, err := ex.ExecByBuilder(ctx, o.from().InsertMany(rows).WithoutTimestamps())
+	return err
+}
+
+// Requeue 将死信事件重新放回待投递状态（重置重试次数），返回影响的行数
+func (o *Outbox) Requeue(ctx context.Context, ids ...int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	tb := o.from()
+	rs, err := o.client.ExecByBuilder(ctx, tb.Where(
+		tb.Field("id").In(ids, "ids"),
+		tb.Field("status").Eq(StatusDead, "dead_status"),
+	).UpdateMap(map[string]any{
+		"status":       StatusPending,
+		"attempts":     0,
+		"available_at": o.now().UTC(),
+	}).WithoutTimestamps())
+	if err != nil {
+		return 0, err
+	}
+	return rs.RowsAffected()
+}
+
+// Counts 按状态统计事件数
+func (o *Outbox) Counts(ctx context.Context) (map[int]int64, error) {
+	var rows []struct {
+		Status int   `db:"status"`
+		Count  int64 `db:"cnt"`
+	}
+	query := fmt.Sprintf("SELECT status, COUNT(*) AS cnt FROM `%s` GROUP BY status", o.table)
+	if err := o.client.QueryRaw(ctx, &rows, query, nil); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, r := range rows {
+		counts[r.Status] = r.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher 内存中的 Publisher，用于测试
+type MemoryPublisher struct {
+	// Fail 不为 nil 且返回错误时投递失败，用于测试重试与死信
+	Fail func(e Event) error
+
+	mu     sync.Mutex
+	events []Event
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, e Event) error {
+	if p.Fail != nil {
+		if err := p.Fail(e); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
+
+// Events 返回已投递的事件（按投递顺序）
+func (p *MemoryPublisher) Events() []Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Event(nil), p.events...)
+}
+
+// Reset 清空已投递的事件
+func (p *MemoryPublisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = nil
+}
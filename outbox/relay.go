@@ -0,0 +1,264 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+)
+
+// Publisher 将事件投递到消息系统；返回错误时事件按退避策略重试
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// PublisherFunc 函数形式的 Publisher
+type PublisherFunc func(ctx context.Context, e Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// RelayConfig Relay 配置，零值字段使用默认值
+type RelayConfig struct {
+	BatchSize    int           // 每批最多投递的事件数，默认 100
+	PollInterval time.Duration // 没有待投递事件时的轮询间隔，默认 1s
+	MaxAttempts  int           // 最大投递次数，达到后进入死信，默认 10
+	// Backoff 第 attempts 次失败后到下一次重试的间隔，默认从 1s 开始指数增长，最长 5m
+	Backoff func(attempts int) time.Duration
+	// OnDeadLetter 事件进入死信时调用
+	OnDeadLetter func(ctx context.Context, e Event, err error)
+}
+
+// Relay 轮询发件箱并投递事件，多个实例可以并发运行：
+// 每批在一个事务中以 SELECT ... FOR UPDATE SKIP LOCKED 锁定事件，
+// 并且只选取每个聚合键最早的待投递事件，保证同一聚合键按顺序投递
+type Relay struct {
+	outbox    *Outbox
+	publisher Publisher
+	cfg       RelayConfig
+}
+
+// Relay 创建投递器
+func (o *Outbox) Relay(publisher Publisher, cfg RelayConfig) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = defaultBackoff
+	}
+	return &Relay{outbox: o, publisher: publisher, cfg: cfg}
+}
+
+func defaultBackoff(attempts int) time.Duration {
+	d := time.Second
+	for i := 1; i < attempts && d < 5*time.Minute; i++ {
+		d *= 2
+	}
+	return min(d, 5*time.Minute)
+}
+
+// Run 持续投递直到 ctx 结束，返回 ctx.Err()
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.outbox.client.Log(ctx, db.LogClient, slog.LevelError, "outbox relay failed",
+				slog.String(db.AttrTable, r.outbox.table), slog.String("error", err.Error()))
+		}
+		if err == nil && n > 0 {
+			continue
+		}
+		timer := time.NewTimer(r.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// eventRow 发件箱表的一行
+type eventRow struct {
+	Id           int64   `db:"id"`
+	AggregateKey string  `db:"aggregate_key"`
+	Topic        string  `db:"topic"`
+	Payload      []byte  `db:"payload"`
+	Headers      *string `db:"headers"`
+	Attempts     int     `db:"attempts"`
+	CreatedAt    dbTime  `db:"created_at"`
+}
+
+// RelayOnce 投递一批事件，返回处理（成功、失败或进入死信）的事件数
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	o := r.outbox
+	var n int
+	err := o.client.RunInTx(ctx, func(ctx context.Context, ex db.Executor) error {
+		var rows []eventRow
+		pending, err := r.pendingQuery(ctx)
+		if err != nil {
+			return err
+		}
+		if err := ex.FetchByBuilder(ctx, pending, &rows); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			e := r.event(ctx, row)
+			if err := r.dispatch(ctx, ex, e); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// pendingQuery 每个聚合键最早的、已到重试时间的待投递事件
+func (r *Relay) pendingQuery(ctx context.Context) (*builder.SqlBuilder, error) {
+	o := r.outbox
+	tb := o.from().As("e")
+	prev := o.from().As("p")
+	earlier, _, err := prev.Select(builder.NewField("1")).Where(
+		prev.Field("aggregate_key").Eq(tb.Field("aggregate_key")),
+		prev.Field("status").Eq(StatusPending),
+		prev.Field("id").Lt(tb.Field("id")),
+	).SqlContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tb.Select(
+		tb.Field("id"), tb.Field("aggregate_key"), tb.Field("topic"), tb.Field("payload"),
+		tb.Field("headers"), tb.Field("attempts"), tb.Field("created_at"),
+	).Where(
+		tb.Field("status").Eq(StatusPending, "pending_status"),
+		tb.Field("available_at").Lte(o.now().UTC(), "available_before"),
+		builder.NotExists(earlier),
+	).Order(tb.Field("id").Asc()).Limit(r.cfg.BatchSize).SkipLocked(), nil
+}
+
+// dispatch 投递单个事件并更新状态；只有更新发件箱失败时返回错误
+func (r *Relay) dispatch(ctx context.Context, ex db.Executor, e Event) error {
+	o := r.outbox
+	now := o.now().UTC()
+	pubErr := r.publish(ctx, e)
+	set := map[string]any{}
+	switch {
+	case pubErr == nil:
+		set["status"] = StatusDispatched
+		set["dispatched_at"] = now
+	case e.Attempts+1 >= r.cfg.MaxAttempts:
+		set["status"] = StatusDead
+		set["attempts"] = e.Attempts + 1
+		set["last_error"] = truncate(pubErr.Error(), 1024)
+		o.client.Log(ctx, db.LogClient, slog.LevelError, "outbox event dead-lettered", r.eventAttrs(e, pubErr)...)
+	default:
+		set["attempts"] = e.Attempts + 1
+		set["last_error"] = truncate(pubErr.Error(), 1024)
+		set["available_at"] = now.Add(r.cfg.Backoff(e.Attempts + 1))
+		o.client.Log(ctx, db.LogClient, slog.LevelWarn, "outbox publish failed", r.eventAttrs(e, pubErr)...)
+	}
+	tb := o.from()
+	if _, err := ex.ExecExpect(ctx, tb.Where(tb.Field("id").Eq(e.Id, "event_id")).UpdateMap(set).WithoutTimestamps(), 1); err != nil {
+		return err
+	}
+	if set["status"] == StatusDead && r.cfg.OnDeadLetter != nil {
+		// 在事务提交、死信状态落库后回调
+		hook := func(ctx context.Context) { r.cfg.OnDeadLetter(ctx, e, pubErr) }
+		if err := db.OnCommit(ctx, hook); err != nil {
+			hook(ctx)
+		}
+	}
+	return nil
+}
+
+// publish 调用 Publisher，panic 视为投递失败
+func (r *Relay) publish(ctx context.Context, e Event) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("outbox: publisher panic: %v", p)
+		}
+	}()
+	return r.publisher.Publish(ctx, e)
+}
+
+func (r *Relay) eventAttrs(e Event, err error) []slog.Attr {
+	return []slog.Attr{
+		slog.String(db.AttrTable, r.outbox.table),
+		slog.Int64("id", e.Id),
+		slog.String("topic", e.Topic),
+		slog.Int("attempts", e.Attempts+1),
+		slog.String("error", err.Error()),
+	}
+}
+
+func (r *Relay) event(ctx context.Context, row eventRow) Event {
+	e := Event{
+		Message: Message{
+			AggregateKey: row.AggregateKey,
+			Topic:        row.Topic,
+			Payload:      row.Payload,
+		},
+		Id:        row.Id,
+		Attempts:  row.Attempts,
+		CreatedAt: time.Time(row.CreatedAt),
+	}
+	if row.Headers != nil && *row.Headers != "" {
+		if err := json.Unmarshal([]byte(*row.Headers), &e.Headers); err != nil {
+			r.outbox.client.Log(ctx, db.LogClient, slog.LevelWarn, "outbox bad headers",
+				slog.String(db.AttrTable, r.outbox.table), slog.Int64("id", row.Id), slog.String("error", err.Error()))
+		}
+	}
+	return e
+}
+
+// truncate 截断到最多 n 字节，不拆分多字节字符（否则严格模式下写入 last_error 会失败）
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// dbTime 兼容 parseTime=true（time.Time）与默认（字符串）两种返回形式的 DATETIME，按 UTC 解析
+type dbTime time.Time
+
+func (t *dbTime) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = dbTime{}
+	case time.Time:
+		*t = dbTime(v)
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("outbox: cannot scan %T into time", src)
+	}
+	return nil
+}
+
+func (t *dbTime) parse(s string) error {
+	v, err := time.ParseInLocation(time.DateTime, s, time.UTC)
+	if err != nil {
+		return err
+	}
+	*t = dbTime(v)
+	return nil
+}
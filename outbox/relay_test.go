@@ -0,0 +1,18 @@
+package outbox
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	s := strings.Repeat("错", 400) // 每个字符 3 字节
+	got := truncate(s, 1024)
+	if !utf8.ValidString(got) || len(got) != 1023 {
+		t.Fatalf("truncate = %d bytes, valid = %v", len(got), utf8.ValidString(got))
+	}
+	if truncate("abc", 1024) != "abc" {
+		t.Fatal("short string changed")
+	}
+}
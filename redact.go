@@ -0,0 +1,320 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RedactionPolicy 日志脱敏策略，作用于日志中的 SQL 语句与参数；审计记录默认不脱敏，见 AuditConfig.Redaction
+// 零值不做任何处理，默认使用 DefaultRedactionPolicy
+type RedactionPolicy struct {
+	// Columns 需要隐藏的列名（参数名）模式，不区分大小写；
+	// 含有 * ? [ 时按 path.Match 匹配整个名称，否则按子串匹配（例如 phone 匹配 user_phone）
+	Columns []string
+	// Types 需要隐藏的值类型，例如 reflect.TypeFor[Password]()
+	Types []reflect.Type
+	// MaxStringLen 字符串超过该长度（字节）时截断，0 表示不截断
+	MaxStringLen int
+	// MaxBytesLen []byte 超过该长度时只记录长度，0 表示不处理
+	MaxBytesLen int
+	// Mask 替换被隐藏的值，默认 ***
+	Mask string
+}
+
+// DefaultRedactionPolicy 默认脱敏策略
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		Columns:      []string{"password", "passwd", "pwd", "secret", "token", "api_key", "id_card", "idcard", "phone", "mobile"},
+		MaxStringLen: 256,
+		MaxBytesLen:  64,
+	}
+}
+
+var defaultRedaction = DefaultRedactionPolicy()
+
+// WithRedaction 设置日志脱敏策略，传入 RedactionPolicy{} 关闭脱敏
+func WithRedaction(p RedactionPolicy) Option {
+	return func(c *MysqlClient) {
+		c.redaction = &p
+	}
+}
+
+// redactor 返回客户端使用的脱敏策略
+func (s MysqlClient) redactor() *RedactionPolicy {
+	if s.redaction != nil {
+		return s.redaction
+	}
+	return &defaultRedaction
+}
+
+// Params 返回脱敏后的命名参数，不修改 params
+func (p *RedactionPolicy) Params(params map[string]any) map[string]any {
+	if params == nil {
+		return nil
+	}
+	out := make(map[string]any, len(params))
+	for k, v := range params {
+		out[k] = p.Value(k, v)
+	}
+	return out
+}
+
+// Args 返回脱敏后的位置参数，不修改 args
+// 参数名从 query 中推断：`col` = ?、`col` IN (?, ?) 以及 INSERT INTO t (a, b) VALUES (?, ?)，推断不出时只按类型与长度处理
+func (p *RedactionPolicy) Args(query string, args []any) []any {
+	if args == nil {
+		return nil
+	}
+	var names []string
+	if len(p.Columns) > 0 {
+		names = placeholderNames(query, len(args))
+	}
+	out := make([]any, len(args))
+	for i, v := range args {
+		var name string
+		if i < len(names) {
+			name = names[i]
+		}
+		out[i] = p.Value(name, v)
+	}
+	return out
+}
+
+// Statement 返回隐藏了敏感列字面量的 SQL，不修改 query
+// builder 会把未命名的值直接写入 SQL（例如 `password` = 'x'），这里把与敏感列比较的引号字面量替换为 Mask，
+// 包括 `col` IN ('a', 'b') 中的每一项
+func (p *RedactionPolicy) Statement(query string) string {
+	if len(p.Columns) == 0 {
+		return query
+	}
+	var out strings.Builder
+	last := 0                   // query 中已写入 out 的位置
+	listEnd, listName := -1, "" // 敏感列 IN 列表中上一个字面量的结束位置与列名
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == '`' {
+			if j := strings.IndexByte(query[i+1:], '`'); j >= 0 {
+				i += j + 1
+			}
+			continue
+		}
+		if c != '\'' && c != '"' {
+			continue
+		}
+		end := literalEnd(query, i)
+		mask := false
+		if listName != "" && strings.TrimSpace(query[listEnd:i]) == "," {
+			mask = true
+		} else if m := compareColumnRe.FindStringSubmatch(query[:i]); m != nil && p.matchColumn(m[1]) {
+			mask = true
+			listName = ""
+			if strings.HasSuffix(m[0], "(") {
+				listName = m[1]
+			}
+		} else {
+			listName = ""
+		}
+		if mask {
+			out.WriteString(query[last:i])
+			out.WriteByte(c)
+			out.WriteString(p.mask())
+			out.WriteByte(c)
+			last = end
+		}
+		listEnd = end
+		i = end - 1
+	}
+	if last == 0 {
+		return query
+	}
+	out.WriteString(query[last:])
+	return out.String()
+}
+
+// literalEnd 返回从 start 开始的引号字面量结束后的位置，支持反斜杠转义与连续两个引号
+func literalEnd(query string, start int) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// Value 按列名与值类型脱敏单个值
+func (p *RedactionPolicy) Value(name string, v any) any {
+	if v == nil {
+		return nil
+	}
+	if name != "" && p.matchColumn(name) {
+		return p.mask()
+	}
+	if len(p.Types) > 0 {
+		t := reflect.TypeOf(v)
+		for _, rt := range p.Types {
+			if t == rt || (t.Kind() == reflect.Pointer && t.Elem() == rt) {
+				return p.mask()
+			}
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		return p.truncate(val)
+	case []byte:
+		if p.MaxBytesLen > 0 && len(val) > p.MaxBytesLen {
+			return fmt.Sprintf("[%d bytes]", len(val))
+		}
+		if utf8.Valid(val) {
+			return p.truncate(string(val))
+		}
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = p.Value("", item)
+		}
+		return out
+	case map[string]any:
+		return p.Params(val)
+	}
+	return v
+}
+
+func (p *RedactionPolicy) mask() string {
+	if p.Mask != "" {
+		return p.Mask
+	}
+	return "***"
+}
+
+func (p *RedactionPolicy) truncate(s string) string {
+	if p.MaxStringLen <= 0 || len(s) <= p.MaxStringLen {
+		return s
+	}
+	cut := p.MaxStringLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(%d bytes)", s[:cut], len(s))
+}
+
+func (p *RedactionPolicy) matchColumn(name string) bool {
+	name = strings.ToLower(strings.Trim(name, "`"))
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, pattern := range p.Columns {
+		pattern = strings.ToLower(pattern)
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		} else if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	insertColumnsRe = regexp.MustCompile("(?is)^\\s*(?:INSERT|REPLACE)\\s+(?:IGNORE\\s+)?(?:INTO\\s+)?[`\\w.]+\\s*\\(([^)]*)\\)\\s*VALUES")
+	compareColumnRe = regexp.MustCompile("(?i)([`\\w.]+)\\s*(?:=|<=>|!=|<>|<=|>=|<|>|\\bLIKE|\\bIN\\s*\\()\\s*$")
+)
+
+// placeholderNames 尽量推断每个 ? 对应的列名，推断不出的为空字符串
+func placeholderNames(query string, n int) []string {
+	names := make([]string, 0, n)
+	var insertCols []string
+	valuesAt := -1
+	if m := insertColumnsRe.FindStringSubmatchIndex(query); m != nil {
+		for _, col := range strings.Split(query[m[2]:m[3]], ",") {
+			insertCols = append(insertCols, strings.Trim(strings.TrimSpace(col), "`"))
+		}
+		valuesAt = m[1]
+	}
+	var quote byte
+	depth, col := 0, 0 // VALUES 中的括号层级与当前元组中的列序号
+	for i := 0; i < len(query) && len(names) < n; i++ {
+		c := query[i]
+		inValues := valuesAt >= 0 && i >= valuesAt
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case inValues && c == '(':
+			if depth++; depth == 1 {
+				col = 0
+			}
+		case inValues && c == ')':
+			if depth--; depth == 0 && strings.HasPrefix(strings.ToUpper(strings.TrimLeft(query[i+1:], " ")), "ON DUPLICATE") {
+				// ON DUPLICATE KEY UPDATE 部分按比较运算推断
+				valuesAt = -1
+			}
+		case inValues && c == ',' && depth == 1:
+			col++
+		case c == '?':
+			name := ""
+			if inValues && depth > 0 {
+				if col < len(insertCols) {
+					name = insertCols[col]
+				}
+			} else if m := compareColumnRe.FindStringSubmatch(inListPrefix(query[:i])); m != nil {
+				name = m[1]
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// inListPrefix 对 IN (?, ?, ?) 中后面的 ?，回退到 IN ( 处，使其与第一个 ? 对应同一列
+func inListPrefix(prefix string) string {
+	trimmed := strings.TrimRight(prefix, " ")
+	for strings.HasSuffix(trimmed, ",") {
+		trimmed = strings.TrimRight(strings.TrimSuffix(trimmed, ","), " ")
+		if !strings.HasSuffix(trimmed, "?") {
+			return prefix
+		}
+		trimmed = strings.TrimRight(strings.TrimSuffix(trimmed, "?"), " ")
+	}
+	return trimmed
+}
+
+// LogValue 日志中隐藏密码
+func (config MysqlConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", config.Host),
+		slog.String("port", config.Port),
+		slog.String("user", config.User),
+		slog.String("password", "***"),
+		slog.String("database", config.Database),
+		slog.Int("maxOpenCons", config.MaxOpenCons),
+		slog.Int("maxIdleCons", config.MaxIdleCons),
+		slog.String("params", config.Params),
+	)
+}
+
+// redactedDSN 隐藏密码的连接串，用于日志
+func (config MysqlConfig) redactedDSN() string {
+	if config.Password != "" {
+		config.Password = "***"
+	}
+	return config.dsn()
+}
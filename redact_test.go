@@ -0,0 +1,119 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/preceeder/db/builder"
+)
+
+func TestRedactionPolicy_Params(t *testing.T) {
+	p := DefaultRedactionPolicy()
+	got := p.Params(map[string]any{
+		"name":        "nick",
+		"password":    "s3cret",
+		"user_phone":  "13800000000",
+		"bio":         strings.Repeat("中", 100),
+		"avatar":      bytes.Repeat([]byte{0xff}, 100),
+		"ids":         []any{1, 2},
+		"new_Token_1": "abc",
+	})
+	if got["name"] != "nick" || got["password"] != "***" || got["user_phone"] != "***" || got["new_Token_1"] != "***" {
+		t.Fatalf("unexpected: %v", got)
+	}
+	if bio := got["bio"].(string); !strings.HasSuffix(bio, "...(300 bytes)") || !strings.HasPrefix(bio, strings.Repeat("中", 85)) {
+		t.Fatalf("bio not truncated on rune boundary: %q", bio)
+	}
+	if got["avatar"] != "[100 bytes]" {
+		t.Fatalf("avatar = %v", got["avatar"])
+	}
+	if !reflect.DeepEqual(got["ids"], []any{1, 2}) {
+		t.Fatalf("ids = %v", got["ids"])
+	}
+}
+
+type secretKey string
+
+func TestRedactionPolicy_TypesAndPatterns(t *testing.T) {
+	p := RedactionPolicy{Columns: []string{"card_*"}, Types: []reflect.Type{reflect.TypeFor[secretKey]()}, Mask: "<hidden>"}
+	if v := p.Value("key", secretKey("k")); v != "<hidden>" {
+		t.Fatalf("type not redacted: %v", v)
+	}
+	k := secretKey("k")
+	if v := p.Value("key", &k); v != "<hidden>" {
+		t.Fatalf("pointer type not redacted: %v", v)
+	}
+	if v := p.Value("card_no", "6222"); v != "<hidden>" {
+		t.Fatalf("glob not matched: %v", v)
+	}
+	if v := p.Value("my_card_no", "6222"); v != "6222" {
+		t.Fatalf("glob should match whole name: %v", v)
+	}
+	// 零值策略不做任何处理
+	long := strings.Repeat("x", 1000)
+	if v := (&RedactionPolicy{}).Value("password", long); v != long {
+		t.Fatal("zero policy changed value")
+	}
+}
+
+func TestRedactionPolicy_Args(t *testing.T) {
+	p := DefaultRedactionPolicy()
+	cases := []struct {
+		query string
+		args  []any
+		want  []any
+	}{
+		{"SELECT * FROM u WHERE name = ? AND `password` = ?", []any{"a", "b"}, []any{"a", "***"}},
+		{"SELECT * FROM u WHERE u.phone IN (?, ?, ?) AND age > ?", []any{"1", "2", "3", 4}, []any{"***", "***", "***", 4}},
+		{"INSERT INTO u (`name`, `pwd`, created_at, token) VALUES (?, ?, NOW(), ?), (?, ?, NOW(), ?)", []any{"a", "b", "c", "d", "e", "f"}, []any{"a", "***", "***", "d", "***", "***"}},
+		{"INSERT INTO u (name, secret) VALUES (?, ?) ON DUPLICATE KEY UPDATE secret = ?, name = ?", []any{"a", "b", "c", "d"}, []any{"a", "***", "***", "d"}},
+		{"UPDATE u SET note = 'a = ?', password = ? WHERE id = ?", []any{"x", 1}, []any{"***", 1}},
+		{"SELECT CONCAT(?, ?)", []any{"a", "b"}, []any{"a", "b"}},
+	}
+	for _, c := range cases {
+		if got := p.Args(c.query, c.args); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s\n got %v\nwant %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestRedactionPolicy_Statement(t *testing.T) {
+	p := DefaultRedactionPolicy()
+	tb := builder.Table("user")
+	q, _, err := tb.Select(tb.Field("id")).Where(tb.Field("password").Eq("hunter2"), tb.Field("name").Eq("bob")).SqlContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Statement(q); strings.Contains(got, "hunter2") || !strings.Contains(got, "`user`.`password` = '***'") || !strings.Contains(got, "'bob'") {
+		t.Fatalf("statement = %s", got)
+	}
+	cases := []struct{ query, want string }{
+		{"SELECT * FROM u WHERE phone IN ('1', \"2\") AND name IN ('a', 'b')", "SELECT * FROM u WHERE phone IN ('***', \"***\") AND name IN ('a', 'b')"},
+		{"UPDATE u SET token = 'it''s', note = 'x' WHERE `secret` LIKE 'a\\'b%'", "UPDATE u SET token = '***', note = 'x' WHERE `secret` LIKE '***'"},
+		{"SELECT 'password = x' FROM u", "SELECT 'password = x' FROM u"},
+	}
+	for _, c := range cases {
+		if got := p.Statement(c.query); got != c.want {
+			t.Errorf("%s\n got %s\nwant %s", c.query, got, c.want)
+		}
+	}
+	if q := "SELECT * FROM u WHERE password = 'x'"; (&RedactionPolicy{}).Statement(q) != q {
+		t.Fatal("zero policy changed statement")
+	}
+}
+
+func TestMysqlConfig_HidesPassword(t *testing.T) {
+	config := MysqlConfig{Host: "127.0.0.1", Port: "3306", User: "root", Password: "p@ss", Database: "app"}
+	if dsn := config.redactedDSN(); strings.Contains(dsn, "p@ss") || dsn != "root:***@tcp(127.0.0.1:3306)/app" {
+		t.Fatalf("dsn = %s", dsn)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("close", "config", config)
+	if strings.Contains(buf.String(), "p@ss") || !strings.Contains(buf.String(), `"database":"app"`) {
+		t.Fatalf("log = %s", buf.String())
+	}
+}
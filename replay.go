@@ -0,0 +1,700 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrReplayMismatch 回放时遇到与录制不一致的语句（多出、缺少或顺序不同）
+var ErrReplayMismatch = errors.New("mdb: replay mismatch")
+
+// 录制/回放在 database/sql 驱动层进行，所以 Builder、Raw、事务等所有方法都会被覆盖。
+// 录制文件每行一个 JSON（语句、参数、结果或错误），回放时严格按顺序匹配。
+// 注意：
+//   - 语句中包含 time.Now()、随机值等非确定参数时，回放会不一致
+//   - 并发执行的语句顺序不确定，录制与回放的用例需要串行执行
+
+// replayEntry 录制文件中的一条记录
+type replayEntry struct {
+	Seq          int           `json:"seq"`
+	Op           string        `json:"op"` // query、exec、begin、commit、rollback
+	Sql          string        `json:"sql,omitempty"`
+	Args         []replayVal   `json:"args,omitempty"`
+	Columns      []string      `json:"columns,omitempty"`
+	Rows         [][]replayVal `json:"rows,omitempty"`
+	LastInsertId int64         `json:"last_insert_id,omitempty"`
+	RowsAffected int64         `json:"rows_affected,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	ErrorNumber  uint16        `json:"error_number,omitempty"`
+}
+
+// replayVal 带类型的值，保证回放时 driver.Value 的类型与录制时一致
+type replayVal struct {
+	T string `json:"t"`
+	V string `json:"v,omitempty"`
+}
+
+func encodeVal(v any) replayVal {
+	switch val := v.(type) {
+	case nil:
+		return replayVal{T: "null"}
+	case []byte:
+		if utf8.Valid(val) {
+			return replayVal{T: "bytes", V: string(val)}
+		}
+		return replayVal{T: "base64", V: base64.StdEncoding.EncodeToString(val)}
+	case string:
+		return replayVal{T: "string", V: val}
+	case bool:
+		return replayVal{T: "bool", V: strconv.FormatBool(val)}
+	case time.Time:
+		return replayVal{T: "time", V: val.Format(time.RFC3339Nano)}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return replayVal{T: "int", V: strconv.FormatInt(rv.Int(), 10)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return replayVal{T: "int", V: strconv.FormatUint(rv.Uint(), 10)}
+	case reflect.Float32, reflect.Float64:
+		return replayVal{T: "float", V: strconv.FormatFloat(rv.Float(), 'g', -1, 64)}
+	}
+	return replayVal{T: "string", V: fmt.Sprint(v)}
+}
+
+func (r replayVal) decode() (driver.Value, error) {
+	switch r.T {
+	case "null":
+		return nil, nil
+	case "bytes":
+		return []byte(r.V), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(r.V)
+	case "string":
+		return r.V, nil
+	case "bool":
+		return strconv.ParseBool(r.V)
+	case "time":
+		return time.Parse(time.RFC3339Nano, r.V)
+	case "int":
+		if i, err := strconv.ParseInt(r.V, 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseUint(r.V, 10, 64)
+	case "float":
+		return strconv.ParseFloat(r.V, 64)
+	}
+	return nil, fmt.Errorf("mdb: unknown replay value type %q", r.T)
+}
+
+func encodeArgs(args []driver.NamedValue) []replayVal {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]replayVal, len(args))
+	for i, a := range args {
+		out[i] = encodeVal(a.Value)
+	}
+	return out
+}
+
+func (e *replayEntry) setError(err error) {
+	e.Error = err.Error()
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		e.ErrorNumber = mysqlErr.Number
+		e.Error = mysqlErr.Message
+	}
+}
+
+func (e *replayEntry) err() error {
+	if e.Error == "" {
+		return nil
+	}
+	if e.ErrorNumber != 0 {
+		return &mysql.MySQLError{Number: e.ErrorNumber, Message: e.Error}
+	}
+	return errors.New(e.Error)
+}
+
+// -------------------- 录制 --------------------
+
+// Recorder 录制器，写入 golden 文件
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int
+	err  error
+}
+
+// Path 返回录制文件路径
+func (r *Recorder) Path() string {
+	return r.file.Name()
+}
+
+// Err 返回录制过程中写文件的第一个错误
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close 关闭录制文件
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+func (r *Recorder) write(e replayEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.Seq = r.seq
+	line, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.file.Write(append(line, '\n'))
+	}
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// NewRecordingClient 连接 config 指定的数据库（真实或 mysqltest 内嵌库），并将执行的每条语句及结果录制到 path（覆盖写）
+func NewRecordingClient(config MysqlConfig, path string, opts ...Option) (*MysqlClient, *Recorder, error) {
+	cfg, err := mysql.ParseDSN(config.dsn())
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec := &Recorder{file: file}
+	sqlDb := sql.OpenDB(&recordConnector{base: base, rec: rec})
+	sqlDb.SetMaxOpenConns(config.MaxOpenCons)
+	sqlDb.SetMaxIdleConns(config.MaxIdleCons)
+	return newMysqlClient(config, sqlx.NewDb(sqlDb, "mysql"), opts...), rec, nil
+}
+
+type recordConnector struct {
+	base driver.Connector
+	rec  *Recorder
+}
+
+func (c *recordConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &recordConn{base: conn, rec: c.rec}, nil
+}
+
+func (c *recordConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+type recordConn struct {
+	base driver.Conn
+	rec  *Recorder
+}
+
+func (c *recordConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recordConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := prepareContext(ctx, c.base, query)
+	if err != nil {
+		return nil, err
+	}
+	return &recordStmt{base: stmt, query: query, rec: c.rec}, nil
+}
+
+func (c *recordConn) Close() error {
+	return c.base.Close()
+}
+
+func (c *recordConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if b, ok := c.base.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.base.Begin() //nolint:staticcheck
+	}
+	e := replayEntry{Op: "begin"}
+	if err != nil {
+		e.setError(err)
+	}
+	c.rec.write(e)
+	if err != nil {
+		return nil, err
+	}
+	return &recordTx{base: tx, rec: c.rec}, nil
+}
+
+func (c *recordConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := queryContext(ctx, c.base, query, args)
+	return c.rec.recordRows(query, args, rows, err)
+}
+
+func (c *recordConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rs, err := execContext(ctx, c.base, query, args)
+	return c.rec.recordResult(query, args, rs, err)
+}
+
+func (c *recordConn) Ping(ctx context.Context) error {
+	if p, ok := c.base.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *recordConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.base.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *recordConn) IsValid() bool {
+	if v, ok := c.base.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *recordConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.base.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type recordTx struct {
+	base driver.Tx
+	rec  *Recorder
+}
+
+func (t *recordTx) Commit() error {
+	err := t.base.Commit()
+	e := replayEntry{Op: "commit"}
+	if err != nil {
+		e.setError(err)
+	}
+	t.rec.write(e)
+	return err
+}
+
+func (t *recordTx) Rollback() error {
+	err := t.base.Rollback()
+	e := replayEntry{Op: "rollback"}
+	if err != nil {
+		e.setError(err)
+	}
+	t.rec.write(e)
+	return err
+}
+
+type recordStmt struct {
+	base  driver.Stmt
+	query string
+	rec   *Recorder
+}
+
+func (s *recordStmt) Close() error  { return s.base.Close() }
+func (s *recordStmt) NumInput() int { return s.base.NumInput() }
+
+func (s *recordStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *recordStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *recordStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	rs, err := stmtExec(ctx, s.base, args)
+	return s.rec.recordResult(s.query, args, rs, err)
+}
+
+func (s *recordStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := stmtQuery(ctx, s.base, args)
+	return s.rec.recordRows(s.query, args, rows, err)
+}
+
+// recordRows 读取全部行并录制，返回内存中的 Rows
+func (r *Recorder) recordRows(query string, args []driver.NamedValue, rows driver.Rows, err error) (driver.Rows, error) {
+	e := replayEntry{Op: "query", Sql: query, Args: encodeArgs(args)}
+	if err != nil {
+		e.setError(err)
+		r.write(e)
+		return nil, err
+	}
+	defer rows.Close()
+	e.Columns = rows.Columns()
+	mem := &memRows{columns: e.Columns}
+	for {
+		dest := make([]driver.Value, len(e.Columns))
+		if err := rows.Next(dest); err != nil {
+			if err != io.EOF {
+				e.setError(err)
+				r.write(e)
+				return nil, err
+			}
+			break
+		}
+		row := make([]replayVal, len(dest))
+		values := make([]driver.Value, len(dest))
+		for i, v := range dest {
+			// 驱动可能复用 []byte 缓冲区，需要复制
+			if b, ok := v.([]byte); ok {
+				v = append([]byte(nil), b...)
+			}
+			row[i] = encodeVal(v)
+			values[i] = v
+		}
+		e.Rows = append(e.Rows, row)
+		mem.rows = append(mem.rows, values)
+	}
+	r.write(e)
+	return mem, nil
+}
+
+func (r *Recorder) recordResult(query string, args []driver.NamedValue, rs driver.Result, err error) (driver.Result, error) {
+	e := replayEntry{Op: "exec", Sql: query, Args: encodeArgs(args)}
+	if err != nil {
+		e.setError(err)
+		r.write(e)
+		return nil, err
+	}
+	e.LastInsertId, _ = rs.LastInsertId()
+	e.RowsAffected, _ = rs.RowsAffected()
+	r.write(e)
+	return rs, nil
+}
+
+// -------------------- 回放 --------------------
+
+// Replayer 回放器，按顺序消费录制文件中的记录
+type Replayer struct {
+	mu      sync.Mutex
+	entries []replayEntry
+	next    int
+}
+
+// Remaining 返回尚未被回放的记录数
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries) - r.next
+}
+
+// Done 检查所有录制的语句都已被回放
+func (r *Replayer) Done() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next < len(r.entries) {
+		e := r.entries[r.next]
+		return fmt.Errorf("%w: %d recorded statements not replayed, next is #%d %s %s", ErrReplayMismatch, len(r.entries)-r.next, e.Seq, e.Op, e.Sql)
+	}
+	return nil
+}
+
+// take 取出下一条记录并与实际调用比较
+func (r *Replayer) take(op, query string, args []driver.NamedValue) (replayEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.entries) {
+		return replayEntry{}, fmt.Errorf("%w: unexpected %s %s (all %d recorded statements replayed)", ErrReplayMismatch, op, query, len(r.entries))
+	}
+	e := r.entries[r.next]
+	got := encodeArgs(args)
+	if e.Op != op || e.Sql != query || !reflect.DeepEqual(e.Args, got) {
+		return replayEntry{}, fmt.Errorf("%w: #%d expected %s %s %v, got %s %s %v", ErrReplayMismatch, e.Seq, e.Op, e.Sql, e.Args, op, query, got)
+	}
+	r.next++
+	return e, nil
+}
+
+// NewReplayClient 从 path 加载录制文件，返回不连接数据库的客户端；config 只用于 MysqlConfig 字段本身
+func NewReplayClient(config MysqlConfig, path string, opts ...Option) (*MysqlClient, *Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	rep := &Replayer{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e replayEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, nil, fmt.Errorf("mdb: bad replay entry %d: %w", len(rep.entries)+1, err)
+		}
+		rep.entries = append(rep.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	sqlDb := sql.OpenDB(&replayConnector{rep: rep})
+	return newMysqlClient(config, sqlx.NewDb(sqlDb, "mysql"), opts...), rep, nil
+}
+
+type replayConnector struct {
+	rep *Replayer
+}
+
+func (c *replayConnector) Connect(context.Context) (driver.Conn, error) {
+	return &replayConn{rep: c.rep}, nil
+}
+
+func (c *replayConnector) Driver() driver.Driver {
+	return &mysql.MySQLDriver{}
+}
+
+type replayConn struct {
+	rep *Replayer
+}
+
+func (c *replayConn) Prepare(query string) (driver.Stmt, error) {
+	return &replayStmt{conn: c, query: query}, nil
+}
+
+func (c *replayConn) Close() error { return nil }
+
+func (c *replayConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *replayConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	e, err := c.rep.take("begin", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.err(); err != nil {
+		return nil, err
+	}
+	return &replayTx{rep: c.rep}, nil
+}
+
+func (c *replayConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.rep.take("query", query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.err(); err != nil {
+		return nil, err
+	}
+	rows := &memRows{columns: e.Columns}
+	for _, row := range e.Rows {
+		values := make([]driver.Value, len(row))
+		for i, v := range row {
+			if values[i], err = v.decode(); err != nil {
+				return nil, err
+			}
+		}
+		rows.rows = append(rows.rows, values)
+	}
+	return rows, nil
+}
+
+func (c *replayConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.rep.take("exec", query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.err(); err != nil {
+		return nil, err
+	}
+	return replayResult{lastInsertId: e.LastInsertId, rowsAffected: e.RowsAffected}, nil
+}
+
+// CheckNamedValue 回放时不做驱动侧的参数转换，按录制的编码比较
+func (c *replayConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if valuer, ok := nv.Value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		nv.Value = v
+		return err
+	}
+	return nil
+}
+
+type replayTx struct {
+	rep *Replayer
+}
+
+func (t *replayTx) Commit() error {
+	e, err := t.rep.take("commit", "", nil)
+	if err != nil {
+		return err
+	}
+	return e.err()
+}
+
+func (t *replayTx) Rollback() error {
+	e, err := t.rep.take("rollback", "", nil)
+	if err != nil {
+		return err
+	}
+	return e.err()
+}
+
+type replayStmt struct {
+	conn  *replayConn
+	query string
+}
+
+func (s *replayStmt) Close() error  { return nil }
+func (s *replayStmt) NumInput() int { return -1 }
+
+func (s *replayStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *replayStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+type replayResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r replayResult) LastInsertId() (int64, error) { return r.lastInsertId, nil }
+func (r replayResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// memRows 内存中的结果集
+type memRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *memRows) Columns() []string { return r.columns }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+// -------------------- 驱动调用辅助 --------------------
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+func plainValues(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, a := range args {
+		out[i] = a.Value
+	}
+	return out
+}
+
+func prepareContext(ctx context.Context, conn driver.Conn, query string) (driver.Stmt, error) {
+	if p, ok := conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return conn.Prepare(query)
+}
+
+// queryContext 优先使用驱动的 QueryerContext，驱动返回 ErrSkip 时（例如未开启 interpolateParams）改用预处理语句
+func queryContext(ctx context.Context, conn driver.Conn, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := conn.(driver.QueryerContext); ok {
+		rows, err := q.QueryContext(ctx, query, args)
+		if !errors.Is(err, driver.ErrSkip) {
+			return rows, err
+		}
+	}
+	stmt, err := prepareContext(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmtQuery(ctx, stmt, args)
+	if err != nil {
+		_ = stmt.Close()
+		return nil, err
+	}
+	return &stmtRows{Rows: rows, stmt: stmt}, nil
+}
+
+func execContext(ctx context.Context, conn driver.Conn, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := conn.(driver.ExecerContext); ok {
+		rs, err := e.ExecContext(ctx, query, args)
+		if !errors.Is(err, driver.ErrSkip) {
+			return rs, err
+		}
+	}
+	stmt, err := prepareContext(ctx, conn, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	return stmtExec(ctx, stmt, args)
+}
+
+func stmtQuery(ctx context.Context, stmt driver.Stmt, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	return stmt.Query(plainValues(args)) //nolint:staticcheck
+}
+
+func stmtExec(ctx context.Context, stmt driver.Stmt, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	return stmt.Exec(plainValues(args)) //nolint:staticcheck
+}
+
+// stmtRows 关闭结果集时同时关闭预处理语句
+type stmtRows struct {
+	driver.Rows
+	stmt driver.Stmt
+}
+
+func (r *stmtRows) Close() error {
+	err := r.Rows.Close()
+	if stmtErr := r.stmt.Close(); err == nil {
+		err = stmtErr
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RetryPolicy 只读查询的重试策略
+// 只作用于事务外的 QueryByBuilder、FetchByBuilder、QueryRaw；DML 与事务内的语句永远不会重试
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数（包含第一次），<=1 表示不重试
+	BaseDelay   time.Duration // 第一次重试前的等待时间，之后按指数增长，默认 50ms
+	MaxDelay    time.Duration // 单次等待上限，默认 1s
+	// Retryable 判断错误是否可以重试，默认为 isTransientError
+	Retryable func(error) bool
+}
+
+// WithRetry 为客户端设置只读查询的重试策略，可用 RetryReads/NoRetry 按调用覆盖
+func WithRetry(p RetryPolicy) Option {
+	return func(c *MysqlClient) {
+		c.retry = &p
+	}
+}
+
+type retryPolicyKey struct{}
+
+// RetryReads 返回一个覆盖本次调用重试策略的 ctx
+func RetryReads(ctx context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// NoRetry 返回一个本次调用不重试的 ctx
+func NoRetry(ctx context.Context) context.Context {
+	return RetryReads(ctx, RetryPolicy{MaxAttempts: 1})
+}
+
+// retryPolicy 本次调用生效的策略，ctx 中的优先
+func (s MysqlClient) retryPolicy(ctx context.Context) (RetryPolicy, bool) {
+	p, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	if !ok {
+		if s.retry == nil {
+			return RetryPolicy{}, false
+		}
+		p = *s.retry
+	}
+	if p.MaxAttempts <= 1 {
+		return RetryPolicy{}, false
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 50 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = time.Second
+	}
+	if p.Retryable == nil {
+		p.Retryable = isTransientError
+	}
+	return p, true
+}
+
+// backoff 第 attempt 次失败后的等待时间：指数增长，取 [d/2, d] 之间的随机值
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryRead 按策略执行只读查询 fn；重试前会清空 dest 中已扫描的切片，避免重复数据
+func (s MysqlClient) retryRead(ctx context.Context, op string, dest any, fn func() error) error {
+	p, ok := s.retryPolicy(ctx)
+	if !ok {
+		return fn()
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		delay := p.backoff(attempt)
+		s.logger().log(ctx, LogQueryError, slog.LevelWarn, "mdb retry read", slog.String(AttrOperation, op), slog.Int("attempt", attempt),
+			slog.Duration("delay", delay), errorAttr(err))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		resetSlice(dest)
+	}
+}
+
+// resetSlice 将 *[]T 置为空切片
+func resetSlice(dest any) {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Slice {
+		rv.Elem().SetLen(0)
+	}
+}
+
+// isTransientError 可以安全重试的瞬时错误：连接失效、网络超时、server has gone away / lost connection
+func isTransientError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 2006 || mysqlErr.Number == 2013
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestRetryRead(t *testing.T) {
+	c := MysqlClient{}
+	WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})(&c)
+	ctx := context.Background()
+
+	// 瞬时错误重试，并在重试前清空已扫描的数据
+	var dest []int
+	calls := 0
+	err := c.retryRead(ctx, "FetchByBuilder", &dest, func() error {
+		calls++
+		dest = append(dest, calls)
+		if calls < 3 {
+			return &mysql.MySQLError{Number: 2013, Message: "Lost connection to MySQL server during query"}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || len(dest) != 1 || dest[0] != 3 {
+		t.Fatalf("err = %v, calls = %d, dest = %v", err, calls, dest)
+	}
+
+	// 超过次数返回最后一次错误
+	calls = 0
+	err = c.retryRead(ctx, "QueryRaw", nil, func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if !errors.Is(err, driver.ErrBadConn) || calls != 3 {
+		t.Fatalf("err = %v, calls = %d", err, calls)
+	}
+
+	// 非瞬时错误不重试
+	calls = 0
+	_ = c.retryRead(ctx, "QueryRaw", nil, func() error {
+		calls++
+		return &mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}
+	})
+	if calls != 1 {
+		t.Fatalf("retried non-transient error %d times", calls)
+	}
+
+	// ctx 覆盖客户端策略
+	calls = 0
+	_ = c.retryRead(NoRetry(ctx), "QueryRaw", nil, func() error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if calls != 1 {
+		t.Fatalf("NoRetry: calls = %d", calls)
+	}
+	calls = 0
+	_ = MysqlClient{}.retryRead(RetryReads(ctx, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}), "QueryRaw", nil, func() error {
+		calls++
+		return mysql.ErrInvalidConn
+	})
+	if calls != 2 {
+		t.Fatalf("RetryReads: calls = %d", calls)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, max := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 4: 50 * time.Millisecond, 40: 50 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %s, want [%s, %s]", attempt, d, max/2, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Schema 当前数据库的表结构元数据，来自 information_schema
+// Schema 会被缓存并在调用方之间共享，不要修改
+type Schema struct {
+	Database string
+	Tables   map[string]*TableInfo
+}
+
+// TableNames 返回排序后的表名
+func (s *Schema) TableNames() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Table 按表名获取表信息
+func (s *Schema) Table(name string) (*TableInfo, bool) {
+	t, ok := s.Tables[name]
+	return t, ok
+}
+
+// TableInfo 表信息
+type TableInfo struct {
+	Name      string
+	View      bool // 是否为视图
+	Engine    string
+	Collation string
+	Comment   string
+	// AutoIncrement 下一个自增值，没有自增列时为 0
+	// 注意：MySQL 8 中该值受 information_schema_stats_expiry 缓存影响，可能不是实时值
+	AutoIncrement uint64
+
+	Columns     []ColumnInfo // 按列顺序
+	Indexes     []IndexInfo  // 按索引名排序，PRIMARY 在最前
+	ForeignKeys []ForeignKeyInfo
+}
+
+// Column 按列名获取列信息
+func (t *TableInfo) Column(name string) (ColumnInfo, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ColumnInfo{}, false
+}
+
+// PrimaryKey 返回主键列，没有主键时返回 nil
+func (t *TableInfo) PrimaryKey() []string {
+	for _, idx := range t.Indexes {
+		if idx.Primary {
+			return idx.Columns
+		}
+	}
+	return nil
+}
+
+// ColumnInfo 列信息
+type ColumnInfo struct {
+	Name       string
+	Position   int
+	DataType   string // 例如 varchar、bigint
+	ColumnType string // 完整类型，例如 varchar(64)、bigint unsigned
+	Nullable   bool
+	// Default 默认值，没有默认值时为 nil（DEFAULT NULL 同样为 nil）
+	Default          *string
+	Extra            string // 例如 auto_increment、on update CURRENT_TIMESTAMP
+	Comment          string
+	Key              string // PRI、UNI、MUL
+	CharMaxLength    int64
+	NumericPrecision int64
+	NumericScale     int64
+
+	Unsigned       bool
+	AutoIncrement  bool
+	Generated      bool   // 生成列（VIRTUAL/STORED）
+	GenerationExpr string // 生成列表达式
+}
+
+// IndexInfo 索引信息
+type IndexInfo struct {
+	Name    string
+	Primary bool
+	Unique  bool
+	Type    string   // BTREE、FULLTEXT 等
+	Columns []string // 按索引中的顺序，函数索引的列为空字符串
+}
+
+// ForeignKeyInfo 外键信息
+type ForeignKeyInfo struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnUpdate   string
+	OnDelete   string
+}
+
+// schemaCache 缓存 Schema，RefreshSchema 时重新加载
+type schemaCache struct {
+	mu     sync.Mutex
+	schema *Schema
+}
+
+// Schema 返回当前数据库的表结构（第一次调用时加载，之后使用缓存）
+func (s MysqlClient) Schema(ctx context.Context) (*Schema, error) {
+	if s.schema == nil {
+		return s.loadSchema(ctx)
+	}
+	s.schema.mu.Lock()
+	defer s.schema.mu.Unlock()
+	if s.schema.schema != nil {
+		return s.schema.schema, nil
+	}
+	schema, err := s.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.schema.schema = schema
+	return schema, nil
+}
+
+// RefreshSchema 重新从 information_schema 加载表结构并更新缓存（例如执行迁移之后）
+func (s MysqlClient) RefreshSchema(ctx context.Context) (*Schema, error) {
+	if s.schema == nil {
+		return s.loadSchema(ctx)
+	}
+	s.schema.mu.Lock()
+	defer s.schema.mu.Unlock()
+	schema, err := s.loadSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.schema.schema = schema
+	return schema, nil
+}
+
+type schemaTableRow struct {
+	Name          string         `db:"table_name"`
+	Type          string         `db:"table_type"`
+	Engine        sql.NullString `db:"engine"`
+	AutoIncrement sql.NullInt64  `db:"auto_increment"`
+	Collation     sql.NullString `db:"table_collation"`
+	Comment       sql.NullString `db:"table_comment"`
+}
+
+type schemaColumnRow struct {
+	Table            string         `db:"table_name"`
+	Name             string         `db:"column_name"`
+	Position         int            `db:"ordinal_position"`
+	Default          sql.NullString `db:"column_default"`
+	Nullable         string         `db:"is_nullable"`
+	DataType         string         `db:"data_type"`
+	ColumnType       string         `db:"column_type"`
+	CharMaxLength    sql.NullInt64  `db:"character_maximum_length"`
+	NumericPrecision sql.NullInt64  `db:"numeric_precision"`
+	NumericScale     sql.NullInt64  `db:"numeric_scale"`
+	Key              string         `db:"column_key"`
+	Extra            string         `db:"extra"`
+	Comment          string         `db:"column_comment"`
+	GenerationExpr   sql.NullString `db:"generation_expression"`
+}
+
+type schemaIndexRow struct {
+	Table     string         `db:"table_name"`
+	Name      string         `db:"index_name"`
+	NonUnique int            `db:"non_unique"`
+	Seq       int            `db:"seq_in_index"`
+	Column    sql.NullString `db:"column_name"`
+	Type      string         `db:"index_type"`
+}
+
+type schemaForeignKeyRow struct {
+	Table     string `db:"table_name"`
+	Name      string `db:"constraint_name"`
+	Column    string `db:"column_name"`
+	RefTable  string `db:"referenced_table_name"`
+	RefColumn string `db:"referenced_column_name"`
+	OnUpdate  string `db:"update_rule"`
+	OnDelete  string `db:"delete_rule"`
+}
+
+const (
+	schemaTablesSql = "SELECT TABLE_NAME AS table_name, TABLE_TYPE AS table_type, ENGINE AS engine, AUTO_INCREMENT AS auto_increment, " +
+		"TABLE_COLLATION AS table_collation, TABLE_COMMENT AS table_comment " +
+		"FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()"
+	schemaColumnsSql = "SELECT TABLE_NAME AS table_name, COLUMN_NAME AS column_name, ORDINAL_POSITION AS ordinal_position, " +
+		"COLUMN_DEFAULT AS column_default, IS_NULLABLE AS is_nullable, DATA_TYPE AS data_type, COLUMN_TYPE AS column_type, " +
+		"CHARACTER_MAXIMUM_LENGTH AS character_maximum_length, NUMERIC_PRECISION AS numeric_precision, NUMERIC_SCALE AS numeric_scale, " +
+		"COLUMN_KEY AS column_key, EXTRA AS extra, COLUMN_COMMENT AS column_comment, GENERATION_EXPRESSION AS generation_expression " +
+		"FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION"
+	schemaIndexesSql = "SELECT TABLE_NAME AS table_name, INDEX_NAME AS index_name, NON_UNIQUE AS non_unique, " +
+		"SEQ_IN_INDEX AS seq_in_index, COLUMN_NAME AS column_name, INDEX_TYPE AS index_type " +
+		"FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX"
+	schemaForeignKeysSql = "SELECT k.TABLE_NAME AS table_name, k.CONSTRAINT_NAME AS constraint_name, k.COLUMN_NAME AS column_name, " +
+		"k.REFERENCED_TABLE_NAME AS referenced_table_name, k.REFERENCED_COLUMN_NAME AS referenced_column_name, " +
+		"r.UPDATE_RULE AS update_rule, r.DELETE_RULE AS delete_rule " +
+		"FROM information_schema.KEY_COLUMN_USAGE k JOIN information_schema.REFERENTIAL_CONSTRAINTS r " +
+		"ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME " +
+		"WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_NAME IS NOT NULL " +
+		"ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION"
+)
+
+// loadSchema 从 information_schema 读取表结构
+func (s MysqlClient) loadSchema(ctx context.Context) (*Schema, error) {
+	var database string
+	if err := s.Db.GetContext(ctx, &database, "SELECT DATABASE()"); err != nil {
+		return nil, err
+	}
+	var (
+		tables  []schemaTableRow
+		columns []schemaColumnRow
+		indexes []schemaIndexRow
+		fks     []schemaForeignKeyRow
+	)
+	if err := s.Db.SelectContext(ctx, &tables, schemaTablesSql); err != nil {
+		return nil, err
+	}
+	if err := s.Db.SelectContext(ctx, &columns, schemaColumnsSql); err != nil {
+		return nil, err
+	}
+	if err := s.Db.SelectContext(ctx, &indexes, schemaIndexesSql); err != nil {
+		return nil, err
+	}
+	if err := s.Db.SelectContext(ctx, &fks, schemaForeignKeysSql); err != nil {
+		return nil, err
+	}
+	return assembleSchema(database, tables, columns, indexes, fks), nil
+}
+
+// assembleSchema 将 information_schema 的查询结果组装为 Schema
+func assembleSchema(database string, tables []schemaTableRow, columns []schemaColumnRow, indexes []schemaIndexRow, fks []schemaForeignKeyRow) *Schema {
+	schema := &Schema{Database: database, Tables: make(map[string]*TableInfo, len(tables))}
+	for _, t := range tables {
+		info := &TableInfo{
+			Name:      t.Name,
+			View:      t.Type == "VIEW",
+			Engine:    t.Engine.String,
+			Collation: t.Collation.String,
+			Comment:   t.Comment.String,
+		}
+		if t.AutoIncrement.Valid && t.AutoIncrement.Int64 > 0 {
+			info.AutoIncrement = uint64(t.AutoIncrement.Int64)
+		}
+		schema.Tables[t.Name] = info
+	}
+
+	for _, c := range columns {
+		t, ok := schema.Tables[c.Table]
+		if !ok {
+			continue
+		}
+		extra := strings.ToLower(c.Extra)
+		col := ColumnInfo{
+			Name:             c.Name,
+			Position:         c.Position,
+			DataType:         strings.ToLower(c.DataType),
+			ColumnType:       c.ColumnType,
+			Nullable:         c.Nullable == "YES",
+			Extra:            c.Extra,
+			Comment:          c.Comment,
+			Key:              c.Key,
+			CharMaxLength:    c.CharMaxLength.Int64,
+			NumericPrecision: c.NumericPrecision.Int64,
+			NumericScale:     c.NumericScale.Int64,
+			Unsigned:         strings.Contains(strings.ToLower(c.ColumnType), "unsigned"),
+			AutoIncrement:    strings.Contains(extra, "auto_increment"),
+			Generated:        strings.Contains(extra, "virtual generated") || strings.Contains(extra, "stored generated"),
+		}
+		if col.Generated {
+			col.GenerationExpr = c.GenerationExpr.String
+		}
+		if c.Default.Valid {
+			def := c.Default.String
+			col.Default = &def
+		}
+		t.Columns = append(t.Columns, col)
+	}
+
+	for _, idx := range indexes {
+		t, ok := schema.Tables[idx.Table]
+		if !ok {
+			continue
+		}
+		n := len(t.Indexes)
+		if n == 0 || t.Indexes[n-1].Name != idx.Name {
+			t.Indexes = append(t.Indexes, IndexInfo{
+				Name:    idx.Name,
+				Primary: idx.Name == "PRIMARY",
+				Unique:  idx.NonUnique == 0,
+				Type:    idx.Type,
+			})
+			n++
+		}
+		t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, idx.Column.String)
+	}
+	for _, t := range schema.Tables {
+		sort.SliceStable(t.Indexes, func(i, j int) bool {
+			if t.Indexes[i].Primary != t.Indexes[j].Primary {
+				return t.Indexes[i].Primary
+			}
+			return t.Indexes[i].Name < t.Indexes[j].Name
+		})
+	}
+
+	for _, fk := range fks {
+		t, ok := schema.Tables[fk.Table]
+		if !ok {
+			continue
+		}
+		n := len(t.ForeignKeys)
+		if n == 0 || t.ForeignKeys[n-1].Name != fk.Name {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKeyInfo{
+				Name:     fk.Name,
+				RefTable: fk.RefTable,
+				OnUpdate: fk.OnUpdate,
+				OnDelete: fk.OnDelete,
+			})
+			n++
+		}
+		t.ForeignKeys[n-1].Columns = append(t.ForeignKeys[n-1].Columns, fk.Column)
+		t.ForeignKeys[n-1].RefColumns = append(t.ForeignKeys[n-1].RefColumns, fk.RefColumn)
+	}
+	return schema
+}
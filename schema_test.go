@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestAssembleSchema(t *testing.T) {
+	tables := []schemaTableRow{
+		{Name: "user", Type: "BASE TABLE", Engine: sql.NullString{String: "InnoDB", Valid: true}, AutoIncrement: sql.NullInt64{Int64: 42, Valid: true}},
+		{Name: "order", Type: "BASE TABLE", Engine: sql.NullString{String: "InnoDB", Valid: true}},
+		{Name: "user_view", Type: "VIEW"},
+	}
+	columns := []schemaColumnRow{
+		{Table: "user", Name: "id", Position: 1, Nullable: "NO", DataType: "bigint", ColumnType: "bigint unsigned", Key: "PRI", Extra: "auto_increment"},
+		{Table: "user", Name: "name", Position: 2, Nullable: "YES", DataType: "varchar", ColumnType: "varchar(64)", CharMaxLength: sql.NullInt64{Int64: 64, Valid: true}},
+		{Table: "user", Name: "created_at", Position: 3, Nullable: "NO", DataType: "datetime", ColumnType: "datetime",
+			Default: sql.NullString{String: "CURRENT_TIMESTAMP", Valid: true}, Extra: "DEFAULT_GENERATED"},
+		{Table: "user", Name: "name_len", Position: 4, Nullable: "YES", DataType: "int", ColumnType: "int",
+			Extra: "VIRTUAL GENERATED", GenerationExpr: sql.NullString{String: "char_length(`name`)", Valid: true}},
+		{Table: "order", Name: "id", Position: 1, Nullable: "NO", DataType: "bigint", ColumnType: "bigint", Key: "PRI"},
+		{Table: "order", Name: "user_id", Position: 2, Nullable: "NO", DataType: "bigint", ColumnType: "bigint unsigned", Key: "MUL"},
+		{Table: "missing", Name: "id"},
+	}
+	indexes := []schemaIndexRow{
+		{Table: "user", Name: "PRIMARY", NonUnique: 0, Seq: 1, Column: sql.NullString{String: "id", Valid: true}, Type: "BTREE"},
+		{Table: "user", Name: "idx_name_created", NonUnique: 1, Seq: 1, Column: sql.NullString{String: "name", Valid: true}, Type: "BTREE"},
+		{Table: "user", Name: "idx_name_created", NonUnique: 1, Seq: 2, Column: sql.NullString{String: "created_at", Valid: true}, Type: "BTREE"},
+		{Table: "order", Name: "PRIMARY", NonUnique: 0, Seq: 1, Column: sql.NullString{String: "id", Valid: true}, Type: "BTREE"},
+	}
+	fks := []schemaForeignKeyRow{
+		{Table: "order", Name: "fk_order_user", Column: "user_id", RefTable: "user", RefColumn: "id", OnUpdate: "RESTRICT", OnDelete: "CASCADE"},
+	}
+	schema := assembleSchema("app", tables, columns, indexes, fks)
+
+	if got := schema.TableNames(); !reflect.DeepEqual(got, []string{"order", "user", "user_view"}) {
+		t.Fatalf("TableNames = %v", got)
+	}
+	user, _ := schema.Table("user")
+	if user.AutoIncrement != 42 || len(user.Columns) != 4 {
+		t.Fatalf("unexpected user table: %+v", user)
+	}
+	id, _ := user.Column("id")
+	if !id.AutoIncrement || !id.Unsigned || id.Nullable || id.Default != nil {
+		t.Errorf("unexpected id column: %+v", id)
+	}
+	created, _ := user.Column("created_at")
+	if created.Default == nil || *created.Default != "CURRENT_TIMESTAMP" || created.Generated {
+		t.Errorf("unexpected created_at column: %+v", created)
+	}
+	nameLen, _ := user.Column("name_len")
+	if !nameLen.Generated || nameLen.GenerationExpr != "char_length(`name`)" {
+		t.Errorf("unexpected name_len column: %+v", nameLen)
+	}
+	if pk := user.PrimaryKey(); !reflect.DeepEqual(pk, []string{"id"}) {
+		t.Errorf("PrimaryKey = %v", pk)
+	}
+	if len(user.Indexes) != 2 || !reflect.DeepEqual(user.Indexes[1].Columns, []string{"name", "created_at"}) || user.Indexes[1].Unique {
+		t.Errorf("unexpected indexes: %+v", user.Indexes)
+	}
+
+	order, _ := schema.Table("order")
+	want := []ForeignKeyInfo{{Name: "fk_order_user", Columns: []string{"user_id"}, RefTable: "user", RefColumns: []string{"id"}, OnUpdate: "RESTRICT", OnDelete: "CASCADE"}}
+	if !reflect.DeepEqual(order.ForeignKeys, want) {
+		t.Errorf("ForeignKeys = %+v", order.ForeignKeys)
+	}
+	if view, _ := schema.Table("user_view"); !view.View {
+		t.Errorf("expected user_view to be a view")
+	}
+}
@@ -0,0 +1,437 @@
+package schemadiff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/builder"
+)
+
+// Diff 模型与数据库表结构的差异
+type Diff struct {
+	MissingTables []*Model    // 模型中有，数据库中没有
+	ExtraTables   []string    // 数据库中有，模型中没有（只在 Compare 的 AllTables 模式下报告）
+	Tables        []TableDiff // 两边都有但存在差异的表
+}
+
+// TableDiff 单个表的差异
+type TableDiff struct {
+	Table           string
+	MissingColumns  []Column
+	ExtraColumns    []string
+	ColumnMismatch  []ColumnMismatch
+	PrimaryKey      *[2][]string // [数据库, 模型]，模型声明了主键且不一致时非 nil
+	MissingIndexes  []ModelIndex
+	ExtraIndexes    []string
+	IndexMismatches []IndexMismatch
+
+	model *Model
+}
+
+// ColumnMismatch 列定义不一致
+type ColumnMismatch struct {
+	Column string
+	Reason string // 例如 "type varchar(32), model varchar(64)"
+	fixed  bool   // 是否可以生成 MODIFY COLUMN
+}
+
+// IndexMismatch 同名索引的列或唯一性不一致
+type IndexMismatch struct {
+	Name     string
+	Database db.IndexInfo
+	Model    ModelIndex
+}
+
+// Empty 没有任何差异
+func (d *Diff) Empty() bool {
+	return len(d.MissingTables) == 0 && len(d.ExtraTables) == 0 && len(d.Tables) == 0
+}
+
+// Options 比较选项
+type Options struct {
+	// AllTables 报告数据库中没有对应模型的表，默认只比较传入的模型
+	AllTables bool
+	// Ignore 不参与比较的表，例如 schema_migrations
+	Ignore []string
+}
+
+// Check 读取客户端当前的表结构（刷新缓存）并与模型比较，适合在 CI 中运行
+func Check(ctx context.Context, client *db.MysqlClient, opts Options, models ...*Model) (*Diff, error) {
+	schema, err := client.RefreshSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(schema, opts, models...), nil
+}
+
+// Compare 比较表结构与模型
+func Compare(schema *db.Schema, opts Options, models ...*Model) *Diff {
+	ignore := map[string]bool{}
+	for _, t := range opts.Ignore {
+		ignore[t] = true
+	}
+	d := &Diff{}
+	modeled := map[string]bool{}
+	sorted := append([]*Model(nil), models...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Table < sorted[j].Table })
+	for _, m := range sorted {
+		modeled[m.Table] = true
+		if ignore[m.Table] {
+			continue
+		}
+		t, ok := schema.Table(m.Table)
+		if !ok {
+			d.MissingTables = append(d.MissingTables, m)
+			continue
+		}
+		if td := compareTable(t, m); td != nil {
+			d.Tables = append(d.Tables, *td)
+		}
+	}
+	if opts.AllTables {
+		for _, name := range schema.TableNames() {
+			if !modeled[name] && !ignore[name] && !schema.Tables[name].View {
+				d.ExtraTables = append(d.ExtraTables, name)
+			}
+		}
+	}
+	return d
+}
+
+func compareTable(t *db.TableInfo, m *Model) *TableDiff {
+	td := &TableDiff{Table: m.Table, model: m}
+	for _, c := range m.Columns {
+		info, ok := t.Column(c.Name)
+		if !ok {
+			td.MissingColumns = append(td.MissingColumns, c)
+			continue
+		}
+		if mm, ok := compareColumn(info, c); ok {
+			td.ColumnMismatch = append(td.ColumnMismatch, mm)
+		}
+	}
+	for _, info := range t.Columns {
+		if _, ok := m.column(info.Name); !ok {
+			td.ExtraColumns = append(td.ExtraColumns, info.Name)
+		}
+	}
+
+	if len(m.PrimaryKey) > 0 && !equalStrings(t.PrimaryKey(), m.PrimaryKey) {
+		td.PrimaryKey = &[2][]string{t.PrimaryKey(), m.PrimaryKey}
+	}
+	// 模型没有声明索引时不比较索引
+	if len(m.Indexes) > 0 {
+		declared := map[string]bool{}
+		for _, mi := range m.Indexes {
+			declared[mi.Name] = true
+			idx, ok := findIndex(t, mi.Name)
+			if !ok {
+				td.MissingIndexes = append(td.MissingIndexes, mi)
+				continue
+			}
+			if idx.Unique != mi.Unique || !equalStrings(idx.Columns, mi.Columns) {
+				td.IndexMismatches = append(td.IndexMismatches, IndexMismatch{Name: mi.Name, Database: idx, Model: mi})
+			}
+		}
+		for _, idx := range t.Indexes {
+			if !idx.Primary && !declared[idx.Name] && !foreignKeyIndex(t, idx) {
+				td.ExtraIndexes = append(td.ExtraIndexes, idx.Name)
+			}
+		}
+	}
+
+	if len(td.MissingColumns) == 0 && len(td.ExtraColumns) == 0 && len(td.ColumnMismatch) == 0 && td.PrimaryKey == nil &&
+		len(td.MissingIndexes) == 0 && len(td.ExtraIndexes) == 0 && len(td.IndexMismatches) == 0 {
+		return nil
+	}
+	return td
+}
+
+// compareColumn 显式声明 type 的列比较完整类型，推断的类型只比较类型族（例如 string 与 varchar/text 均兼容）
+// 模型为非指针而数据库列可为 NULL 时视为不一致（扫描 NULL 会失败），反之则不报告
+func compareColumn(info db.ColumnInfo, c Column) (ColumnMismatch, bool) {
+	var reasons []string
+	fixed := c.ExplicitType
+	dbType := normalizeType(info.ColumnType)
+	if c.ExplicitType {
+		if dbType != normalizeType(c.Type) {
+			reasons = append(reasons, fmt.Sprintf("type %s, model %s", dbType, normalizeType(c.Type)))
+		}
+	} else if !compatible(info, c.Type) {
+		reasons = append(reasons, fmt.Sprintf("type %s, model %s (%s)", dbType, c.GoType, c.Type))
+	}
+	if info.Nullable && !c.Nullable {
+		reasons = append(reasons, "database column is nullable, model field is not")
+	}
+	if c.AutoIncrement && !info.AutoIncrement {
+		reasons = append(reasons, "missing auto_increment")
+	}
+	if len(reasons) == 0 {
+		return ColumnMismatch{}, false
+	}
+	return ColumnMismatch{Column: c.Name, Reason: strings.Join(reasons, "; "), fixed: fixed}, true
+}
+
+var intWidthRe = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+
+// normalizeType 统一大小写并去掉整数显示宽度（MySQL 8 不再显示），tinyint(1) 保留
+func normalizeType(t string) string {
+	t = strings.Join(strings.Fields(strings.ToLower(t)), " ")
+	if strings.HasPrefix(t, "tinyint(1)") {
+		return t
+	}
+	t = strings.Replace(t, "integer", "int", 1)
+	return intWidthRe.ReplaceAllString(t, "$1")
+}
+
+// compatible 判断推断的类型与数据库列是否属于同一类型族
+func compatible(info db.ColumnInfo, inferred string) bool {
+	family := func(dataType string) string {
+		switch dataType {
+		case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year", "bit":
+			return "int"
+		case "float", "double", "real", "decimal", "numeric":
+			return "float"
+		case "date":
+			return "date"
+		case "datetime", "timestamp":
+			return "datetime"
+		case "json":
+			return "json"
+		case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+			return "bytes"
+		default:
+			return "string"
+		}
+	}
+	dbFamily := family(info.DataType)
+	want := family(strings.Fields(strings.SplitN(inferred, "(", 2)[0])[0])
+	switch want {
+	case "string":
+		// 字符串常用于接收 decimal 与未开启 parseTime 的日期列
+		return dbFamily != "int" && (dbFamily != "float" || info.DataType == "decimal" || info.DataType == "numeric")
+	case "bytes":
+		return true
+	case "json":
+		return dbFamily == "json" || dbFamily == "string" || dbFamily == "bytes"
+	case "float":
+		return dbFamily == "float" || dbFamily == "int"
+	}
+	return dbFamily == want
+}
+
+func findIndex(t *db.TableInfo, name string) (db.IndexInfo, bool) {
+	for _, idx := range t.Indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return db.IndexInfo{}, false
+}
+
+// foreignKeyIndex InnoDB 为外键自动创建的同名索引不报告为多余
+func foreignKeyIndex(t *db.TableInfo, idx db.IndexInfo) bool {
+	for _, fk := range t.ForeignKeys {
+		if fk.Name == idx.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String 返回可读的差异报告，每行一条
+func (d *Diff) String() string {
+	var lines []string
+	for _, m := range d.MissingTables {
+		lines = append(lines, fmt.Sprintf("missing table %s", m.Table))
+	}
+	for _, t := range d.ExtraTables {
+		lines = append(lines, fmt.Sprintf("extra table %s", t))
+	}
+	for _, t := range d.Tables {
+		for _, c := range t.MissingColumns {
+			lines = append(lines, fmt.Sprintf("table %s: missing column %s %s", t.Table, c.Name, c.Type))
+		}
+		for _, c := range t.ExtraColumns {
+			lines = append(lines, fmt.Sprintf("table %s: extra column %s", t.Table, c))
+		}
+		for _, c := range t.ColumnMismatch {
+			lines = append(lines, fmt.Sprintf("table %s: column %s: %s", t.Table, c.Column, c.Reason))
+		}
+		if t.PrimaryKey != nil {
+			lines = append(lines, fmt.Sprintf("table %s: primary key (%s), model (%s)", t.Table,
+				strings.Join(t.PrimaryKey[0], ", "), strings.Join(t.PrimaryKey[1], ", ")))
+		}
+		for _, idx := range t.MissingIndexes {
+			lines = append(lines, fmt.Sprintf("table %s: missing index %s (%s)", t.Table, idx.Name, strings.Join(idx.Columns, ", ")))
+		}
+		for _, idx := range t.ExtraIndexes {
+			lines = append(lines, fmt.Sprintf("table %s: extra index %s", t.Table, idx))
+		}
+		for _, idx := range t.IndexMismatches {
+			lines = append(lines, fmt.Sprintf("table %s: index %s is (%s) unique=%v, model (%s) unique=%v", t.Table, idx.Name,
+				strings.Join(idx.Database.Columns, ", "), idx.Database.Unique, strings.Join(idx.Model.Columns, ", "), idx.Model.Unique))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Statements 生成使模型与数据库一致的 DDL
+// drop 为 false 时不生成删除多余表/列/索引的语句
+// 推断类型的列不一致时无法确定目标类型，只在报告中体现，不生成 MODIFY COLUMN
+func (d *Diff) Statements(drop bool) []string {
+	var stmts []string
+	for _, m := range d.MissingTables {
+		stmts = append(stmts, createTable(m).Sql())
+	}
+	for _, t := range d.Tables {
+		alter := builder.AlterTable(t.Table)
+		for _, c := range t.MissingColumns {
+			def := columnDef(c)
+			if prev := previousColumn(t.model, c.Name); prev != "" {
+				def.After(prev)
+			} else {
+				def.First()
+			}
+			alter.AddColumn(def)
+		}
+		for _, mm := range t.ColumnMismatch {
+			if c, ok := t.model.column(mm.Column); ok && mm.fixed {
+				alter.ModifyColumn(columnDef(c))
+			}
+		}
+		if t.PrimaryKey != nil {
+			if len(t.PrimaryKey[0]) > 0 {
+				alter.DropPrimaryKey()
+			}
+			alter.AddPrimaryKey(t.PrimaryKey[1]...)
+		}
+		for _, idx := range t.IndexMismatches {
+			alter.DropIndex(idx.Name).AddIndex(indexDef(idx.Model))
+		}
+		for _, idx := range t.MissingIndexes {
+			alter.AddIndex(indexDef(idx))
+		}
+		if drop {
+			for _, idx := range t.ExtraIndexes {
+				alter.DropIndex(idx)
+			}
+			for _, c := range t.ExtraColumns {
+				alter.DropColumn(c)
+			}
+		}
+		if !alter.Empty() {
+			stmts = append(stmts, alter.Sql())
+		}
+	}
+	if drop && len(d.ExtraTables) > 0 {
+		stmts = append(stmts, builder.DropTable(false, d.ExtraTables...))
+	}
+	return stmts
+}
+
+func createTable(m *Model) *builder.CreateTableBuilder {
+	ct := builder.CreateTable(m.Table)
+	for _, c := range m.Columns {
+		ct.Column(columnDef(c))
+	}
+	if len(m.PrimaryKey) > 0 {
+		ct.PrimaryKey(m.PrimaryKey...)
+	}
+	for _, idx := range m.Indexes {
+		ct.Index(indexDef(idx))
+	}
+	return ct
+}
+
+func columnDef(c Column) *builder.ColumnDef {
+	def := builder.Col(c.Name, strings.ToUpper(c.Type))
+	if !c.Nullable {
+		def.NotNull()
+	}
+	if c.Default != nil {
+		def.DefaultExpr(*c.Default)
+	}
+	if c.AutoIncrement {
+		def.AutoIncrement()
+	}
+	if c.Comment != "" {
+		def.Comment(c.Comment)
+	}
+	return def
+}
+
+func indexDef(idx ModelIndex) *builder.IndexDef {
+	if idx.Unique {
+		return builder.UniqueIndex(idx.Name, idx.Columns...)
+	}
+	return builder.Index(idx.Name, idx.Columns...)
+}
+
+func previousColumn(m *Model, name string) string {
+	for i, c := range m.Columns {
+		if c.Name == name && i > 0 {
+			return m.Columns[i-1].Name
+		}
+	}
+	return ""
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_.+\.(up|down)\.sql$`)
+
+// WriteMigration 将 Statements 写入 dir 中下一个版本的迁移文件（<版本>_<name>.up.sql），返回文件路径
+// 没有差异时不写文件，返回空字符串
+func (d *Diff) WriteMigration(dir, name string, drop bool) (string, error) {
+	stmts := d.Statements(drop)
+	if len(stmts) == 0 {
+		return "", nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	var version int64
+	width := 4
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		if v, err := strconv.ParseInt(m[1], 10, 64); err == nil && v > version {
+			version = v
+			width = len(m[1])
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%0*d_%s.up.sql", width, version+1, name))
+	var b strings.Builder
+	b.WriteString("-- generated by schemadiff\n")
+	for _, line := range strings.Split(d.String(), "\n") {
+		b.WriteString("-- " + line + "\n")
+	}
+	for _, stmt := range stmts {
+		b.WriteString("\n" + stmt + ";\n")
+	}
+	return path, os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,275 @@
+package schemadiff
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	db "github.com/preceeder/db"
+)
+
+// Model 从 Go 结构体解析出的表定义
+//
+// 列来自 db 标签（与 sqlx 一致，db:"-" 忽略，匿名嵌入结构体会展开），
+// 可选的 mdb 标签补充 DDL 信息，多个选项用分号分隔：
+//
+//	Id    uint64  `db:"id" mdb:"pk;auto_increment"`
+//	Name  string  `db:"name" mdb:"type:varchar(64);default:'';unique:uk_name"`
+//	OrgId *int64  `db:"org_id" mdb:"index:idx_org_created"`
+//
+// 选项：pk、auto_increment、type:<列类型>、null、notnull、default:<表达式>、
+// index:<索引名>、unique:<索引名>、comment:<注释>。同名索引按字段顺序组成联合索引。
+type Model struct {
+	Table      string
+	Columns    []Column
+	PrimaryKey []string
+	Indexes    []ModelIndex
+}
+
+// Column 模型中的列
+type Column struct {
+	Name          string
+	Type          string // 列类型，没有 type 选项时由 Go 类型推断
+	ExplicitType  bool   // Type 来自 mdb 标签
+	Nullable      bool
+	AutoIncrement bool
+	Default       *string
+	Comment       string
+	GoType        reflect.Type
+}
+
+// ModelIndex 模型中声明的索引
+type ModelIndex struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// tableNamer 实现 TableName 的模型（mdbgen 生成的结构体均实现）
+type tableNamer interface {
+	TableName() string
+}
+
+// ParseModel 解析结构体（或其指针）为 Model，表名取 TableName() 方法，否则为类型名的 snake_case
+func ParseModel(v any) (*Model, error) {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schemadiff: model must be a struct, got %T", v)
+	}
+	m := &Model{Table: snakeCase(rt.Name())}
+	if tn, ok := v.(tableNamer); ok {
+		m.Table = tn.TableName()
+	} else if tn, ok := reflect.New(rt).Interface().(tableNamer); ok {
+		m.Table = tn.TableName()
+	}
+	if err := m.addFields(rt); err != nil {
+		return nil, err
+	}
+	if len(m.Columns) == 0 {
+		return nil, fmt.Errorf("schemadiff: model %s has no db columns", rt.Name())
+	}
+	return m, nil
+}
+
+// MustParseModel 同 ParseModel，出错时 panic，便于在变量初始化中使用
+func MustParseModel(v any) *Model {
+	m, err := ParseModel(v)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *Model) addFields(rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && !hasTag {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !isScalarStruct(ft) {
+				if err := m.addFields(ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			// 与 sqlx 默认的 NameMapper（strings.ToLower）一致
+			name = strings.ToLower(f.Name)
+		}
+		col := Column{Name: name, GoType: f.Type}
+		col.Type, col.Nullable = inferType(f.Type)
+		if err := m.applyOptions(&col, f.Tag.Get("mdb")); err != nil {
+			return fmt.Errorf("schemadiff: %s.%s: %w", rt.Name(), f.Name, err)
+		}
+		m.Columns = append(m.Columns, col)
+	}
+	return nil
+}
+
+func (m *Model) applyOptions(col *Column, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	for _, opt := range strings.Split(tag, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), ":")
+		switch strings.ToLower(key) {
+		case "":
+		case "pk":
+			m.PrimaryKey = append(m.PrimaryKey, col.Name)
+			col.Nullable = false
+		case "auto_increment":
+			col.AutoIncrement = true
+		case "type":
+			col.Type, col.ExplicitType = strings.ToLower(strings.TrimSpace(value)), true
+		case "null":
+			col.Nullable = true
+		case "notnull":
+			col.Nullable = false
+		case "default":
+			def := value
+			col.Default = &def
+		case "comment":
+			col.Comment = value
+		case "index", "unique":
+			m.addIndexColumn(value, key == "unique", col.Name)
+		default:
+			return fmt.Errorf("unknown mdb option %q", key)
+		}
+	}
+	return nil
+}
+
+func (m *Model) addIndexColumn(name string, unique bool, column string) {
+	for i := range m.Indexes {
+		if m.Indexes[i].Name == name {
+			m.Indexes[i].Columns = append(m.Indexes[i].Columns, column)
+			m.Indexes[i].Unique = m.Indexes[i].Unique || unique
+			return
+		}
+	}
+	m.Indexes = append(m.Indexes, ModelIndex{Name: name, Unique: unique, Columns: []string{column}})
+}
+
+// column 按列名查找
+func (m *Model) column(name string) (Column, bool) {
+	for _, c := range m.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	dateType      = reflect.TypeOf(db.Date(0))
+	dateTimeType  = reflect.TypeOf(db.DateTime{})
+	jsonType      = reflect.TypeOf(db.Json{})
+	jsonSliceType = reflect.TypeOf(db.JsonSlice{})
+	bytesType     = reflect.TypeOf([]byte(nil))
+)
+
+// isScalarStruct 作为单个列处理的结构体类型
+func isScalarStruct(t reflect.Type) bool {
+	if t == timeType || t == dateTimeType {
+		return true
+	}
+	_, ok := reflect.New(t).Interface().(sql.Scanner)
+	return ok
+}
+
+// inferType 由 Go 类型推断列类型与是否可为 NULL
+func inferType(t reflect.Type) (string, bool) {
+	nullable := false
+	if t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	switch t {
+	case timeType, dateTimeType:
+		return "datetime", nullable
+	case dateType:
+		return "date", nullable
+	case jsonType, jsonSliceType:
+		return "json", nullable
+	case bytesType:
+		return "blob", true
+	}
+	switch reflect.New(t).Interface().(type) {
+	case *sql.NullString:
+		return "varchar(255)", true
+	case *sql.NullInt64:
+		return "bigint", true
+	case *sql.NullInt32:
+		return "int", true
+	case *sql.NullInt16:
+		return "smallint", true
+	case *sql.NullBool:
+		return "tinyint(1)", true
+	case *sql.NullFloat64:
+		return "double", true
+	case *sql.NullTime:
+		return "datetime", true
+	}
+	var typ string
+	switch t.Kind() {
+	case reflect.Bool:
+		typ = "tinyint(1)"
+	case reflect.Int8:
+		typ = "tinyint"
+	case reflect.Uint8:
+		typ = "tinyint unsigned"
+	case reflect.Int16:
+		typ = "smallint"
+	case reflect.Uint16:
+		typ = "smallint unsigned"
+	case reflect.Int32:
+		typ = "int"
+	case reflect.Uint32:
+		typ = "int unsigned"
+	case reflect.Int, reflect.Int64:
+		typ = "bigint"
+	case reflect.Uint, reflect.Uint64:
+		typ = "bigint unsigned"
+	case reflect.Float32:
+		typ = "float"
+	case reflect.Float64:
+		typ = "double"
+	case reflect.Map, reflect.Slice:
+		typ = "json"
+	default:
+		typ = "varchar(255)"
+	}
+	return typ, nullable
+}
+
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
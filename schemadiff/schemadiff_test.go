@@ -0,0 +1,158 @@
+package schemadiff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	db "github.com/preceeder/db"
+	"github.com/preceeder/db/migrations"
+)
+
+type base struct {
+	Id        uint64      `db:"id" mdb:"pk;auto_increment"`
+	CreatedAt db.DateTime `db:"created_at"`
+}
+
+type user struct {
+	base
+	Name    string  `db:"name" mdb:"type:varchar(64);notnull;default:'';unique:uk_name"`
+	Email   string  `db:"email" mdb:"type:varchar(128);default:''"`
+	OrgId   int64   `db:"org_id" mdb:"index:idx_org_age"`
+	Age     int32   `db:"age" mdb:"index:idx_org_age"`
+	Balance float64 `db:"balance"`
+	Extra   db.Json `db:"extra"`
+	Ignored string  `db:"-"`
+}
+
+func (user) TableName() string { return "t_user" }
+
+type orderItem struct {
+	Id    int64  `db:"id" mdb:"pk;auto_increment"`
+	Title string `db:"title"`
+}
+
+func ptr(s string) *string { return &s }
+
+func testSchema() *db.Schema {
+	return &db.Schema{Database: "app", Tables: map[string]*db.TableInfo{
+		"t_user": {
+			Name: "t_user",
+			Columns: []db.ColumnInfo{
+				{Name: "id", DataType: "bigint", ColumnType: "bigint(20) unsigned", Key: "PRI", AutoIncrement: true},
+				{Name: "created_at", DataType: "datetime", ColumnType: "datetime", Default: ptr("CURRENT_TIMESTAMP")},
+				{Name: "name", DataType: "varchar", ColumnType: "varchar(32)"},
+				{Name: "org_id", DataType: "bigint", ColumnType: "bigint", Nullable: true},
+				{Name: "age", DataType: "int", ColumnType: "int(11)"},
+				{Name: "balance", DataType: "decimal", ColumnType: "decimal(10,2)"},
+				{Name: "extra", DataType: "json", ColumnType: "json", Nullable: true},
+				{Name: "legacy", DataType: "varchar", ColumnType: "varchar(10)", Nullable: true},
+			},
+			Indexes: []db.IndexInfo{
+				{Name: "PRIMARY", Primary: true, Unique: true, Columns: []string{"id"}},
+				{Name: "idx_org_age", Columns: []string{"org_id"}},
+				{Name: "idx_legacy", Columns: []string{"legacy"}},
+			},
+		},
+		"t_audit": {Name: "t_audit", Columns: []db.ColumnInfo{{Name: "id", DataType: "bigint", ColumnType: "bigint"}}},
+		"v_user":  {Name: "v_user", View: true},
+	}}
+}
+
+func TestParseModel(t *testing.T) {
+	m, err := ParseModel(&user{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Table != "t_user" {
+		t.Errorf("table = %s", m.Table)
+	}
+	var names []string
+	for _, c := range m.Columns {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "created_at", "name", "email", "org_id", "age", "balance", "extra"}) {
+		t.Errorf("columns = %v", names)
+	}
+	if !reflect.DeepEqual(m.PrimaryKey, []string{"id"}) || len(m.Indexes) != 2 ||
+		!reflect.DeepEqual(m.Indexes[1], ModelIndex{Name: "idx_org_age", Columns: []string{"org_id", "age"}}) {
+		t.Errorf("unexpected keys: %v %+v", m.PrimaryKey, m.Indexes)
+	}
+	id, _ := m.column("id")
+	if id.Type != "bigint unsigned" || !id.AutoIncrement || id.Nullable {
+		t.Errorf("unexpected id: %+v", id)
+	}
+
+	if m := MustParseModel(orderItem{}); m.Table != "order_item" {
+		t.Errorf("table = %s", m.Table)
+	}
+	if _, err := ParseModel(struct {
+		A int `db:"a" mdb:"bogus"`
+	}{}); err == nil {
+		t.Error("expected error for unknown mdb option")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(testSchema(), Options{AllTables: true}, MustParseModel(user{}), MustParseModel(orderItem{}))
+	want := strings.Join([]string{
+		"missing table order_item",
+		"extra table t_audit",
+		"table t_user: missing column email varchar(128)",
+		"table t_user: extra column legacy",
+		"table t_user: column name: type varchar(32), model varchar(64)",
+		"table t_user: column org_id: database column is nullable, model field is not",
+		"table t_user: column extra: database column is nullable, model field is not",
+		"table t_user: missing index uk_name (name)",
+		"table t_user: extra index idx_legacy",
+		"table t_user: index idx_org_age is (org_id) unique=false, model (org_id, age) unique=false",
+	}, "\n")
+	if got := d.String(); got != want {
+		t.Fatalf("diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	stmts := d.Statements(false)
+	wantStmts := []string{
+		"CREATE TABLE `order_item` (\n  `id` BIGINT NOT NULL AUTO_INCREMENT,\n  `title` VARCHAR(255) NOT NULL,\n  PRIMARY KEY (`id`)\n)",
+		"ALTER TABLE `t_user` ADD COLUMN `email` VARCHAR(128) NOT NULL DEFAULT '' AFTER `name`, " +
+			"MODIFY COLUMN `name` VARCHAR(64) NOT NULL DEFAULT '', " +
+			"DROP INDEX `idx_org_age`, ADD KEY `idx_org_age` (`org_id`, `age`), " +
+			"ADD UNIQUE KEY `uk_name` (`name`)",
+	}
+	if !reflect.DeepEqual(stmts, wantStmts) {
+		t.Fatalf("statements:\n%q\nwant:\n%q", stmts, wantStmts)
+	}
+	dropStmts := d.Statements(true)
+	if last := dropStmts[len(dropStmts)-1]; last != "DROP TABLE `t_audit`" ||
+		!strings.Contains(dropStmts[1], "DROP INDEX `idx_legacy`, DROP COLUMN `legacy`") {
+		t.Errorf("drop statements: %q", dropStmts)
+	}
+
+	if d := Compare(testSchema(), Options{Ignore: []string{"order_item", "t_user"}}, MustParseModel(user{}), MustParseModel(orderItem{})); !d.Empty() {
+		t.Errorf("expected empty diff, got %s", d)
+	}
+}
+
+func TestWriteMigration(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0007_init.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d := Compare(testSchema(), Options{}, MustParseModel(orderItem{}))
+	path, err := d.WriteMigration(dir, "add_order_item", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "0008_add_order_item.up.sql" {
+		t.Fatalf("path = %s", path)
+	}
+	migs, err := migrations.LoadFS(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migs) != 2 || len(migrations.SplitStatements(migs[1].UpSQL)) != 1 {
+		t.Errorf("unexpected migrations: %+v", migs)
+	}
+}
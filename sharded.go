@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/preceeder/db/builder"
+)
+
+// ErrCrossShardTx 跨分片事务未被允许
+var ErrCrossShardTx = errors.New("mdb: cross-shard transaction not allowed")
+
+// ShardRouter 根据分片键返回分片名
+type ShardRouter func(key any) (string, error)
+
+// HashRouter 按哈希取模在 names 中选择分片：整数直接取模，其它类型取 fnv32a 哈希
+func HashRouter(names ...string) ShardRouter {
+	return func(key any) (string, error) {
+		if len(names) == 0 {
+			return "", errors.New("mdb: HashRouter without shards")
+		}
+		rv := reflect.ValueOf(key)
+		var n uint64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i := rv.Int()
+			if i < 0 {
+				i = -i
+			}
+			n = uint64(i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = rv.Uint()
+		default:
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(fmt.Sprint(key)))
+			n = uint64(h.Sum32())
+		}
+		return names[n%uint64(len(names))], nil
+	}
+}
+
+// ShardedConfig ShardedClient 配置
+type ShardedConfig struct {
+	KeyColumn         string      // builder 中分片键的列名，ctx 中没有分片键时从 builder 解析
+	Router            ShardRouter // 分片路由，默认对排序后的分片名做 HashRouter
+	MaxConcurrency    int         // 扇出查询的最大并发数，<=0 表示不限制
+	AllowCrossShardTx bool        // 是否允许 TransactionAll 跨分片事务
+}
+
+// ShardedClient 持有多个 MysqlClient（分库），按分片键路由 *ByBuilder 调用
+type ShardedClient struct {
+	shards map[string]*MysqlClient
+	names  []string
+	config ShardedConfig
+}
+
+// NewShardedClient 创建分库客户端，shards 为 分片名 -> 客户端
+func NewShardedClient(shards map[string]*MysqlClient, config ShardedConfig) *ShardedClient {
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if config.Router == nil {
+		config.Router = HashRouter(names...)
+	}
+	return &ShardedClient{shards: shards, names: names, config: config}
+}
+
+type shardKeyCtxKey struct{}
+
+// WithShardKey 返回携带分片键的 ctx，优先级高于从 builder 中解析
+func WithShardKey(ctx context.Context, key any) context.Context {
+	return context.WithValue(ctx, shardKeyCtxKey{}, key)
+}
+
+// Shard 返回指定名称的分片客户端
+func (c *ShardedClient) Shard(name string) (*MysqlClient, bool) {
+	cli, ok := c.shards[name]
+	return cli, ok
+}
+
+// Names 返回所有分片名（已排序）
+func (c *ShardedClient) Names() []string {
+	return append([]string(nil), c.names...)
+}
+
+// Route 根据 ctx 或 builder 中的分片键选择分片
+func (c *ShardedClient) Route(ctx context.Context, b *builder.SqlBuilder) (string, *MysqlClient, error) {
+	key := ctx.Value(shardKeyCtxKey{})
+	if key == nil && b != nil && c.config.KeyColumn != "" {
+		key, _ = b.ShardKey(c.config.KeyColumn)
+	}
+	if key == nil {
+		return "", nil, fmt.Errorf("%w: no shard key in context or builder", builder.ErrShardKeyRequired)
+	}
+	name, err := c.config.Router(key)
+	if err != nil {
+		return "", nil, err
+	}
+	cli, ok := c.shards[name]
+	if !ok {
+		return "", nil, fmt.Errorf("mdb: unknown shard %q", name)
+	}
+	return name, cli, nil
+}
+
+// QueryByBuilder 路由到单个分片执行单行查询
+func (c *ShardedClient) QueryByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any) error {
+	_, cli, err := c.Route(ctx, b)
+	if err != nil {
+		return err
+	}
+	return cli.QueryByBuilder(ctx, b, dest)
+}
+
+// FetchByBuilder 路由到单个分片执行多行查询
+func (c *ShardedClient) FetchByBuilder(ctx context.Context, b *builder.SqlBuilder, dest any) error {
+	_, cli, err := c.Route(ctx, b)
+	if err != nil {
+		return err
+	}
+	return cli.FetchByBuilder(ctx, b, dest)
+}
+
+// ExecByBuilder 路由到单个分片执行 DML
+func (c *ShardedClient) ExecByBuilder(ctx context.Context, b *builder.SqlBuilder) (sql.Result, error) {
+	_, cli, err := c.Route(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ExecByBuilder(ctx, b)
+}
+
+// Transaction 在分片键所在的单个分片上执行事务，没有分片键时返回错误
+func (c *ShardedClient) Transaction(ctx context.Context, queryObj func(context.Context, MysqlClient, *sqlx.Tx) error) error {
+	_, cli, err := c.Route(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCrossShardTx, err)
+	}
+	return cli.Transaction(ctx, queryObj)
+}
+
+// TransactionAll 在所有分片上各开启一个事务执行 queryObj，全部成功后依次提交，否则全部回滚
+// 注意：这不是分布式事务，提交阶段某个分片失败时无法回滚已提交的分片
+// 每个分片的 ctx 带有各自的 TxHooks，OnCommit/OnRollback 回调随该分片事务的结果执行
+// 需要 ShardedConfig.AllowCrossShardTx 显式开启
+func (c *ShardedClient) TransactionAll(ctx context.Context, queryObj func(ctx context.Context, shard string, m MysqlClient, tx *sqlx.Tx) error) (err error) {
+	if !c.config.AllowCrossShardTx {
+		return ErrCrossShardTx
+	}
+	txs := make(map[string]*clientTx, len(c.names))
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("mdb: transaction panic: %v", p)
+		}
+		if err != nil {
+			for _, t := range txs {
+				t.rollback(err)
+			}
+		}
+	}()
+	for _, name := range c.names {
+		t, err := c.shards[name].beginTx(ctx)
+		if err != nil {
+			return err
+		}
+		txs[name] = t
+	}
+	for _, name := range c.names {
+		if err := queryObj(txs[name].ctx, name, *c.shards[name], txs[name].tx); err != nil {
+			return err
+		}
+	}
+	errs := ShardErrors{}
+	for _, name := range c.names {
+		t := txs[name]
+		delete(txs, name)
+		if err := t.commit(); err != nil {
+			c.shards[name].logger().log(ctx, LogRollback, slog.LevelError, "mdb cross-shard commit failed", slog.String("shard", name), errorAttr(err))
+			errs[name] = err
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// FetchAll 在所有分片上并发执行多行查询，并将结果按分片名顺序合并到 dest（必须是切片指针）
+// 部分分片失败时仍会合并成功分片的结果，并返回 ShardErrors
+func (c *ShardedClient) FetchAll(ctx context.Context, b *builder.SqlBuilder, dest any) error {
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("mdb: FetchAll dest must be a pointer to slice, got %T", dest)
+	}
+	sliceType := dv.Elem().Type()
+
+	results := make([]reflect.Value, len(c.names))
+	errs := ShardErrors{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var sem chan struct{}
+	if c.config.MaxConcurrency > 0 {
+		sem = make(chan struct{}, c.config.MaxConcurrency)
+	}
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					mu.Lock()
+					errs[name] = ctx.Err()
+					mu.Unlock()
+					return
+				}
+			}
+			part := reflect.New(sliceType)
+			if err := c.shards[name].FetchByBuilder(ctx, b, part.Interface()); err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+				return
+			}
+			results[i] = part.Elem()
+		}(i, name)
+	}
+	wg.Wait()
+
+	merged := dv.Elem()
+	for _, part := range results {
+		if part.IsValid() {
+			merged = reflect.AppendSlice(merged, part)
+		}
+	}
+	dv.Elem().Set(merged)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ShardErrors 按分片名聚合的错误
+type ShardErrors map[string]error
+
+func (e ShardErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+": "+e[name].Error())
+	}
+	return "mdb: shard errors: " + strings.Join(parts, "; ")
+}
+
+// Unwrap 支持 errors.Is/As 匹配任一分片的错误
+func (e ShardErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
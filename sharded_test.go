@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/preceeder/db/builder"
+)
+
+func newTestShardedClient(cfg ShardedConfig) *ShardedClient {
+	return NewShardedClient(map[string]*MysqlClient{
+		"db0": {}, "db1": {}, "db2": {},
+	}, cfg)
+}
+
+func TestShardedClient_Route(t *testing.T) {
+	c := newTestShardedClient(ShardedConfig{KeyColumn: "user_id"})
+	ctx := context.Background()
+
+	tu := builder.Table("t_user")
+	name, _, err := c.Route(ctx, tu.Where(tu.Field("user_id").Eq(4, "uid")))
+	if err != nil || name != "db1" {
+		t.Fatalf("route by builder: %s %v", name, err)
+	}
+
+	name, _, err = c.Route(WithShardKey(ctx, 5), builder.Table("t_user"))
+	if err != nil || name != "db2" {
+		t.Fatalf("route by ctx: %s %v", name, err)
+	}
+
+	name, _, err = c.Route(ctx, builder.Table("t_user").InsertMap(map[string]any{"user_id": 3}))
+	if err != nil || name != "db0" {
+		t.Fatalf("route by insert: %s %v", name, err)
+	}
+
+	_, _, err = c.Route(ctx, builder.Table("t_user"))
+	if !errors.Is(err, builder.ErrShardKeyRequired) {
+		t.Fatalf("missing key should fail, got %v", err)
+	}
+}
+
+func TestShardedClient_RejectCrossShardTx(t *testing.T) {
+	c := newTestShardedClient(ShardedConfig{})
+	noop := func(context.Context, MysqlClient, *sqlx.Tx) error { return nil }
+	if err := c.Transaction(context.Background(), noop); !errors.Is(err, ErrCrossShardTx) {
+		t.Fatalf("transaction without key should be rejected, got %v", err)
+	}
+	err := c.TransactionAll(context.Background(), func(context.Context, string, MysqlClient, *sqlx.Tx) error { return nil })
+	if !errors.Is(err, ErrCrossShardTx) {
+		t.Fatalf("TransactionAll should require AllowCrossShardTx, got %v", err)
+	}
+}
+
+func TestShardErrors(t *testing.T) {
+	err := error(ShardErrors{"db1": sql.ErrConnDone, "db0": errors.New("boom")})
+	if !errors.Is(err, sql.ErrConnDone) {
+		t.Fatal("ShardErrors should unwrap shard errors")
+	}
+	if want := "mdb: shard errors: db0: boom; db1: sql: connection is already closed"; err.Error() != want {
+		t.Fatalf("ShardErrors message: %s", err.Error())
+	}
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InflightCall 正在执行的调用（查询、DML 或 Transaction 回调）
+type InflightCall struct {
+	Op      string // 方法名，例如 QueryByBuilder、Transaction
+	Sql     string // Transaction 为空
+	Started time.Time
+}
+
+func (c InflightCall) String() string {
+	s := fmt.Sprintf("%s (%s)", c.Op, time.Since(c.Started).Round(time.Millisecond))
+	if c.Sql != "" {
+		s += " " + c.Sql
+	}
+	return s
+}
+
+// ShutdownError Shutdown 在 ctx 结束前没有等到全部调用完成，已强制关闭
+type ShutdownError struct {
+	Pending []InflightCall // 强制关闭时仍在执行的调用，按开始时间排序
+	Err     error          // ctx.Err()
+}
+
+func (e *ShutdownError) Error() string {
+	calls := make([]string, len(e.Pending))
+	for i, c := range e.Pending {
+		calls[i] = c.String()
+	}
+	return fmt.Sprintf("mdb: forced shutdown with %d calls in flight: %s", len(e.Pending), strings.Join(calls, "; "))
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// inflight 记录客户端正在执行的调用，供 Shutdown 等待
+// 事务内的语句（传入了 tx）归属于所在的 Transaction，不单独记录，Shutdown 期间也不会被拒绝
+type inflight struct {
+	mu      sync.Mutex
+	closing bool
+	nextId  uint64
+	calls   map[uint64]*trackedCall
+	idle    chan struct{} // Shutdown 时创建，calls 清空后关闭
+}
+
+type trackedCall struct {
+	InflightCall
+	cancel context.CancelFunc
+}
+
+// begin 在执行调用前依次检查：是否已 Shutdown、熔断、并发限制，返回可被强制关闭取消的 ctx
+// 以及结束时需要以调用结果调用的 done。调用处于事务内（传入了 tx）时不做检查，已占用连接的事务不应被拒绝
+func (s MysqlClient) begin(ctx context.Context, op, query string, tx int) (context.Context, func(error), error) {
+	if tx > 0 {
+		return ctx, func(error) {}, nil
+	}
+	ctx, untrack, err := s.inflight.add(ctx, op, query)
+	if err != nil {
+		return ctx, nil, err
+	}
+	// 熔断时直接拒绝，不占用并发名额也不排队等待
+	report, err := s.breaker.allow(op)
+	if err != nil {
+		untrack()
+		return ctx, nil, err
+	}
+	release, err := s.bulkhead.acquire(ctx, op)
+	if err != nil {
+		report(errNotStarted)
+		untrack()
+		return ctx, nil, err
+	}
+	return s.bulkhead.hold(ctx), func(err error) {
+		release()
+		report(err)
+		untrack()
+	}, nil
+}
+
+// add 登记一次调用；f 为 nil（直接构造的 MysqlClient）时不做记录
+func (f *inflight) add(ctx context.Context, op, query string) (context.Context, func(), error) {
+	if f == nil {
+		return ctx, func() {}, nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closing {
+		return ctx, nil, &ClientClosedError{Op: op}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	f.nextId++
+	id := f.nextId
+	if f.calls == nil {
+		f.calls = make(map[uint64]*trackedCall)
+	}
+	f.calls[id] = &trackedCall{InflightCall: InflightCall{Op: op, Sql: query, Started: time.Now()}, cancel: cancel}
+	return ctx, func() {
+		cancel()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.calls, id)
+		if f.idle != nil && len(f.calls) == 0 {
+			select {
+			case <-f.idle:
+			default:
+				close(f.idle)
+			}
+		}
+	}, nil
+}
+
+// close 停止接受新的调用，返回全部调用结束时关闭的 channel
+func (f *inflight) close() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closing = true
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+		if len(f.calls) == 0 {
+			close(f.idle)
+		}
+	}
+	return f.idle
+}
+
+func (f *inflight) isClosing() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closing
+}
+
+// snapshot 返回按开始时间排序的调用，cancel 为 true 时同时取消它们的 ctx
+func (f *inflight) snapshot(cancel bool) []InflightCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]InflightCall, 0, len(f.calls))
+	for _, c := range f.calls {
+		calls = append(calls, c.InflightCall)
+		if cancel {
+			c.cancel()
+		}
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].Started.Before(calls[j].Started) })
+	return calls
+}
+
+// InFlight 返回正在执行的调用
+func (s MysqlClient) InFlight() []InflightCall {
+	if s.inflight == nil {
+		return nil
+	}
+	return s.inflight.snapshot(false)
+}
+
+// Shutdown 优雅关闭：立即拒绝新的 Builder/Raw/Transaction 调用（返回 *ClientClosedError），
+// 等待正在执行的查询与 Transaction 回调完成后关闭连接池。
+// ctx 结束时仍未完成的调用会被取消（未提交的事务回滚），随后关闭连接池并返回 *ShutdownError 列出这些调用
+func (s MysqlClient) Shutdown(ctx context.Context) error {
+	if s.inflight == nil {
+		return s.Db.Close()
+	}
+	select {
+	case <-s.inflight.close():
+		s.logger().log(ctx, LogClose, slog.LevelInfo, "mdb shutdown", slog.String("database", s.MysqlConfig.Database))
+		return s.Db.Close()
+	case <-ctx.Done():
+	}
+	pending := s.inflight.snapshot(true)
+	if err := s.Db.Close(); err != nil {
+		s.logger().log(ctx, LogClose, slog.LevelError, "mdb close failed", errorAttr(err))
+	}
+	err := &ShutdownError{Pending: pending, Err: ctx.Err()}
+	s.logger().log(ctx, LogClose, slog.LevelError, "mdb forced shutdown", errorAttr(err))
+	return err
+}
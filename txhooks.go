@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrNotInTransaction 在事务回调之外注册 OnCommit/OnRollback
+var ErrNotInTransaction = errors.New("mdb: not in a transaction")
+
+// TxHooks 一个事务（或保存点）上注册的提交/回滚回调
+//
+// Transaction、RunInTx 传给回调的 ctx 中带有 TxHooks，通过 OnCommit/OnRollback 注册：
+//
+//	err := cli.Transaction(ctx, func(ctx context.Context, m db.MysqlClient, tx *sqlx.Tx) error {
+//		...
+//		return db.OnCommit(ctx, func(ctx context.Context) { publish(event) })
+//	})
+//
+// 嵌套的 RunInTx 使用保存点：保存点释放后其回调并入外层事务，随最外层事务的结果执行；
+// 回滚到保存点时立即执行其 OnRollback 回调并丢弃 OnCommit 回调
+type TxHooks struct {
+	mu       sync.Mutex
+	parent   *TxHooks
+	done     bool
+	commit   []func(ctx context.Context)
+	rollback []func(ctx context.Context, err error)
+	log      *dbLogger // 开启事务的客户端的日志，nil 时使用默认日志
+}
+
+type txHooksKey struct{}
+
+// NewTxHooks 返回带有新 TxHooks 的 ctx，开启事务的一方在得知结果后调用 RunCommit 或 RunRollback
+// 供 Executor 的其它实现（例如 dbfake）使用
+func NewTxHooks(ctx context.Context) (context.Context, *TxHooks) {
+	h := &TxHooks{}
+	return context.WithValue(ctx, txHooksKey{}, h), h
+}
+
+// nestedTxHooks 为保存点创建子 TxHooks；ctx 中没有 TxHooks 时返回 nil
+func nestedTxHooks(ctx context.Context) (context.Context, *TxHooks) {
+	parent, _ := ctx.Value(txHooksKey{}).(*TxHooks)
+	if parent == nil {
+		return ctx, nil
+	}
+	h := &TxHooks{parent: parent, log: parent.log}
+	return context.WithValue(ctx, txHooksKey{}, h), h
+}
+
+// OnCommit 注册在事务提交成功后执行的回调，按注册顺序执行
+func OnCommit(ctx context.Context, fn func(ctx context.Context)) error {
+	h, _ := ctx.Value(txHooksKey{}).(*TxHooks)
+	if h == nil {
+		return ErrNotInTransaction
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done {
+		return ErrNotInTransaction
+	}
+	h.commit = append(h.commit, fn)
+	return nil
+}
+
+// OnRollback 注册在事务回滚（包括提交失败）后执行的回调，err 为导致回滚的错误，按注册顺序执行
+func OnRollback(ctx context.Context, fn func(ctx context.Context, err error)) error {
+	h, _ := ctx.Value(txHooksKey{}).(*TxHooks)
+	if h == nil {
+		return ErrNotInTransaction
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done {
+		return ErrNotInTransaction
+	}
+	h.rollback = append(h.rollback, fn)
+	return nil
+}
+
+// take 结束注册并取出回调
+func (h *TxHooks) take() ([]func(context.Context), []func(context.Context, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.done = true
+	commit, rollback := h.commit, h.rollback
+	h.commit, h.rollback = nil, nil
+	return commit, rollback
+}
+
+// release 保存点释放：回调并入外层
+func (h *TxHooks) release() {
+	commit, rollback := h.take()
+	p := h.parent
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commit = append(p.commit, commit...)
+	p.rollback = append(p.rollback, rollback...)
+}
+
+// RunCommit 事务已提交，执行 OnCommit 回调；回调 panic 会被记录并继续执行后续回调
+func (h *TxHooks) RunCommit(ctx context.Context) {
+	commit, _ := h.take()
+	for _, fn := range commit {
+		func() {
+			defer h.recoverHook(ctx, "commit")
+			fn(ctx)
+		}()
+	}
+}
+
+// RunRollback 事务已回滚，执行 OnRollback 回调；回调 panic 会被记录并继续执行后续回调
+func (h *TxHooks) RunRollback(ctx context.Context, err error) {
+	_, rollback := h.take()
+	for _, fn := range rollback {
+		func() {
+			defer h.recoverHook(ctx, "rollback")
+			fn(ctx, err)
+		}()
+	}
+}
+
+func (h *TxHooks) recoverHook(ctx context.Context, kind string) {
+	if p := recover(); p != nil {
+		h.logger().log(ctx, LogClient, slog.LevelError, "mdb transaction hook panic", slog.String("hook", kind), slog.Any("panic", p))
+	}
+}
+
+func (h *TxHooks) logger() *dbLogger {
+	if h.log != nil {
+		return h.log
+	}
+	return defaultLogger
+}
+
+// loggerFromContext 返回 ctx 中事务所属客户端的日志
+func loggerFromContext(ctx context.Context) *dbLogger {
+	if h, _ := ctx.Value(txHooksKey{}).(*TxHooks); h != nil {
+		return h.logger()
+	}
+	return defaultLogger
+}